@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/reload"
+	"otte_main_backend/src/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SetRoleRequestDTO struct {
+	Role auth.Role `json:"role"`
+}
+
+type SetRoleResponseDTO struct {
+	PlayerID uint32    `json:"playerId"`
+	Role     auth.Role `json:"role"`
+}
+
+// Management endpoints. Every route here must require a role.
+func applyAdminApi(app *fiber.App, appContext *meta.ApplicationContext, authService *auth.AuthService, configReloader *reload.ConfigReloader) error {
+	log.Println("[Admin API] Applying admin API")
+
+	app.Get("/api/v1/admin/player/:playerId/role", auth.RequireRole(appContext, auth.RoleTeacher, getPlayerRoleHandler))
+
+	app.Post("/api/v1/admin/player/:playerId/role", auth.RequireRole(appContext, auth.RoleAdmin, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		return setPlayerRoleHandler(c, appContext, authService)
+	}))
+
+	// Delivery status of the progress reported to Vitec for the player, ?recent=<n> for the amount of events included (default 20)
+	app.Get("/api/v1/admin/player/:playerId/progress-reports", auth.RequireRole(appContext, auth.RoleAdmin, getProgressReportsHandler))
+
+	// Re-reads the config sources and applies changes to reloadable keys, like SIGHUP does
+	app.Post("/api/v1/admin/config/reload", auth.RequireRole(appContext, auth.RoleAdmin, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		return reloadConfigHandler(c, appContext, configReloader)
+	}))
+
+	return nil
+}
+
+func getPlayerRoleHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	playerId, parseErr := c.ParamsInt("playerId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid player ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+	}
+
+	player, err := appContext.Players.FindByID(c.UserContext(), uint32(playerId))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Player not found")
+			return fiber.NewError(fiber.StatusNotFound, "Player not found")
+		}
+		// Gorm exposes secrets in err when DB is down, so it can't be included in the response
+		c.Response().Header.Set(appContext.DDH, "Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(SetRoleResponseDTO{PlayerID: player.ID, Role: player.Role})
+}
+
+func setPlayerRoleHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
+	playerId, parseErr := c.ParamsInt("playerId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid player ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+	}
+
+	var request SetRoleRequestDTO
+	if err := c.BodyParser(&request); err != nil || !request.Role.IsValid() {
+		c.Response().Header.Set(appContext.DDH, "Invalid request body, expected role to be one of: player, teacher, admin")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := appContext.Players.SetRole(c.UserContext(), uint32(playerId), request.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Player not found")
+			return fiber.NewError(fiber.StatusNotFound, "Player not found")
+		}
+		c.Response().Header.Set(appContext.DDH, "Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+	//The role is cached along with the sessions, and carried by signed tokens, which would otherwise keep the old role
+	if err := auth.ApplyRoleChange(c.UserContext(), uint32(playerId), appContext, authService); err != nil {
+		log.Printf("[Admin API] Unable to apply the role change of player %d to their sessions: %s\n", playerId, err.Error())
+		c.Response().Header.Set(appContext.DDH, "Role set, but existing sessions may keep the previous role")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	log.Printf("[Admin API] Role of player %d set to %s\n", playerId, request.Role)
+	c.Status(fiber.StatusOK)
+	return c.JSON(SetRoleResponseDTO{PlayerID: uint32(playerId), Role: request.Role})
+}
+
+func getProgressReportsHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	playerId, parseErr := c.ParamsInt("playerId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid player ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+	}
+	recent := c.QueryInt("recent", 20)
+	if recent < 0 || recent > 1000 {
+		c.Response().Header.Set(appContext.DDH, "Invalid recent, expected 0-1000")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid recent")
+	}
+
+	summary, err := appContext.Players.FindProgressSummary(c.UserContext(), uint32(playerId), recent)
+	if err != nil {
+		log.Printf("[Admin API] Unable to summarize progress reports of player %d: %s\n", playerId, err.Error())
+		c.Response().Header.Set(appContext.DDH, "Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(summary)
+}
+
+// The reload applies to requests arriving afterwards, this one is still answered with the values it arrived with
+func reloadConfigHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, configReloader *reload.ConfigReloader) error {
+	result, err := configReloader.Reload()
+
+	if err != nil {
+		log.Println("[Admin API] Config reload failed: " + err.Error())
+		c.Response().Header.Set(appContext.DDH, "Config reload failed, nothing was applied")
+		if result == nil {
+			result = &reload.ReloadResultDTO{Applied: []string{}, Rejected: []string{}}
+		}
+		result.Error = err.Error()
+		c.Status(fiber.StatusConflict)
+		return c.JSON(result)
+	}
+	c.Status(fiber.StatusOK)
+	return c.JSON(result)
+}
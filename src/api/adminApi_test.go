@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSetPlayerRole(t *testing.T) {
+	_, appContext, authService := setupSessionTest(t)
+	app := fiber.New()
+	if err := applyAdminApi(app, appContext, authService, nil); err != nil {
+		t.Fatal("failed to apply admin API:", err)
+	}
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1, Role: repository.RoleAdmin})
+	players.PutPlayer(repository.Player{ID: 2})
+	putTestSession(t, appContext, authService, 1, "admin")
+	putTestSession(t, appContext, authService, 2, "player")
+
+	testSessionRequest(t, app, "POST", "/api/v1/admin/player/2/role", "player", `{"role":"admin"}`, fiber.StatusForbidden)
+	testSessionRequest(t, app, "POST", "/api/v1/admin/player/2/role", "admin", `{"role":"superuser"}`, fiber.StatusBadRequest)
+	testSessionRequest(t, app, "POST", "/api/v1/admin/player/2/role", "admin", `{}`, fiber.StatusBadRequest)
+	testSessionRequest(t, app, "POST", "/api/v1/admin/player/3/role", "admin", `{"role":"teacher"}`, fiber.StatusNotFound)
+	if player, _ := appContext.Players.FindByID(context.Background(), 2); player.Role != "" {
+		t.Fatalf("expected the role to be left alone, got %s", player.Role)
+	}
+
+	testSessionRequest(t, app, "POST", "/api/v1/admin/player/2/role", "admin", `{"role":"teacher"}`, fiber.StatusOK)
+	if player, _ := appContext.Players.FindByID(context.Background(), 2); player.Role != auth.RoleTeacher {
+		t.Errorf("expected the role to be set, got %s", player.Role)
+	}
+}
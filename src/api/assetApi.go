@@ -4,6 +4,7 @@ import (
 	"errors"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
 	"strconv"
 	"strings"
@@ -11,23 +12,11 @@ import (
 	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // DTO's
-type AssetResponse struct {
-	ID      uint32 `json:"id"`
-	UseCase string `json:"useCase" gorm:"column:useCase"`
-	Type    string `json:"type"`
-	Width   uint32 `json:"width"`
-	Height  uint32 `json:"height"`
-	/** DEPRECATED see explanation in lodAPI.go
-	HasLODs bool   `json:"hasLODs" gorm:"column:hasLODs"`
-	Blob    []byte       `json:"blob"`
-	*/
-	Alias string       `json:"alias"`
-	LODs  []LODDetails `json:"LODs" gorm:"foreignKey:GraphicalAsset;references:ID"`
-}
+// Without blobs, see lodAPI.go for why blobs were removed
+type AssetResponse = repository.Asset
 
 type MultiAssetResponse []AssetResponse
 
@@ -56,13 +45,9 @@ func getLODByAssetAndDetailLevel(c *fiber.Ctx, appContext *meta.ApplicationConte
 		return fiber.NewError(fiber.StatusBadRequest, "Error parsing asset or LOD id")
 	}
 
-	var lod LOD
-	err := appContext.ColonyAssetDB.
-		Where(`"LOD"."graphicalAsset" = ? AND "LOD"."detailLevel" = ?`, assetId, lodId).
-		First(&lod).Error
-
+	lod, err := appContext.Assets.FindLODByDetailLevel(c.UserContext(), uint32(assetId), uint32(lodId))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "No such LOD")
 			return fiber.NewError(fiber.StatusNotFound, "No such LOD")
 		}
@@ -72,7 +57,7 @@ func getLODByAssetAndDetailLevel(c *fiber.Ctx, appContext *meta.ApplicationConte
 	}
 
 	c.Status(fiber.StatusOK)
-	SetHeadersForLODBlob(c, &lod)
+	SetHeadersForLODBlob(c, lod)
 	return c.Send(lod.Blob)
 }
 
@@ -86,25 +71,18 @@ func getMultipleAssetsByIds(c *fiber.Ctx, appContext *meta.ApplicationContext) e
 		return fiber.NewError(fiber.StatusBadRequest, "Error in parsing asset id "+parseErr.Error())
 	}
 
-	var assets []AssetResponse
-	err := appContext.ColonyAssetDB.
-		Table("GraphicalAsset").
-		Preload("LODs"). // Preload the LODs field for each asset, can be optimized as this fetches the blob too, which superfluous
-		Where(`"GraphicalAsset".id IN ?`, ids).
-		Find(&assets).Error
-
-	// This check goes first since the query is for any amount of rows (including 0 as a valid amount)
-	if errors.Is(err, gorm.ErrRecordNotFound) || len(assets) == 0 {
-		c.Response().Header.Set(appContext.DDH, "No such assets")
-		return fiber.NewError(fiber.StatusNotFound, "No such assets")
-	}
-
+	assets, err := appContext.Assets.FindByIDs(c.UserContext(), util.ArrayMap(ids, func(id int) uint32 { return uint32(id) }))
 	if err != nil {
 		// Gorm exposes secrets in err when DB is down, so it can't be included in the response
 		c.Response().Header.Set(appContext.DDH, "Internal error")
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
 	}
 
+	if len(assets) == 0 {
+		c.Response().Header.Set(appContext.DDH, "No such assets")
+		return fiber.NewError(fiber.StatusNotFound, "No such assets")
+	}
+
 	if len(assets) != len(ids) {
 		c.Response().Header.Set(appContext.DDH, "Some assets were not found")
 		c.Status(fiber.StatusPartialContent)
@@ -123,15 +101,9 @@ func getAssetByIdHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) erro
 		return fiber.NewError(fiber.StatusBadRequest, "Error parsing id "+parsingError.Error())
 	}
 
-	var dto AssetResponse
-	err := appContext.ColonyAssetDB.
-		Table("GraphicalAsset").
-		Preload("LODs"). // Preload the LODs field using the foreign key
-		Where(`"GraphicalAsset".id = ?`, id).
-		First(&dto).Error
-
+	dto, err := appContext.Assets.FindByID(c.UserContext(), uint32(id))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "No such asset")
 			return fiber.NewError(fiber.StatusNotFound, "No such asset")
 		}
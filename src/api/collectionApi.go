@@ -5,9 +5,9 @@ import (
 	"log"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 type MinimizedAssetWithTransformDTO struct {
@@ -29,53 +29,7 @@ func (a *AssetCollectionResponseDTO) TableName() string {
 	return "AssetCollection"
 }
 
-type RawResult struct {
-	AssetCollectionID uint32  `gorm:"column:assetCollectionId"`
-	CollectionName    string  `gorm:"column:collectionName"`
-	CollectionEntryID uint32  `gorm:"column:collectionEntryId"`
-	GraphicalAssetID  uint32  `gorm:"column:graphicalAssetId"`
-	Width             int     `gorm:"column:width"`
-	Height            int     `gorm:"column:height"`
-	Alias             string  `gorm:"column:alias"`
-	Type              string  `gorm:"column:type"`
-	XOffset           float32 `gorm:"column:xOffset"`
-	YOffset           float32 `gorm:"column:yOffset"`
-	ZIndex            uint32  `gorm:"column:zIndex"`
-	XScale            float32 `gorm:"column:xScale"`
-	YScale            float32 `gorm:"column:yScale"`
-	LODID             uint32  `gorm:"column:lodId"`
-	DetailLevel       int     `gorm:"column:detailLevel"`
-}
-
-const collectionQuery = `
-SELECT 
-	ac.id AS "assetCollectionId",
-	ac.name AS "collectionName",
-	ce.id AS "collectionEntryId",
-	ga.id AS "graphicalAssetId",
-	ga.width AS "width",
-	ga.height AS "height",
-	ga.alias AS "alias",
-	ga.type AS "type",
-	t."xOffset" AS "xOffset",
-	t."yOffset" AS "yOffset",
-	t."zIndex" AS "zIndex",
-	t."xScale" AS "xScale",
-	t."yScale" AS "yScale",
-	lod.id AS "lodId",
-	lod."detailLevel" AS "detailLevel"
-FROM 
-	"AssetCollection" ac
-JOIN 
-	"CollectionEntry" ce ON ce."assetCollection" = ac.id
-JOIN 
-	"Transform" t ON t.id = ce.transform
-JOIN 
-	"GraphicalAsset" ga ON ga.id = ce."graphicalAsset"
-LEFT JOIN 
-	"LOD" lod ON lod."graphicalAsset" = ga.id
-WHERE 
-	ac.id = ?`
+type RawResult = repository.CollectionEntryRow
 
 func applyCollectionApi(app *fiber.App, appContext *meta.ApplicationContext) error {
 	log.Println("[Collection API] Applying collection API")
@@ -92,12 +46,8 @@ func getCollectionByIDHandler(c *fiber.Ctx, appContext *meta.ApplicationContext)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid collection ID: "+parseErr.Error())
 	}
 
-	var rawResults []RawResult
-	if err := appContext.ColonyAssetDB.Raw(collectionQuery, collectionId).Scan(&rawResults).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.Response().Header.Set(appContext.DDH, "No such collection")
-			return fiber.NewError(fiber.StatusNotFound, "No such collection")
-		}
+	rawResults, err := appContext.Assets.FindCollectionEntries(c.UserContext(), uint32(collectionId))
+	if err != nil {
 		log.Printf("[Collection API] Error retrieving collection: %v", err)
 		return fiber.NewError(fiber.StatusInternalServerError, "Error retrieving collection")
 	}
@@ -4,31 +4,42 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
-	"otte_main_backend/src/middleware"
 	"otte_main_backend/src/multiplayer"
+	"otte_main_backend/src/ratelimit"
+	"otte_main_backend/src/repository"
+	"otte_main_backend/src/vitec"
 	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 func applyColonyApi(app *fiber.App, appContext *meta.ApplicationContext) error {
-	app.Post("/api/v1/colony/:colonyId/location/:colonyLocationId/upgrade", func(c *fiber.Ctx) error {
-		return upgradeColonyLocationHandler(c, appContext)
-	})
+	// Managing a colony, upgrading its locations included, is only allowed for its owner. Visitors still need the pathgraph.
+	ownsColony := auth.SessionOwnsColonyParam("colonyId")
+
+	// Join codes are short, so guessing them is throttled and repeated misses lock the player out
+	rateLimits := appContext.Config.RateLimit
+	joinIPLimiter := ratelimit.NewLimiterFromConfig("COLONY_JOIN_IP", rateLimits, rateLimits.ColonyJoinIP)
+	joinSessionLimiter := ratelimit.NewLimiterFromConfig("COLONY_JOIN_SESSION", rateLimits, rateLimits.ColonyJoinSession)
+	joinLockout := ratelimit.NewLockoutFromConfig("COLONY_JOIN", rateLimits, rateLimits.ColonyJoinLockout)
+
+	app.Post("/api/v1/colony/:colonyId/location/:colonyLocationId/upgrade", auth.PrefixOn(appContext, upgradeColonyLocationHandler, ownsColony))
 	app.Get("/api/v1/colony/:colonyId/pathgraph", auth.PrefixOn(appContext, getPathGraphHandler))
-	app.Get("/api/v1/colony/:colonyId/code", auth.PrefixOn(appContext, getColonyCodeHandler))
-	app.Post("/api/v1/colony/:colonyId/open", auth.PrefixOn(appContext, openColonyHandler))
-	app.Post("/api/v1/colony/:colonyId/close", func(c *fiber.Ctx) error {
-		return closeColonyHandler(c, appContext)
-	})
-	app.Post("/api/v1/colony/join/:code", auth.PrefixOn(appContext, joinColonyHandler))
-	app.Post("/api/v1/colony/:colonyId/update-last-visit", auth.PrefixOn(appContext, updateLatestVisitHandler))
+	app.Get("/api/v1/colony/:colonyId/code", auth.PrefixOn(appContext, getColonyCodeHandler, ownsColony))
+	app.Post("/api/v1/colony/:colonyId/open", auth.PrefixOn(appContext, openColonyHandler, ownsColony, auth.PlayerBodyFieldMatchesSession("playerId")))
+	app.Post("/api/v1/colony/:colonyId/close", auth.PrefixOn(appContext, closeColonyHandler, ownsColony, auth.PlayerBodyFieldMatchesSession("playerId")))
+	app.Post("/api/v1/colony/join/:code",
+		joinIPLimiter.Middleware(appContext, ratelimit.ByIP),
+		auth.PrefixOn(appContext, joinSessionLimiter.Wrap(ratelimit.BySession, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+			return joinColonyHandler(c, appContext, joinLockout)
+		})))
+	app.Post("/api/v1/colony/:colonyId/update-last-visit", auth.PrefixOn(appContext, updateLatestVisitHandler, ownsColony))
 	return nil
 }
 
@@ -36,55 +47,50 @@ func upgradeColonyLocationHandler(c *fiber.Ctx, context *meta.ApplicationContext
 	colonyID, err := c.ParamsInt("colonyId")
 	if err != nil {
 		c.Response().Header.Set(context.DDH, "Invalid colony ID "+err.Error())
-		c.Status(fiber.StatusBadRequest)
-		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid colony ID")
 	}
 	colonyLocationID, err := c.ParamsInt("colonyLocationId")
 	if err != nil {
 		c.Response().Header.Set(context.DDH, "Invalid colony location ID "+err.Error())
-		c.Status(fiber.StatusBadRequest)
-		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid colony location ID")
 	}
 
-	var toReturn = struct {
-		Level int `json:"level" gorm:"column:level"`
-		ID    int `json:"id" gorm:"column:id"`
-	}{}
-
 	//Increment "level" of ColonyLocation with ID colonyLocationID
-	if err := context.ColonyAssetDB.Model(&ColonyLocationModel{}).
-		Where("id = ? AND colony = ?", colonyLocationID, colonyID).
-		Update("level", gorm.Expr("level + 1")).
-		Select("level", "id").First(&toReturn).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	location, err := context.Colonies.UpgradeLocation(c.UserContext(), uint32(colonyID), uint32(colonyLocationID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(context.DDH, "ColonyLocation not found")
-			c.Status(fiber.StatusNotFound)
-			middleware.LogRequests(c)
 			return fiber.NewError(fiber.StatusNotFound, "ColonyLocation not found")
 		}
 
-		c.Response().Header.Set(context.DDH, "Internal server error "+err.Error())
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
+		log.Println("[Colony API] Unable to upgrade colony location: " + err.Error())
+		c.Response().Header.Set(context.DDH, "Internal server error")
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
+	toReturn := struct {
+		Level uint32 `json:"level"`
+		ID    uint32 `json:"id"`
+	}{
+		Level: location.Level,
+		ID:    location.ID,
+	}
+
+	if colony, err := context.Colonies.FindByID(c.UserContext(), uint32(colonyID)); err == nil {
+		context.VitecIntegration.ReportProgress(c.UserContext(), colony.Owner, vitec.ProgressLocationUpgrade, map[string]interface{}{
+			"colonyId":         colonyID,
+			"colonyLocationId": colonyLocationID,
+			"level":            toReturn.Level,
+		})
+	} else {
+		log.Println("[Colony API] Unable to lookup colony owner for progress report: " + err.Error())
+	}
+
 	c.Status(fiber.StatusOK)
-	middleware.LogRequests(c)
 	return c.JSON(toReturn)
 }
 
-type PathDTO struct {
-	From uint32 `json:"from" gorm:"column:locationA"` //Id of ColonyLocation
-	To   uint32 `json:"to" gorm:"column:locationB"`   //Id of ColonyLocation
-}
-
-func (p *PathDTO) TableName() string {
-	return "ColonyLocationPath"
-}
+type PathDTO = repository.ColonyPath
 
 type PathGraphDTO struct {
 	Paths []PathDTO `json:"paths"`
@@ -96,13 +102,12 @@ func getPathGraphHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) erro
 		c.Response().Header.Set(appContext.DDH, "Invalid colony ID "+err.Error())
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid colony ID")
 	}
-	var paths []PathDTO
-	if dbErr := appContext.ColonyAssetDB.Where("colony = ?", colonyID).Find(&paths).Error; dbErr != nil || len(paths) == 0 {
-		if !errors.Is(dbErr, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Internal error")
-			return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
-		}
-
+	paths, dbErr := appContext.Colonies.FindPaths(c.UserContext(), uint32(colonyID))
+	if dbErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+	if len(paths) == 0 {
 		c.Response().Header.Set(appContext.DDH, "Colony not found or paths not found")
 		return fiber.NewError(fiber.StatusNotFound, "Colony not found or paths not found")
 	}
@@ -129,33 +134,7 @@ type JoinColonyResponse struct {
 	ColonyID                 uint32 `json:"colonyId"`
 }
 
-type ColonyDTO struct {
-	ID          uint32           `gorm:"column:id;primaryKey"`
-	Name        string           `gorm:"column:name"`
-	AccLevel    int              `gorm:"column:accLevel"`
-	Owner       uint32           `gorm:"column:owner"`
-	LatestVisit string           `gorm:"column:latestVisit"`
-	ColonyCode  *ColonyCodeModel `gorm:"foreignKey:ColonyID"`
-}
-
-func (ColonyDTO) TableName() string {
-	return "Colony"
-}
-
-type ColonyCodeModel struct {
-	ID              uint32    `gorm:"column:id;primaryKey"`
-	LobbyID         uint32    `gorm:"column:lobbyId"`
-	ServerAddress   string    `gorm:"column:serverAddress"`
-	ColonyID        uint32    `gorm:"column:colony"`
-	Value           string    `gorm:"column:value"`
-	OwnerID         uint32    `gorm:"column:owner"`
-	CreatedAt       time.Time `gorm:"column:createdAt"`
-	ValidDurationMS uint32    `gorm:"column:validDurationMS"`
-}
-
-func (ColonyCodeModel) TableName() string {
-	return "ColonyCode"
-}
+type ColonyCodeModel = repository.ColonyCode
 
 func openColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	colonyID, err := c.ParamsInt("colonyId")
@@ -173,39 +152,43 @@ func openColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 		req.DurationMS = 600000
 	}
 
-	var colony ColonyDTO
-	if err := appContext.ColonyAssetDB.
-		Preload("ColonyCode").
-		Where("id = ? AND owner = ?", colonyID, req.PlayerID).
-		First(&colony).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player "+err.Error())
+	colony, err := appContext.Colonies.FindByID(c.UserContext(), uint32(colonyID))
+	if err == nil && colony.Owner != req.PlayerID {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player")
 			return fiber.NewError(fiber.StatusNotFound, "Colony not found or not owned by player")
 		}
 		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	if colony.ColonyCode != nil {
-		if colony.ColonyCode.CreatedAt.Add(time.Duration(colony.ColonyCode.ValidDurationMS) * time.Millisecond).After(time.Now()) {
+	if colony.ColonyCode != 0 {
+		existingCode, err := appContext.Colonies.FindCode(c.UserContext(), colony.ColonyCode)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
+			return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
+		}
+		if existingCode != nil && !existingCode.ExpiredAt(time.Now()) {
 			response := OpenColonyResponse{
-				Code:                     colony.ColonyCode.Value,
-				LobbyID:                  colony.ColonyCode.LobbyID,
-				MultiplayerServerAddress: colony.ColonyCode.ServerAddress,
+				Code:                     existingCode.Value,
+				LobbyID:                  existingCode.LobbyID,
+				MultiplayerServerAddress: existingCode.ServerAddress,
 			}
 			c.Status(fiber.StatusOK)
 			return c.JSON(response)
 		}
 	}
 
-	lobbyID, err := multiplayer.CreateLobby(req.PlayerID, colony.ID, appContext)
+	lobbyID, err := multiplayer.CreateLobby(c.UserContext(), req.PlayerID, colony.ID, appContext)
 	if err != nil {
 		c.Response().Header.Set(appContext.DDH, "Failed to create lobby "+err.Error())
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to create lobby")
 	}
 
-	colony.LatestVisit = req.LatestVisit
-	colony.ColonyCode = &ColonyCodeModel{
+	colonyCode := &ColonyCodeModel{
 		LobbyID:         lobbyID,
 		ServerAddress:   appContext.ExternalMultiplayerServerAddress,
 		ColonyID:        colony.ID,
@@ -231,35 +214,16 @@ func openColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 			backToInt += 100000
 		}
 
-		colony.ColonyCode.Value = fmt.Sprintf("%d", backToInt)
-
-		tx := appContext.ColonyAssetDB.Begin()
-		if err := tx.Error; err != nil {
-			c.Response().Header.Set(appContext.DDH, "Failed to begin transaction "+err.Error())
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to begin transaction")
-		}
+		colonyCode.Value = fmt.Sprintf("%d", backToInt)
 
-		if err := tx.Create(colony.ColonyCode).Error; err != nil {
-			tx.Rollback()
-			if errors.Is(err, gorm.ErrDuplicatedKey) {
+		if err := appContext.Colonies.AttachCode(c.UserContext(), colonyCode); err != nil {
+			if errors.Is(err, repository.ErrDuplicateCode) {
 				retryCount++
 				continue
 			}
 			c.Response().Header.Set(appContext.DDH, "Failed to create ColonyCode "+err.Error())
 			return fiber.NewError(fiber.StatusInternalServerError, "Failed to create ColonyCode")
 		}
-
-		if err := tx.Model(&colony).Update("colonyCode", colony.ColonyCode.ID).Error; err != nil {
-			tx.Rollback()
-			c.Response().Header.Set(appContext.DDH, "Failed to update Colony colonyCode "+err.Error())
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to update Colony")
-		}
-
-		if err := tx.Commit().Error; err != nil {
-			tx.Rollback()
-			c.Response().Header.Set(appContext.DDH, "Failed to commit transaction "+err.Error())
-			return fiber.NewError(fiber.StatusInternalServerError, "Failed to commit transaction")
-		}
 		isGood = true
 	}
 
@@ -269,15 +233,32 @@ func openColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 	}
 
 	response := OpenColonyResponse{
-		Code:                     colony.ColonyCode.Value,
-		LobbyID:                  colony.ColonyCode.LobbyID,
-		MultiplayerServerAddress: colony.ColonyCode.ServerAddress,
+		Code:                     colonyCode.Value,
+		LobbyID:                  colonyCode.LobbyID,
+		MultiplayerServerAddress: colonyCode.ServerAddress,
 	}
 	c.Status(fiber.StatusOK)
 	return c.JSON(response)
 }
 
-func joinColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+func joinColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, lockout *ratelimit.Lockout) error {
+	lockoutKey := ratelimit.BySession(c)
+	if locked, retryAfter := lockout.IsLocked(lockoutKey, time.Now()); locked {
+		return ratelimit.RejectWithRetryAfter(c, appContext, retryAfter, "Too many failed join attempts")
+	}
+	// Any client error means a wrong guess, which counts towards the lockout
+	joinErr := tryJoinColony(c, appContext)
+	if fiberErr, ok := joinErr.(*fiber.Error); ok && fiberErr.Code >= 400 && fiberErr.Code < 500 {
+		if lockedFor := lockout.RegisterFailure(lockoutKey, time.Now()); lockedFor > 0 {
+			c.Response().Header.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(lockedFor.Seconds())))
+		}
+	} else if joinErr == nil {
+		lockout.RegisterSuccess(lockoutKey)
+	}
+	return joinErr
+}
+
+func tryJoinColony(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	code := c.Params("code")
 
 	if code == "" {
@@ -295,22 +276,20 @@ func joinColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid colony code format")
 	}
 
-	var colonyCodes []ColonyCodeModel
-	if err := appContext.ColonyAssetDB.
-		Where("value = ?", code).
-		Find(&colonyCodes).Error; err != nil {
+	colonyCode, err := appContext.Colonies.FindCodeByValue(c.UserContext(), code)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony code not found: "+code)
+			return fiber.NewError(fiber.StatusNotFound, "Colony code not found")
+		}
 		c.Response().Header.Set(appContext.DDH, "Database error: "+err.Error())
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	if len(colonyCodes) == 0 {
-		c.Response().Header.Set(appContext.DDH, "Colony code not found: "+code)
-		return fiber.NewError(fiber.StatusNotFound, "Colony code not found")
-	}
-
-	colonyCode := colonyCodes[0]
-	if colonyCode.CreatedAt.Add(time.Duration(colonyCode.ValidDurationMS) * time.Millisecond).Before(time.Now()) {
-		appContext.ColonyAssetDB.Delete(&colonyCode)
+	if colonyCode.ExpiredAt(time.Now()) {
+		if err := appContext.Colonies.DeleteCode(c.UserContext(), colonyCode.ID); err != nil {
+			log.Println("[Colony API] Unable to delete expired colony code: " + err.Error())
+		}
 		c.Response().Header.Set(appContext.DDH, "Code expired")
 		return fiber.NewError(fiber.StatusNotFound, "Colony code not found: "+code)
 	}
@@ -346,26 +325,17 @@ func updateLatestVisitHandler(c *fiber.Ctx, appContext *meta.ApplicationContext)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	var colony ColonyDTO
-	if err := appContext.ColonyAssetDB.
-		Where("id = ?", colonyID).
-		First(&colony).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player "+err.Error())
+	if err := appContext.Colonies.UpdateLatestVisit(c.UserContext(), uint32(colonyID), req.LatestVisit); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player")
 			return fiber.NewError(fiber.StatusNotFound, "Colony not found or not owned by player")
 		}
-		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
-		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
-	}
-
-	colony.LatestVisit = req.LatestVisit
-	if err := appContext.ColonyAssetDB.Save(&colony).Error; err != nil {
 		c.Response().Header.Set(appContext.DDH, "Failed to update LatestVisit "+err.Error())
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update LatestVisit")
 	}
 
 	response := UpdateLatestVisitResponse{
-		LatestVisit: colony.LatestVisit,
+		LatestVisit: req.LatestVisit,
 	}
 
 	return c.JSON(response)
@@ -379,74 +349,28 @@ func closeColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 	colonyID, err := c.ParamsInt("colonyId")
 	if err != nil {
 		c.Response().Header.Set(appContext.DDH, "Invalid colony ID "+err.Error())
-		c.Status(fiber.StatusBadRequest)
-		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid colony ID")
 	}
 
 	var req CloseColonyRequest
 	if err := c.BodyParser(&req); err != nil {
 		c.Response().Header.Set(appContext.DDH, "Invalid request body "+err.Error())
-		c.Status(fiber.StatusBadRequest)
-		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 	if req.PlayerID == 0 {
 		c.Response().Header.Set(appContext.DDH, "Invalid request body: Player ID is 0")
-		c.Status(fiber.StatusBadRequest)
-		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	tx := appContext.ColonyAssetDB.Begin()
-	if err := tx.Error; err != nil {
-		c.Response().Header.Set(appContext.DDH, "Failed to begin transaction "+err.Error())
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to begin transaction")
-	}
-
-	var colony ColonyDTO
-	if err := tx.Where("id = ? AND owner = ?", colonyID, req.PlayerID).First(&colony).Error; err != nil {
-		tx.Rollback()
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player "+err.Error())
-			c.Status(fiber.StatusNotFound)
-			middleware.LogRequests(c)
+	if err := appContext.Colonies.DetachCodes(c.UserContext(), uint32(colonyID), req.PlayerID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony not found or not owned by player")
 			return fiber.NewError(fiber.StatusNotFound, "Colony not found or not owned by player")
 		}
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
-		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
-		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
-	}
-
-	if err := tx.Model(&colony).Update("colonyCode", nil).Error; err != nil {
-		tx.Rollback()
-		c.Response().Header.Set(appContext.DDH, "Failed to update Colony colonyCode "+err.Error())
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to update Colony")
+		c.Response().Header.Set(appContext.DDH, "Failed to close colony "+err.Error())
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to close colony")
 	}
 
-	if err := tx.Where("colony = ?", colonyID).Delete(&ColonyCodeModel{}).Error; err != nil {
-		tx.Rollback()
-		c.Response().Header.Set(appContext.DDH, "Failed to delete ColonyCode "+err.Error())
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to delete ColonyCode")
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		c.Response().Header.Set(appContext.DDH, "Failed to commit transaction "+err.Error())
-		c.Status(fiber.StatusInternalServerError)
-		middleware.LogRequests(c)
-		return fiber.NewError(fiber.StatusInternalServerError, "Failed to commit transaction")
-	}
-
-	c.Status(fiber.StatusOK)
-	middleware.LogRequests(c)
 	return c.SendStatus(fiber.StatusOK)
 }
 
@@ -458,17 +382,10 @@ func getColonyCodeHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 	}
 
 	// First get the colony to find its colonyCode ID
-	var colony struct {
-		ID         uint32 `gorm:"column:id"`
-		ColonyCode uint32 `gorm:"column:colonyCode"`
-	}
-	if err := appContext.ColonyAssetDB.
-		Table("Colony").
-		Select("id, \"colonyCode\"").
-		Where("id = ?", colonyID).
-		First(&colony).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony not found "+err.Error())
+	colony, err := appContext.Colonies.FindByID(c.UserContext(), uint32(colonyID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony not found")
 			return fiber.NewError(fiber.StatusNotFound, "Colony not found")
 		}
 		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
@@ -481,13 +398,10 @@ func getColonyCodeHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 	}
 
 	// Now get the actual colony code using the ID from Colony table
-	var colonyCode ColonyCodeModel
-	if err := appContext.ColonyAssetDB.
-		Table("ColonyCode").
-		Where("id = ?", colony.ColonyCode).
-		First(&colonyCode).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony code not found "+err.Error())
+	colonyCode, err := appContext.Colonies.FindCode(c.UserContext(), colony.ColonyCode)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony code not found")
 			return fiber.NewError(fiber.StatusNotFound, "Colony code not found")
 		}
 		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
@@ -495,7 +409,7 @@ func getColonyCodeHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 	}
 
 	// Check if the code has expired
-	if colonyCode.CreatedAt.Add(time.Duration(colonyCode.ValidDurationMS) * time.Millisecond).Before(time.Now()) {
+	if colonyCode.ExpiredAt(time.Now()) {
 		c.Response().Header.Set(appContext.DDH, "Colony code has expired")
 		return fiber.NewError(fiber.StatusNotFound, "Colony code has expired")
 	}
@@ -1,14 +1,26 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"otte_main_backend/src/api/proxy"
 	"otte_main_backend/src/auth"
+	db "otte_main_backend/src/database"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/metrics"
+	"otte_main_backend/src/middleware"
+	"otte_main_backend/src/reload"
+	"otte_main_backend/src/vitec"
+	"sort"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func ApplyEndpoints(app *fiber.App, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
+func ApplyEndpoints(app *fiber.App, appContext *meta.ApplicationContext, authService *auth.AuthService, configReloader *reload.ConfigReloader) error {
+	applyReloadableSnapshots(app, appContext)
+	applyRequestDeadlines(app, appContext)
+	applyDatabaseDependencies(app, appContext)
 	if err := applyCatalog(app, appContext); err != nil {
 		return err
 	}
@@ -21,7 +33,7 @@ func ApplyEndpoints(app *fiber.App, appContext *meta.ApplicationContext, authSer
 	if err := applyCollectionApi(app, appContext); err != nil {
 		return err
 	}
-	if err := applyHealthApi(app, appContext); err != nil {
+	if err := applyHealthApi(app, appContext, authService); err != nil {
 		return err
 	}
 	if err := applyLocationApi(app, appContext); err != nil {
@@ -42,8 +54,128 @@ func ApplyEndpoints(app *fiber.App, appContext *meta.ApplicationContext, authSer
 	if err := applySessionApi(app, appContext, authService); err != nil {
 		return err
 	}
+	if err := applyAdminApi(app, appContext, authService, configReloader); err != nil {
+		return err
+	}
+	if err := applyInternalApi(app, appContext); err != nil {
+		return err
+	}
+	if err := vitec.ApplyDeprovisioningWebhook(app, appContext.Players, appContext.Colonies, func() string { return appContext.Current().DDH }, appContext.Config.Vitec, vitec.DeprovisioningHooks{
+		RevokeSessions: func(ctx context.Context, playerID uint32) error {
+			_, err := auth.RevokeAllSessionsForPlayer(ctx, playerID, appContext, authService)
+			return err
+		},
+		ForgetPlayer: auth.ForgetReferenceID,
+	}); err != nil {
+		return err
+	}
 	if err := proxy.ApplyProxyAPI(app, appContext); err != nil {
 		return err
 	}
 	return nil
 }
+
+// Every request is handled with the reloadable values published when it arrived, see meta.ApplicationContext.Snapshot.
+// A config reload therefore never waits for requests in flight, and requests never wait for a reload.
+func applyReloadableSnapshots(app *fiber.App, appContext *meta.ApplicationContext) {
+	app.Use(func(c *fiber.Ctx) error {
+		meta.BindToRequest(c, appContext.Snapshot())
+		return c.Next()
+	})
+}
+
+const requestTimeoutLocal = "requestTimeout"
+
+// Every request is given a deadline through its user context, which the database and multiplayer backend calls
+// made for it are bound to. Requests failing after exceeding it are answered with 504 and counted in metrics.RequestTimeouts.
+// The deadline is REQUEST_TIMEOUT_MS, or the override with the longest prefix matching the route.
+func applyRequestDeadlines(app *fiber.App, appContext *meta.ApplicationContext) {
+	var prefixes []string
+	for prefix := range appContext.Config.RequestTimeoutOverrides {
+		prefixes = append(prefixes, prefix)
+	}
+	//Shortest first, as the override applied last wins
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) < len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		timeout := appContext.Config.RequestTimeoutOverrides[prefix]
+		app.Use(prefix, func(c *fiber.Ctx) error {
+			c.Locals(requestTimeoutLocal, timeout)
+			return c.Next()
+		})
+	}
+
+	app.Use(func(c *fiber.Ctx) error {
+		timeout := appContext.Config.RequestTimeout
+		if override, ok := c.Locals(requestTimeoutLocal).(time.Duration); ok {
+			timeout = override
+		}
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) || responseStatus(c, err) < fiber.StatusInternalServerError {
+			return err
+		}
+		metrics.RequestTimeouts.Inc(c.Route().Path)
+		c.Response().Header.Set(meta.ForRequest(c, appContext).DDH, "Request timed out after "+timeout.String())
+		c.Status(fiber.StatusGatewayTimeout)
+		middleware.LogRequests(c)
+		return fiber.NewError(fiber.StatusGatewayTimeout, "Request timed out")
+	})
+}
+
+// The status the request is answered with, given the error returned by the handler
+func responseStatus(c *fiber.Ctx, err error) int {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	if err != nil {
+		return fiber.StatusInternalServerError
+	}
+	return c.Response().StatusCode()
+}
+
+// Endpoints by path prefix and the databases they can't do without, see applyDatabaseDependencies.
+// Endpoints not listed, like the health check, don't depend on any.
+var databaseDependencies = []struct {
+	prefix    string
+	databases []string
+}{
+	{"/api/v1/catalog", []string{db.LanguageDBName}},
+	{"/api/v1/asset", []string{db.ColonyAssetDBName}},
+	{"/api/v1/assets", []string{db.ColonyAssetDBName}},
+	{"/api/v1/lod", []string{db.ColonyAssetDBName}},
+	{"/api/v1/collection", []string{db.ColonyAssetDBName}},
+	{"/api/v1/location", []string{db.ColonyAssetDBName}},
+	{"/api/v1/minigame", []string{db.ColonyAssetDBName}},
+	{"/api/v1/colony", []string{db.ColonyAssetDBName}},
+	{"/api/v1/player", []string{db.PlayerDBName}},
+	{"/api/v1/player/:playerId/colony", []string{db.ColonyAssetDBName}},
+	{"/api/v1/player/:playerId/colonies", []string{db.ColonyAssetDBName}},
+	{"/api/v1/player/:playerId/minigame", []string{db.ColonyAssetDBName}},
+	{"/api/v1/session", []string{db.PlayerDBName}},
+	{"/api/v1/admin/player", []string{db.PlayerDBName}},
+	{"/api/v1/internal", []string{db.PlayerDBName}},
+	{"/api/v1/vitec", []string{db.PlayerDBName, db.ColonyAssetDBName}},
+}
+
+// Requests to an endpoint depending on a degraded database are refused up front with 503,
+// so the endpoints that don't keep being served while a database is down
+func applyDatabaseDependencies(app *fiber.App, appContext *meta.ApplicationContext) {
+	for _, dependency := range databaseDependencies {
+		databases := dependency.databases
+		app.Use(dependency.prefix, func(c *fiber.Ctx) error {
+			for _, database := range databases {
+				if !appContext.DBMonitor.IsHealthy(database) {
+					c.Response().Header.Set(meta.ForRequest(c, appContext).DDH, "Database unavailable: "+database)
+					c.Status(fiber.StatusServiceUnavailable)
+					middleware.LogRequests(c)
+					return fiber.NewError(fiber.StatusServiceUnavailable, "Service temporarily unavailable")
+				}
+			}
+			return c.Next()
+		})
+	}
+}
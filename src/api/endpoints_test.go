@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	db "otte_main_backend/src/database"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/metrics"
+	"otte_main_backend/src/repository"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDegradedDatabaseOnlyAffectsDependentEndpoints(t *testing.T) {
+	monitor := db.NewMonitor(time.Minute, db.Backoff{Base: time.Second, Max: time.Second})
+	monitor.Watch(db.PlayerDBName, nil, true)
+	monitor.Watch(db.ColonyAssetDBName, nil, false)
+	monitor.Watch(db.LanguageDBName, nil, true)
+	appContext := &meta.ApplicationContext{DBMonitor: monitor, DDH: "Test-DDH"}
+
+	app := fiber.New()
+	applyDatabaseDependencies(app, appContext)
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for path, expectedStatusCode := range map[string]int{
+		"/api/v1/health":             fiber.StatusOK,
+		"/api/v1/catalog/languages":  fiber.StatusOK,
+		"/api/v1/player/1":           fiber.StatusOK,
+		"/api/v1/player/1/colonies":  fiber.StatusServiceUnavailable,
+		"/api/v1/player/1/colony/2":  fiber.StatusServiceUnavailable,
+		"/api/v1/asset/8001":         fiber.StatusServiceUnavailable,
+		"/api/v1/assets":             fiber.StatusServiceUnavailable,
+		"/api/v1/colony/1/pathgraph": fiber.StatusServiceUnavailable,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal("failed to process the request:", err)
+		}
+		if resp.StatusCode != expectedStatusCode {
+			t.Errorf("%s: got %d, expected %d", path, resp.StatusCode, expectedStatusCode)
+		}
+	}
+}
+
+// A player repository where lookups only return once the request is done, as with a stalled database
+type stalledPlayerRepository struct {
+	repository.PlayerRepository
+}
+
+func (r stalledPlayerRepository) FindByID(ctx context.Context, id uint32) (*repository.Player, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestExceedingDeadlineIsAnsweredWithGatewayTimeout(t *testing.T) {
+	appContext := newTestAppContext(t)
+	appContext.Config.RequestTimeout = time.Hour
+	appContext.Config.RequestTimeoutOverrides = map[string]time.Duration{
+		"/api/v1":                  time.Hour,
+		"/api/v1/player/:playerId": 20 * time.Millisecond,
+	}
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1})
+
+	app := fiber.New()
+	applyRequestDeadlines(app, appContext)
+	if err := applyPlayerApi(app, appContext); err != nil {
+		t.Fatal("failed to apply player API:", err)
+	}
+
+	// Requests finishing in time are unaffected
+	testPlayerRequest(t, app, "GET", "/api/v1/player/1", "", fiber.StatusOK)
+
+	timeoutsBefore := metrics.RequestTimeouts.Get("/api/v1/player/:playerId")
+	appContext.Players = stalledPlayerRepository{}
+	req := httptest.NewRequest("GET", "/api/v1/player/1", nil)
+	req.Header.Set(testAuthTokenName, "OTTE-Token")
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatal("failed to process the request:", err)
+	}
+	if resp.StatusCode != fiber.StatusGatewayTimeout {
+		t.Errorf("expected the most specific override to apply and the request to time out, got status %d", resp.StatusCode)
+	}
+	if ddh := resp.Header.Get(appContext.DDH); ddh != "Request timed out after 20ms" {
+		t.Errorf("unexpected debug header: %q", ddh)
+	}
+	if timeouts := metrics.RequestTimeouts.Get("/api/v1/player/:playerId"); timeouts != timeoutsBefore+1 {
+		t.Errorf("expected the timeout to be counted once for the route, got %d more", timeouts-timeoutsBefore)
+	}
+}
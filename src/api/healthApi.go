@@ -3,12 +3,14 @@ package api
 import (
 	"log"
 	"otte_main_backend/src/auth"
+	db "otte_main_backend/src/database"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/metrics"
 	"otte_main_backend/src/multiplayer"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 type ServiceStatus struct {
@@ -17,6 +19,9 @@ type ServiceStatus struct {
 	LanguageDBConnection         bool                                `json:"languageDBStatus"`
 	PlayerDBConnection           bool                                `json:"playerDBStatus"`
 	MultiplayerBackendConnection bool                                `json:"multiplayerBackendStatus"`
+	Databases                    []db.DBStatus                       `json:"databases"`
+	SessionReaper                *auth.SessionReaperStats            `json:"sessionReaper"`
+	RequestTimeouts              metrics.CounterSnapshot             `json:"requestTimeouts"`
 	StatusMessage                string                              `json:"statusMessage"`
 	Timestamp                    string                              `json:"timestamp"`
 }
@@ -25,16 +30,16 @@ func rootHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	return c.Status(fiber.StatusOK).SendString("You've reached the backend.")
 }
 
-func healthRouteHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
-	//Check db connections here
-	colonyDBErr := appContext.ColonyAssetDB.Connection(func(tx *gorm.DB) error { return nil })
-	languageDBErr := appContext.LanguageDB.Connection(func(tx *gorm.DB) error { return nil })
-	playerDBErr := appContext.PlayerDB.Connection(func(tx *gorm.DB) error { return nil })
-	mbCheckResp := multiplayer.CheckConnection(appContext)
+func healthRouteHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
+	//Connectivity as of the latest check of the monitor, so this never waits on a database that is down
+	colonyDBHealthy := appContext.DBMonitor.IsHealthy(db.ColonyAssetDBName)
+	languageDBHealthy := appContext.DBMonitor.IsHealthy(db.LanguageDBName)
+	playerDBHealthy := appContext.DBMonitor.IsHealthy(db.PlayerDBName)
+	mbCheckResp := multiplayer.CheckConnection(c.UserContext(), appContext)
 	var statusMessage string
-	if colonyDBErr != nil || languageDBErr != nil || playerDBErr != nil {
-		c.Status(fiber.StatusInternalServerError)
-		statusMessage = "Database Connection Error"
+	if !colonyDBHealthy || !languageDBHealthy || !playerDBHealthy {
+		c.Status(fiber.StatusServiceUnavailable)
+		statusMessage = "Degraded, unavailable databases: " + strings.Join(appContext.DBMonitor.Degraded(), ", ")
 	} else {
 		c.Status(fiber.StatusOK)
 		statusMessage = "OK"
@@ -43,20 +48,28 @@ func healthRouteHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 	var status = ServiceStatus{
 		MultiplayerStatus:    mbCheckResp,
 		StatusMessage:        statusMessage,
-		ColonyDBConnection:   colonyDBErr == nil,
-		LanguageDBConnection: languageDBErr == nil,
-		PlayerDBConnection:   playerDBErr == nil,
+		ColonyDBConnection:   colonyDBHealthy,
+		LanguageDBConnection: languageDBHealthy,
+		PlayerDBConnection:   playerDBHealthy,
+		Databases:            appContext.DBMonitor.Statuses(),
+		RequestTimeouts:      metrics.RequestTimeouts.Snapshot(),
 		Timestamp:            time.Now().Format(time.RFC3339),
 	}
+	if authService.Reaper != nil {
+		reaperStats := authService.Reaper.Stats()
+		status.SessionReaper = &reaperStats
+	}
 	return c.JSON(status)
 }
 
-func applyHealthApi(app *fiber.App, appContext *meta.ApplicationContext) error {
+func applyHealthApi(app *fiber.App, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
 	log.Println("[Health API] Applying health API")
 
 	app.Get("/api/v1", auth.PrefixOn(appContext, rootHandler))
 
-	app.Get("/api/v1/health", auth.PrefixOn(appContext, healthRouteHandler))
+	app.Get("/api/v1/health", auth.PrefixOn(appContext, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		return healthRouteHandler(c, appContext, authService)
+	}))
 
 	return nil
 }
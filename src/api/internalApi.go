@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/meta"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// All active sessions of the player, newest first
+type ReferencedSessionsResponseDTO struct {
+	Sessions []ReferencedSessionResponseDTO `json:"sessions"`
+}
+
+type ReferencedSessionResponseDTO struct {
+	PlayerID    uint32    `json:"playerId"`
+	SessionID   uint32    `json:"sessionId"`
+	Role        auth.Role `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastCheckIn time.Time `json:"lastCheckIn"`
+}
+
+// Service-to-service endpoints (multiplayer backend, Vitec driven flows). Every route here must be wrapped in auth.InternalOnly.
+func applyInternalApi(app *fiber.App, appContext *meta.ApplicationContext) error {
+	log.Println("[Internal API] Applying internal API")
+
+	app.Get("/api/v1/internal/session/reference/:referenceID", auth.InternalOnly(appContext, getSessionByReferenceIDHandler))
+
+	return nil
+}
+
+func getSessionByReferenceIDHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	referenceID := c.Params("referenceID")
+	if referenceID == "" {
+		c.Response().Header.Set(appContext.DDH, "Missing referenceID")
+		return fiber.NewError(fiber.StatusBadRequest, "Missing referenceID")
+	}
+
+	sessions, err := auth.GetSessionsByReferenceID(c.UserContext(), referenceID, appContext)
+	if err != nil {
+		if errors.Is(err, auth.ErrNoActiveSession) {
+			c.Response().Header.Set(appContext.DDH, "No active session for referenceID "+referenceID)
+			return fiber.NewError(fiber.StatusNotFound, "No active session found")
+		}
+		log.Println("[Internal API] Unable to lookup sessions by referenceID: " + err.Error())
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+
+	response := ReferencedSessionsResponseDTO{Sessions: make([]ReferencedSessionResponseDTO, 0, len(sessions))}
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, ReferencedSessionResponseDTO{
+			PlayerID:    session.Player,
+			SessionID:   session.ID,
+			Role:        session.Role,
+			CreatedAt:   session.CreatedAt,
+			LastCheckIn: auth.LatestCheckIn(&session),
+		})
+	}
+	c.Status(fiber.StatusOK)
+	return c.JSON(response)
+}
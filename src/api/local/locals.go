@@ -4,4 +4,5 @@ type LocalType = int
 
 const (
 	Session LocalType = iota
+	Role
 )
@@ -5,9 +5,9 @@ import (
 	"log"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 func applyLocationApi(app *fiber.App, appContext *meta.ApplicationContext) error {
@@ -76,108 +76,6 @@ type MinigameDifficultyLocationDTO struct {
 	IconID      uint32 `json:"iconID"`
 }
 
-// LocationModel represents the Location table
-type LocationModel struct {
-	ID          uint32                    `gorm:"column:id;primaryKey"`
-	Name        string                    `gorm:"column:name"`
-	Description string                    `gorm:"column:description"`
-	MinigameID  uint32                    `gorm:"column:minigame"`
-	Minigame    MinigameModel             `gorm:"foreignKey:MinigameID"`
-	Appearances []LocationAppearanceModel `gorm:"foreignKey:LocationID"`
-}
-
-func (lm *LocationModel) TableName() string {
-	return "Location"
-}
-
-type LocationAppearanceModel struct {
-	ID                uint32               `gorm:"column:id;primaryKey"`
-	Level             int                  `gorm:"column:level"`
-	LocationID        uint32               `gorm:"column:location"`
-	SplashArt         uint32               `json:"splashArt" gorm:"column:splashArt"`
-	AssetCollectionID uint32               `gorm:"column:assetCollection"`
-	AssetCollection   AssetCollectionModel `gorm:"foreignKey:AssetCollectionID"`
-}
-
-func (lam *LocationAppearanceModel) TableName() string {
-	return "LocationAppearance"
-}
-
-type AssetCollectionModel struct {
-	ID                uint32                 `gorm:"column:id;primaryKey"`
-	Name              string                 `gorm:"column:name"`
-	UseCase           string                 `gorm:"column:useCase"`
-	CollectionEntries []CollectionEntryModel `gorm:"foreignKey:AssetCollectionID"`
-}
-
-func (acm *AssetCollectionModel) TableName() string {
-	return "AssetCollection"
-}
-
-type CollectionEntryModel struct {
-	ID                uint32              `gorm:"column:id;primaryKey"`
-	GraphicalAssetID  uint32              `gorm:"column:graphicalAsset"`
-	GraphicalAsset    GraphicalAssetModel `gorm:"foreignKey:GraphicalAssetID"`
-	TransformID       uint32              `gorm:"column:transform"`
-	Transform         TransformModel      `gorm:"foreignKey:TransformID"`
-	AssetCollectionID uint32              `gorm:"column:assetCollection"`
-}
-
-func (cem *CollectionEntryModel) TableName() string {
-	return "CollectionEntry"
-}
-
-type GraphicalAssetModel struct {
-	ID      uint32     `gorm:"column:id;primaryKey"`
-	Alias   string     `gorm:"column:alias"`
-	Type    string     `gorm:"column:type"`
-	UseCase string     `gorm:"column:useCase"`
-	Width   int        `gorm:"column:width"`
-	Height  int        `gorm:"column:height"`
-	LODs    []LODModel `gorm:"foreignKey:GraphicalAssetID"`
-}
-
-func (ga *GraphicalAssetModel) TableName() string {
-	return "GraphicalAsset"
-}
-
-type LODModel struct {
-	ID               uint32 `gorm:"column:id;primaryKey"`
-	DetailLevel      int    `gorm:"column:detailLevel"`
-	Type             string `gorm:"column:type"`
-	GraphicalAssetID uint32 `gorm:"column:graphicalAsset"`
-}
-
-func (lm *LODModel) TableName() string {
-	return "LOD"
-}
-
-type MinigameModel struct {
-	ID           uint32                    `gorm:"column:id;primaryKey"`
-	Name         string                    `gorm:"column:name"`
-	Description  string                    `gorm:"column:description"`
-	IconID       uint32                    `gorm:"column:icon"`
-	Icon         GraphicalAssetModel       `gorm:"foreignKey:IconID"`
-	Difficulties []MinigameDifficultyModel `gorm:"foreignKey:MinigameID"`
-}
-
-func (mm *MinigameModel) TableName() string {
-	return "MiniGame"
-}
-
-type MinigameDifficultyModel struct {
-	ID          uint32              `gorm:"column:id;primaryKey"`
-	Name        string              `gorm:"column:name"`
-	Description string              `gorm:"column:description"`
-	IconID      uint32              `gorm:"column:icon"`
-	Icon        GraphicalAssetModel `gorm:"foreignKey:IconID"`
-	MinigameID  uint32              `gorm:"column:minigame"`
-}
-
-func (mdm *MinigameDifficultyModel) TableName() string {
-	return "MiniGameDifficulty"
-}
-
 func getLocationInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	locationID, err := c.ParamsInt("locationID")
 	if err != nil {
@@ -185,12 +83,9 @@ func getLocationInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) e
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid location ID")
 	}
 
-	var location LocationModel
-	if err := appContext.ColonyAssetDB.
-		Preload("Appearances").
-		Where("id = ?", locationID).
-		First(&location).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	location, err := appContext.Colonies.FindWorldLocation(c.UserContext(), uint32(locationID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Location not found "+err.Error())
 			return fiber.NewError(fiber.StatusNotFound, "Location not found")
 		}
@@ -240,15 +135,9 @@ func getLocationFullInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid location ID")
 	}
 
-	var location LocationModel
-	if err := appContext.ColonyAssetDB.
-		Preload("Minigame.Icon").
-		Preload("Minigame.Difficulties.Icon").
-		Preload("Appearances.AssetCollection.CollectionEntries.GraphicalAsset.LODs").
-		Preload("Appearances.AssetCollection.CollectionEntries.Transform").
-		Where("id = ?", locationID).
-		First(&location).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	location, err := appContext.Colonies.FindWorldLocationFull(c.UserContext(), uint32(locationID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Location not found "+err.Error())
 			return fiber.NewError(fiber.StatusNotFound, "Location not found")
 		}
@@ -267,7 +156,7 @@ func getLocationFullInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 			lods := make([]AssetLODDTO, len(entry.GraphicalAsset.LODs))
 			for k, lod := range entry.GraphicalAsset.LODs {
 				lods[k] = AssetLODDTO{
-					DetailLevel: uint32(lod.DetailLevel),
+					DetailLevel: lod.DetailLevel,
 					ID:          lod.ID,
 				}
 			}
@@ -281,7 +170,7 @@ func getLocationFullInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 					YScale:  entry.Transform.YScale,
 					XOffset: entry.Transform.XOffset,
 					YOffset: entry.Transform.YOffset,
-					ZIndex:  uint32(entry.Transform.ZIndex),
+					ZIndex:  entry.Transform.ZIndex,
 				},
 				Asset: MinimizedAssetLocationDTO{
 					ID:     entry.GraphicalAsset.ID,
@@ -305,7 +194,7 @@ func getLocationFullInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 		ID:           location.Minigame.ID,
 		Name:         location.Minigame.Name,
 		Description:  location.Minigame.Description,
-		IconID:       location.Minigame.IconID,
+		IconID:       location.Minigame.Icon,
 		Difficulties: []MinigameDifficultyLocationDTO{},
 	}
 
@@ -313,7 +202,7 @@ func getLocationFullInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 		minigame.Difficulties = append(minigame.Difficulties, MinigameDifficultyLocationDTO{
 			Name:        difficulty.Name,
 			Description: difficulty.Description,
-			IconID:      difficulty.IconID,
+			IconID:      difficulty.Icon,
 		})
 	}
 
@@ -6,11 +6,11 @@ import (
 	"net/http"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 /**
@@ -36,19 +36,7 @@ func applyLodApi(app *fiber.App, appContext *meta.ApplicationContext) error {
 	return nil
 }
 
-type LOD struct {
-	ID             uint32 `json:"id"`
-	DetailLevel    uint32 `json:"detailLevel" gorm:"column:detailLevel"`
-	GraphicalAsset uint32 `json:"graphicalAsset" gorm:"column:graphicalAsset"`
-	Blob           []byte `json:"blob" gorm:"column:blob"`
-	ETag           string `json:"etag" gorm:"column:etag"`
-	MIMEType       string `json:"type" gorm:"column:type"`
-}
-
-func (lod *LOD) TableName() string {
-	//Gorm would otherwise overwrite the "LOD" to "lo_d" or smth like that
-	return "LOD"
-}
+type LOD = repository.LOD
 
 const detailLevelHeaderName = "URSA-DETAIL-LEVEL"
 const assetIDHeaderName = "URSA-ASSET-ID"
@@ -59,9 +47,9 @@ func getLODByIDHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 	if idErr != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid ID: "+idErr.Error())
 	}
-	var lod LOD
-	if dbErr := appContext.ColonyAssetDB.Where("id = ?", id).First(&lod).Error; dbErr != nil {
-		if errors.Is(dbErr, gorm.ErrRecordNotFound) {
+	lod, dbErr := appContext.Assets.FindLOD(c.UserContext(), uint32(id))
+	if dbErr != nil {
+		if errors.Is(dbErr, repository.ErrNotFound) {
 			c.Status(fiber.StatusNotFound)
 			c.Response().Header.Set(appContext.DDH, "No such LOD")
 			return fiber.NewError(fiber.StatusNotFound, "LOD not found")
@@ -71,7 +59,7 @@ func getLODByIDHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 	c.Status(fiber.StatusOK)
-	SetHeadersForLODBlob(c, &lod)
+	SetHeadersForLODBlob(c, lod)
 	return c.Send(lod.Blob)
 }
 
@@ -6,44 +6,18 @@ import (
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
 	"otte_main_backend/src/middleware"
+	"otte_main_backend/src/repository"
+	"otte_main_backend/src/vitec"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/datatypes"
-	"gorm.io/gorm"
 )
 
-type MinimizedMinigameDTO struct {
-	Settings            datatypes.JSON `json:"settings"`
-	OverwritingSettings datatypes.JSON `json:"overwritingSettings" gorm:"column:overwritingSettings"`
-}
-
-type MinigameDifficultyDTO struct {
-	ID                  uint32 `json:"id"`
-	Name                string `json:"name"`
-	MinigameID          uint32 `json:"-" gorm:"column:minigame"`
-	Icon                uint32 `json:"icon"`
-	Description         string `json:"description"`
-	RequiredLevel       uint32 `json:"requiredLevel" gorm:"column:requiredLevel"`
-	OverwritingSettings string `json:"overwritingSettings" gorm:"column:overwritingSettings"`
-}
-
-func (mdDTO *MinigameDifficultyDTO) TableName() string {
-	return "MiniGameDifficulty"
-}
+type MinimizedMinigameDTO = repository.MinigameSettings
 
-type MinigameInfoDTO struct {
-	ID           uint32                  `json:"id"`
-	Name         string                  `json:"name"`
-	Icon         uint32                  `json:"icon"`
-	Description  string                  `json:"description"`
-	Settings     string                  `json:"settings"`
-	Difficulties []MinigameDifficultyDTO `json:"difficulties" gorm:"foreignKey:MinigameID;references:ID"`
-}
+type MinigameDifficultyDTO = repository.MinigameDifficulty
 
-func (mInfoDTO *MinigameInfoDTO) TableName() string {
-	return "MiniGame"
-}
+type MinigameInfoDTO = repository.Minigame
 
 func applyMinigameApi(app *fiber.App, appContext *meta.ApplicationContext) error {
 	log.Println("[Minigame API] Applying Minigame API")
@@ -51,7 +25,7 @@ func applyMinigameApi(app *fiber.App, appContext *meta.ApplicationContext) error
 	//Fiber is so stateful that it infers "minimized" as a query param if registered after minigames/:id
 	//y
 	app.Get("/api/v1/minigame/minimized", func(c *fiber.Ctx) error {
-		return getMinimizedMinigameHandler(c, appContext)
+		return getMinimizedMinigameHandler(c, meta.ForRequest(c, appContext))
 	})
 
 	app.Get("/api/v1/minigame/:id", auth.PrefixOn(appContext, getMinigameInfoHandler))
@@ -77,20 +51,13 @@ func getMinimizedMinigameHandler(c *fiber.Ctx, appContext *meta.ApplicationConte
 		return fiber.NewError(fiber.StatusBadRequest, "Error in parsing minigame difficulty "+diffParseErr.Error())
 	}
 
-	var minigame MinimizedMinigameDTO
-	if err := appContext.ColonyAssetDB.
-		Table("MiniGame").
-		Table("MiniGame").
-		Select(`"MiniGame".settings, "MiniGameDifficulty"."overwritingSettings"`).
-		Joins(`JOIN "MiniGameDifficulty" ON "MiniGame".id = ?`, minigameID).
-		Where(`"MiniGameDifficulty".id = ?`, diffID).
-		Scan(&minigame).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "No such minigame or difficulty")
+	minigame, err := appContext.Minigames.FindSettings(c.UserContext(), uint32(minigameID), uint32(diffID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "No such minigame or minigame difficulty")
 			c.Status(fiber.StatusNotFound)
 			middleware.LogRequests(c)
-			return fiber.NewError(fiber.StatusNotFound, "No such minigame or difficulty")
+			return fiber.NewError(fiber.StatusNotFound, "No such minigame or minigame difficulty")
 		}
 
 		c.Response().Header.Set(appContext.DDH, "Error in fetching minigame "+err.Error())
@@ -99,13 +66,6 @@ func getMinimizedMinigameHandler(c *fiber.Ctx, appContext *meta.ApplicationConte
 		return fiber.NewError(fiber.StatusInternalServerError, "Error in fetching minigame "+err.Error())
 	}
 
-	if minigame.Settings == nil || minigame.OverwritingSettings == nil {
-		c.Response().Header.Set(appContext.DDH, "No such minigame or minigame difficulty")
-		c.Status(fiber.StatusNotFound)
-		middleware.LogRequests(c)
-		return fiber.NewError(fiber.StatusNotFound, "No such minigame or minigame difficulty")
-	}
-
 	c.Status(fiber.StatusOK)
 	middleware.LogRequests(c)
 	return c.JSON(minigame)
@@ -120,13 +80,9 @@ func getMinigameInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) e
 		return fiber.NewError(fiber.StatusBadRequest, "Error in parsing minigame id "+parseErr.Error())
 	}
 
-	var minigame MinigameInfoDTO
-	if err := appContext.ColonyAssetDB.
-		Preload("Difficulties").
-		Where(`"MiniGame".id = ?`, id).
-		First(&minigame).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	minigame, err := appContext.Minigames.FindByID(c.UserContext(), uint32(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "No such minigame")
 			return fiber.NewError(fiber.StatusNotFound, "No such minigame")
 		}
@@ -138,3 +94,60 @@ func getMinigameInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) e
 	c.Status(fiber.StatusOK)
 	return c.JSON(minigame)
 }
+
+// Scores are reported as the percentage of the maximum attainable score
+const (
+	MINIGAME_SCORE_MIN = 0
+	MINIGAME_SCORE_MAX = 100
+)
+
+type CompleteMinigameRequestDTO struct {
+	DifficultyID uint32 `json:"difficultyId"`
+	// Required, from MINIGAME_SCORE_MIN to MINIGAME_SCORE_MAX
+	Score *int `json:"score"`
+}
+
+// Reports the completion of a minigame as learning progress to Vitec
+func completeMinigameHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	playerId, parseErr := c.ParamsInt("playerId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid player ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+	}
+	minigameId, parseErr := c.ParamsInt("minigameId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid minigame ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid minigame ID")
+	}
+	var request CompleteMinigameRequestDTO
+	if err := c.BodyParser(&request); err != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid request body "+err.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	//The score is reported to Vitec as is, so anything the client can't have scored is refused
+	if request.Score == nil || *request.Score < MINIGAME_SCORE_MIN || *request.Score > MINIGAME_SCORE_MAX {
+		c.Response().Header.Set(appContext.DDH, "Invalid score, expected "+strconv.Itoa(MINIGAME_SCORE_MIN)+" to "+strconv.Itoa(MINIGAME_SCORE_MAX))
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid score")
+	}
+
+	difficulty, err := appContext.Minigames.FindDifficulty(c.UserContext(), uint32(minigameId), request.DifficultyID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "No such difficulty for minigame")
+			return fiber.NewError(fiber.StatusBadRequest, "No such difficulty for minigame")
+		}
+		log.Println("[Minigame API] Unable to lookup minigame difficulty: " + err.Error())
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+
+	appContext.VitecIntegration.ReportProgress(c.UserContext(), uint32(playerId), vitec.ProgressMinigameCompletion, map[string]interface{}{
+		"minigameId":   minigameId,
+		"difficultyId": difficulty.ID,
+		"difficulty":   difficulty.Name,
+		"score":        *request.Score,
+	})
+
+	c.Status(fiber.StatusOK)
+	return nil
+}
@@ -3,36 +3,22 @@ package api
 import (
 	"errors"
 	"log"
-	"otte_main_backend/src/api/colony"
 	"otte_main_backend/src/auth"
-	"otte_main_backend/src/database"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
+	"otte_main_backend/src/vitec"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 // PlayerPreferenceModel represents a single preference item.
-type PlayerPreferenceModel struct {
-	ID            uint32 `json:"id"`
-	Player        uint32 `json:"player" gorm:"column:player;foreignKey:player;references:ID"`
-	PreferenceKey string `json:"key" gorm:"column:preferenceKey;foreignKey:preferenceKey;references:preferenceKey"`
-	ChosenValue   string `json:"chosenValue" gorm:"column:chosenValue"`
-}
-
-func (p *PlayerPreferenceModel) TableName() string {
-	return "PlayerPreference"
-}
+type PlayerPreferenceModel = repository.PlayerPreference
 
 type PlayerPreferenceResponseDTO struct {
-	PreferenceKey string `json:"key" gorm:"column:preferenceKey;foreignKey:preferenceKey;references:preferenceKey"`
-	ChosenValue   string `json:"chosenValue" gorm:"column:chosenValue"`
-}
-
-func (p *PlayerPreferenceResponseDTO) TableName() string {
-	return "PlayerPreference"
+	PreferenceKey string `json:"key"`
+	ChosenValue   string `json:"chosenValue"`
 }
 
 // PlayerPreferencesResponse represents the data returned for a player's preferences.
@@ -76,24 +62,30 @@ type ColonyOverviewResponse struct {
 func applyPlayerApi(app *fiber.App, appContext *meta.ApplicationContext) error {
 	log.Println("[Player API] Applying Player API")
 
-	app.Post("/api/v1/player/:playerId/achievement/:achievementId", auth.PrefixOn(appContext, grantPlayerAchievementHandler))
+	// All player routes are only accessible to the player itself
+	ownsPlayer := auth.PlayerParamMatchesSession("playerId")
+
+	app.Post("/api/v1/player/:playerId/achievement/:achievementId", auth.PrefixOn(appContext, grantPlayerAchievementHandler, ownsPlayer))
+
+	// Route for reporting the completion of a minigame
+	app.Post("/api/v1/player/:playerId/minigame/:minigameId/complete", auth.PrefixOn(appContext, completeMinigameHandler, ownsPlayer))
 
 	// Route for fetching a player's preferences by their ID
-	app.Get("/api/v1/player/:playerId/preferences", auth.PrefixOn(appContext, getPlayerPreferencesHandler))
+	app.Get("/api/v1/player/:playerId/preferences", auth.PrefixOn(appContext, getPlayerPreferencesHandler, ownsPlayer))
 
-	app.Post("/api/v1/player/:playerId/preferences", auth.PrefixOn(appContext, setPlayerPreferenceHandler))
+	app.Post("/api/v1/player/:playerId/preferences", auth.PrefixOn(appContext, setPlayerPreferenceHandler, ownsPlayer))
 
 	// Route for fetching colony info by colonyId and playerId
-	app.Get("/api/v1/player/:playerId/colony/:colonyId", auth.PrefixOn(appContext, getColonyInfoHandler))
+	app.Get("/api/v1/player/:playerId/colony/:colonyId", auth.PrefixOn(appContext, getColonyInfoHandler, ownsPlayer))
 
 	// Route for fetching overview of all colonies for a player
-	app.Get("/api/v1/player/:playerId/colonies", auth.PrefixOn(appContext, getColonyOverviewHandler))
+	app.Get("/api/v1/player/:playerId/colonies", auth.PrefixOn(appContext, getColonyOverviewHandler, ownsPlayer))
 
 	// Route for creating a new colony
-	app.Post("/api/v1/player/:playerId/colony/create", auth.PrefixOn(appContext, createColonyHandler))
+	app.Post("/api/v1/player/:playerId/colony/create", auth.PrefixOn(appContext, createColonyHandler, ownsPlayer))
 
 	// Route for fetching a single player's info by their ID
-	app.Get("/api/v1/player/:playerId", auth.PrefixOn(appContext, getPlayerInfoHandler))
+	app.Get("/api/v1/player/:playerId", auth.PrefixOn(appContext, getPlayerInfoHandler, ownsPlayer))
 	return nil
 }
 
@@ -102,15 +94,7 @@ type SetPreferenceRequestDTO struct {
 	Value string `json:"value"`
 }
 
-type AvailablePreferenceModel struct {
-	ID              uint32             `gorm:"primaryKey"`
-	PreferenceKey   string             `gorm:"column:preferenceKey"`
-	AvailableValues util.PGStringArray `gorm:"column:availableValues"`
-}
-
-func (a *AvailablePreferenceModel) TableName() string {
-	return "AvailablePreference"
-}
+type AvailablePreferenceModel = repository.AvailablePreference
 
 func setPlayerPreferenceHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	playerId, parseErr := c.ParamsInt("playerId")
@@ -126,9 +110,8 @@ func setPlayerPreferenceHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 	}
 
 	// Check if the preference key exists
-	var availablePreference AvailablePreferenceModel
-	if err := appContext.PlayerDB.Where(`"preferenceKey" = ?`, request.Key).First(&availablePreference).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	if _, err := appContext.Players.FindAvailablePreference(c.UserContext(), request.Key); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "No such preference")
 			return fiber.NewError(fiber.StatusNotFound, "No such preference")
 		}
@@ -137,24 +120,8 @@ func setPlayerPreferenceHandler(c *fiber.Ctx, appContext *meta.ApplicationContex
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	var existingPreference PlayerPreferenceModel
-	if err := appContext.PlayerDB.Where(`"player" = ? AND "preferenceKey" = ?`, playerId, request.Key).First(&existingPreference).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Internal server error")
-			return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
-		}
-	}
-
-	// If the preference already exists, update it
-	var updatedPreference = PlayerPreferenceModel{
-		ID:            existingPreference.ID,
-		Player:        uint32(playerId),
-		PreferenceKey: request.Key,
-		ChosenValue:   request.Value,
-	}
-
-	if setValueErr := appContext.PlayerDB.Table("PlayerPreference").
-		Save(&updatedPreference).Error; setValueErr != nil {
+	// If the preference already exists, it is updated
+	if setValueErr := appContext.Players.SetPreference(c.UserContext(), uint32(playerId), request.Key, request.Value); setValueErr != nil {
 		c.Response().Header.Set(appContext.DDH, "Internal server error")
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
@@ -174,9 +141,9 @@ func grantPlayerAchievementHandler(c *fiber.Ctx, appContext *meta.ApplicationCon
 		c.Response().Header.Set(appContext.DDH, "Invalid achievement ID "+parseErr.Error())
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid achievement ID "+parseErr.Error())
 	}
-	var achievement AchievementModel
-	if achievementExistErr := appContext.PlayerDB.Where("id = ?", achievementId).First(&achievement).Error; achievementExistErr != nil {
-		if !errors.Is(achievementExistErr, gorm.ErrRecordNotFound) {
+	achievement, achievementExistErr := appContext.Players.FindAchievement(c.UserContext(), uint32(achievementId))
+	if achievementExistErr != nil {
+		if !errors.Is(achievementExistErr, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Internal error")
 			return fiber.NewError(fiber.StatusNotFound, "Internal error")
 		}
@@ -185,10 +152,10 @@ func grantPlayerAchievementHandler(c *fiber.Ctx, appContext *meta.ApplicationCon
 		return fiber.NewError(fiber.StatusNotFound, "Achievement does not exist "+achievementExistErr.Error())
 	}
 
-	//Separate check needed as the insert statement doesn't actually error
-	//if the player doesn't exist
-	if playerExistErr := appContext.PlayerDB.Table("Player").Where("id = ?", playerId).First(&PlayerDTO{}).Error; playerExistErr != nil {
-		if !errors.Is(playerExistErr, gorm.ErrRecordNotFound) {
+	//Looked up first, as the achievements the player already has decide whether progress is reported
+	existingPlayer, playerExistErr := appContext.Players.FindByID(c.UserContext(), uint32(playerId))
+	if playerExistErr != nil {
+		if !errors.Is(playerExistErr, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Internal error")
 			return fiber.NewError(fiber.StatusNotFound, "Internal error")
 		}
@@ -197,8 +164,8 @@ func grantPlayerAchievementHandler(c *fiber.Ctx, appContext *meta.ApplicationCon
 		return fiber.NewError(fiber.StatusNotFound, "Player does not exist "+playerExistErr.Error())
 	}
 
-	if insertErr := GrantPlayerAchievement(appContext.PlayerDB, uint32(playerId), uint32(achievementId)); insertErr != nil {
-		if !errors.Is(insertErr, gorm.ErrRecordNotFound) {
+	if insertErr := appContext.Players.GrantAchievement(c.UserContext(), uint32(playerId), uint32(achievementId)); insertErr != nil {
+		if !errors.Is(insertErr, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Internal error")
 			return fiber.NewError(fiber.StatusNotFound, "Internal error")
 		}
@@ -206,21 +173,18 @@ func grantPlayerAchievementHandler(c *fiber.Ctx, appContext *meta.ApplicationCon
 		c.Response().Header.Set(appContext.DDH, "No such player")
 		return fiber.NewError(fiber.StatusInternalServerError, "No such player")
 	}
+	//Granting is idempotent, only newly granted achievements are reported
+	if !util.ArrayContains(existingPlayer.Achievements, achievementId) {
+		appContext.VitecIntegration.ReportProgress(c.UserContext(), uint32(playerId), vitec.ProgressAchievement, map[string]interface{}{
+			"achievementId": achievementId,
+			"title":         achievement.Title,
+		})
+	}
 
 	c.Status(fiber.StatusOK)
 	return nil
 }
 
-func GrantPlayerAchievement(db database.PlayerDB, playerId uint32, achievementID uint32) error {
-	result := db.Exec(`
-        UPDATE "Player"
-        SET achievements = ARRAY(SELECT DISTINCT UNNEST(array_append(achievements, ?)))
-        WHERE "id" = ?
-    `, achievementID, playerId)
-
-	return result.Error
-}
-
 func getPlayerPreferencesHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	playerIdStr := c.Params("playerId")
 	playerId, parseErr := strconv.Atoi(playerIdStr)
@@ -229,46 +193,33 @@ func getPlayerPreferencesHandler(c *fiber.Ctx, appContext *meta.ApplicationConte
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID "+parseErr.Error())
 	}
 
-	if !doesPlayerExist(appContext.PlayerDB, playerId) {
-		c.Response().Header.Set(appContext.DDH, "Player does not exist")
-		return fiber.NewError(fiber.StatusNotFound, "Player does not exist")
+	if _, err := appContext.Players.FindByID(c.UserContext(), uint32(playerId)); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Player does not exist")
+			return fiber.NewError(fiber.StatusNotFound, "Player does not exist")
+		}
+		c.Response().Header.Set(appContext.DDH, "Internal server error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	// Fetch player preferences and join them with available values
-	var preferences []PlayerPreferenceResponseDTO
-	if err := appContext.PlayerDB.
-		Table(`PlayerPreference`).
-		Where(`"PlayerPreference".player = ?`, playerId).
-		Select(`"PlayerPreference".id,
-				"PlayerPreference"."preferenceKey",
-				"PlayerPreference"."chosenValue",
-				"AvailablePreference"."availableValues"`).
-		Joins(`JOIN "AvailablePreference" ON "PlayerPreference"."preferenceKey" = "AvailablePreference"."preferenceKey"`).
-		Find(&preferences).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Preferences not found "+err.Error())
-			return fiber.NewError(fiber.StatusNotFound, "Preferences not found "+err.Error())
-		}
+	// Fetch player preferences, only those still available are included
+	preferences, err := appContext.Players.FindPreferences(c.UserContext(), uint32(playerId))
+	if err != nil {
 		c.Response().Header.Set(appContext.DDH, "Internal server error")
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
 	// Return the preferences in a structured format
-	response := PlayerPreferencesResponse{Preferences: preferences}
+	response := PlayerPreferencesResponse{
+		Preferences: util.ArrayMap(preferences, func(preference PlayerPreferenceModel) PlayerPreferenceResponseDTO {
+			return PlayerPreferenceResponseDTO{PreferenceKey: preference.PreferenceKey, ChosenValue: preference.ChosenValue}
+		}),
+	}
 
 	c.Status(fiber.StatusOK)
 	return c.JSON(response)
 }
 
-func doesPlayerExist(db database.PlayerDB, playerId int) bool {
-	var player PlayerDTO
-	if err := db.Table("Player").Where("id = ?", playerId).First(&player).Error; err != nil {
-		return false
-	}
-	return true
-}
-
 func getPlayerInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
 	playerId, parseErr := c.ParamsInt("playerId")
 	if parseErr != nil {
@@ -277,14 +228,9 @@ func getPlayerInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 	}
 
 	// Fetch player information from the database
-	var player PlayerDTO
-	if err := appContext.PlayerDB.
-		Table("Player").
-		Select(`id, "firstName", "lastName", sprite, achievements`).
-		Where("id = ?", playerId).
-		First(&player).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	player, err := appContext.Players.FindByID(c.UserContext(), uint32(playerId))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			c.Response().Header.Set(appContext.DDH, "Player not found "+err.Error())
 			return fiber.NewError(fiber.StatusNotFound, "Player not found "+err.Error())
 		}
@@ -294,29 +240,20 @@ func getPlayerInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
 
-	// Compute the 'HasCompletedTutorial' field
-	// Achievement id 1 is always the tutorial achievement
-	player.HasCompletedTutorial = util.ArrayContains(player.Achievements, 1)
-
 	c.Status(fiber.StatusOK)
-	return c.JSON(player)
+	return c.JSON(PlayerDTO{
+		ID:           player.ID,
+		FirstName:    player.FirstName,
+		LastName:     player.LastName,
+		Sprite:       player.Sprite,
+		Achievements: player.Achievements,
+		// Achievement id 1 is always the tutorial achievement
+		HasCompletedTutorial: util.ArrayContains(player.Achievements, 1),
+	})
 }
 
 // Model
-type ColonyModel struct {
-	ID          uint32 `gorm:"primaryKey"`
-	Name        string
-	AccLevel    uint32 `gorm:"column:accLevel"`
-	LatestVisit string `gorm:"column:latestVisit"`
-	ColonyCode  uint32 `gorm:"foreignKey:ColonyCode;references:ID;column:colonyCode"`
-	Owner       uint32
-	Assets      util.PGIntArray
-	Locations   util.PGIntArray
-}
-
-func (c *ColonyModel) TableName() string {
-	return "Colony"
-}
+type ColonyModel = repository.Colony
 
 type AssetCollectionID struct {
 	ID uint32 `gorm:"primaryKey"`
@@ -326,17 +263,7 @@ func (a *AssetCollectionID) TableName() string {
 	return "AssetCollection"
 }
 
-type ColonyLocationModel struct {
-	ID        uint32 `gorm:"primaryKey"`
-	Level     uint32
-	Colony    uint32 `gorm:"foreignKey:Colony;references:ID"`
-	Transform uint32 `gorm:"foreignKey:Transform;references:ID"`
-	Location  uint32 `gorm:"foreignKey:Location;references:ID"`
-}
-
-func (a *ColonyLocationModel) TableName() string {
-	return "ColonyLocation"
-}
+type ColonyLocationModel = repository.ColonyLocation
 
 type LocationTransformTuple struct {
 	Transform  CLIFormatTransform `json:"transform"`
@@ -364,13 +291,13 @@ func getColonyInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 	}
 
 	// Fetch the colony information
-	var colony ColonyModel
-	if err := appContext.ColonyAssetDB.
-		Where("id = ? AND owner = ?", colonyId, playerId).
-		First(&colony).Error; err != nil {
-
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "Colony not found "+err.Error())
+	colony, err := appContext.Colonies.FindByID(c.UserContext(), uint32(colonyId))
+	if err == nil && colony.Owner != uint32(playerId) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "Colony not found")
 			return fiber.NewError(fiber.StatusNotFound, "Colony not found")
 		}
 
@@ -380,47 +307,31 @@ func getColonyInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 
 	colonyAssets := make([]AssetTransformTuple, 0, len(colony.Assets))
 	for _, colonyAssetID := range colony.Assets {
-		var transform TransformDTO
-		subQuery := appContext.ColonyAssetDB.
-			Table("ColonyAsset").
-			Select("transform").
-			Where("id = ?", colonyAssetID)
-		if err := appContext.ColonyAssetDB.
-			Table("Transform").
-			Where("id = (?)", subQuery).
-			First(&transform).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.Response().Header.Set(appContext.DDH, "No such transform "+err.Error())
-				return fiber.NewError(fiber.StatusNotFound, "No such transform")
+		colonyAsset, err := appContext.Colonies.FindAsset(c.UserContext(), uint32(colonyAssetID))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.Response().Header.Set(appContext.DDH, "No such ColonyAsset")
+				return fiber.NewError(fiber.StatusNotFound, "No such ColonyAsset")
 			}
 
-			c.Response().Header.Set(appContext.DDH, "Error fetching transforms "+err.Error())
-			return fiber.NewError(fiber.StatusInternalServerError, "Error fetching transforms")
+			c.Response().Header.Set(appContext.DDH, "Error fetching ColonyAsset "+err.Error())
+			return fiber.NewError(fiber.StatusInternalServerError, "Error fetching ColonyAsset")
 		}
 
-		// Get the assetCollection ID from the ColonyAsset's assetCollection field
-		var assetCollectionID uint32
-		if err := appContext.ColonyAssetDB.
-			Table("ColonyAsset").
-			Select(`"assetCollection"`).
-			Where("id = ?", colonyAssetID).
-			Scan(&assetCollectionID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.Response().Header.Set(appContext.DDH, "No such ColonyAsset "+err.Error())
-				return fiber.NewError(fiber.StatusNotFound, "No such ColonyAsset")
+		transform, err := appContext.Colonies.FindTransform(c.UserContext(), colonyAsset.Transform)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.Response().Header.Set(appContext.DDH, "No such transform")
+				return fiber.NewError(fiber.StatusNotFound, "No such transform")
 			}
 
-			c.Response().Header.Set(appContext.DDH, "Error fetching assetCollection ID "+err.Error())
-			return fiber.NewError(fiber.StatusInternalServerError, "Error fetching assetCollection ID")
+			c.Response().Header.Set(appContext.DDH, "Error fetching transforms "+err.Error())
+			return fiber.NewError(fiber.StatusInternalServerError, "Error fetching transforms")
 		}
 
 		// Verify the AssetCollection exists
-		exists := false
-		if err := appContext.ColonyAssetDB.
-			Table("AssetCollection").
-			Select("1").
-			Where("id = ?", assetCollectionID).
-			Scan(&exists).Error; err != nil {
+		exists, err := appContext.Assets.CollectionExists(c.UserContext(), colonyAsset.AssetCollection)
+		if err != nil {
 			c.Response().Header.Set(appContext.DDH, "Error verifying AssetCollection "+err.Error())
 			return fiber.NewError(fiber.StatusInternalServerError, "Error verifying AssetCollection")
 		}
@@ -431,19 +342,17 @@ func getColonyInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 		}
 
 		colonyAssets = append(colonyAssets, AssetTransformTuple{
-			Transform:         transform.ToShortCLINotation(),
-			AssetCollectionID: assetCollectionID,
+			Transform:         toTransformDTO(transform).ToShortCLINotation(),
+			AssetCollectionID: colonyAsset.AssetCollection,
 		})
 	}
 
 	colonyLocations := make([]LocationTransformTuple, 0, len(colony.Locations))
 	for _, colonyLocationID := range colony.Locations {
-		var colonyLocation ColonyLocationModel
-		if err := appContext.ColonyAssetDB.
-			Where(`id = ?`, colonyLocationID).
-			First(&colonyLocation).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.Response().Header.Set(appContext.DDH, "No such location "+err.Error())
+		colonyLocation, err := appContext.Colonies.FindLocation(c.UserContext(), uint32(colonyLocationID))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.Response().Header.Set(appContext.DDH, "No such location")
 				return fiber.NewError(fiber.StatusNotFound, "No such location")
 			}
 
@@ -451,12 +360,10 @@ func getColonyInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 			return fiber.NewError(fiber.StatusInternalServerError, "Error fetching location")
 		}
 
-		var transform TransformDTO
-		if err := appContext.ColonyAssetDB.
-			Where(`id = ?`, colonyLocation.Transform).
-			First(&transform).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.Response().Header.Set(appContext.DDH, "No such transform "+err.Error())
+		transform, err := appContext.Colonies.FindTransform(c.UserContext(), colonyLocation.Transform)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.Response().Header.Set(appContext.DDH, "No such transform")
 				return fiber.NewError(fiber.StatusNotFound, "No such transform")
 			}
 
@@ -466,7 +373,7 @@ func getColonyInfoHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) err
 
 		colonyLocations = append(colonyLocations, LocationTransformTuple{
 			ID:         colonyLocation.ID,
-			Transform:  transform.ToShortCLINotation(),
+			Transform:  toTransformDTO(transform).ToShortCLINotation(),
 			LocationID: colonyLocation.Location,
 			Level:      colonyLocation.Level,
 		})
@@ -502,14 +409,8 @@ func getColonyOverviewHandler(c *fiber.Ctx, appContext *meta.ApplicationContext)
 	}
 
 	// Fetch all colonies owned by the player, including assets and locations
-	var colonies []ColonyModel
-	if err := appContext.ColonyAssetDB.
-		Where("owner = ?", playerId).
-		Find(&colonies).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.Response().Header.Set(appContext.DDH, "No colonies found for player "+err.Error())
-			return fiber.NewError(fiber.StatusNotFound, "No colonies found for player")
-		}
+	colonies, err := appContext.Colonies.FindByOwner(c.UserContext(), uint32(playerId))
+	if err != nil {
 		c.Response().Header.Set(appContext.DDH, "Internal server error "+err.Error())
 		return fiber.NewError(fiber.StatusInternalServerError, "Internal server error")
 	}
@@ -585,123 +486,17 @@ func createColonyHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) erro
 		colonyName = "DATA.UNNAMED.COLONY"
 	}
 
-	// Start a transaction for the colony insert
-	tx := appContext.ColonyAssetDB.Begin()
-	if tx.Error != nil {
-		c.Response().Header.Set(appContext.DDH, "Error starting transaction: "+tx.Error.Error())
-		return fiber.NewError(fiber.StatusInternalServerError, "Error starting transaction")
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback() // Rollback on panic
-		}
-	}()
-
-	handleError := func(errMsg string, err error, deleteColony bool, locationIDMap map[uint]uint, transformIDs map[string]uint, newColony *ColonyModel) error {
-		tx.Rollback() // Always rollback the current transaction
-
-		// Ensure locations are deleted first, followed by transforms, and then colony itself
-		if deleteColony {
-			// Extract location IDs
-			var locationIDValues []uint
-			for _, id := range locationIDMap {
-				locationIDValues = append(locationIDValues, id)
-			}
-
-			// Delete locations first
-			if len(locationIDValues) > 0 {
-				appContext.ColonyAssetDB.Where("id IN ?", locationIDValues).Delete(&colony.ColonyLocation{})
-			}
-
-			// Extract transform IDs
-			var transformIDValues []uint
-			for _, id := range transformIDs {
-				transformIDValues = append(transformIDValues, id)
-			}
-
-			// Delete transforms next
-			if len(transformIDValues) > 0 {
-				appContext.ColonyAssetDB.Where("id IN ?", transformIDValues).Delete(&colony.Transform{})
-			}
-
-			// Finally, delete the colony
-			if newColony != nil {
-				appContext.ColonyAssetDB.Delete(newColony)
-			}
-		}
-
-		log.Printf("%s: %v", errMsg, err)
-		c.Response().Header.Set(appContext.DDH, errMsg+": "+err.Error())
-		return fiber.NewError(fiber.StatusInternalServerError, "Error creating colony")
-	}
-
-	// Create the new colony
+	// Create the new colony, along with its locations, paths and assets
 	newColony := ColonyModel{
 		Name:        colonyName,
 		Owner:       uint32(playerId),
 		AccLevel:    0,
 		LatestVisit: "DATA.UNVISITED.COLONY",
-		Assets:      make([]int, 0),
-		Locations:   make([]int, 0),
-	}
-
-	if err := tx.Create(&newColony).Error; err != nil {
-		return handleError("Error creating colony", err, false, nil, nil, nil)
 	}
-
-	// Commit colony creation to allow foreign keys in the next steps
-	if err := tx.Commit().Error; err != nil {
-		return handleError("Error committing colony creation", err, false, nil, nil, &newColony)
-	}
-
-	// Start a new transaction for colony locations, paths, and assets
-	tx = appContext.ColonyAssetDB.Begin()
-
-	// Insert transforms
-	transformIDs, err, boundingBox := colony.InsertLocationTransforms(appContext, tx)
-	if err != nil {
-		return handleError("Error inserting transforms", err, true, nil, transformIDs, &newColony)
-	}
-
-	// Insert colony locations
-	locationIDMap, err := colony.InsertColonyLocations(appContext, tx, uint(newColony.ID), transformIDs)
-	if err != nil {
-		return handleError("Error inserting colony locations", err, true, nil, transformIDs, &newColony)
-	}
-
-	// Update the Locations array in newColony
-	for _, locationID := range locationIDMap {
-		newColony.Locations = append(newColony.Locations, int(locationID))
-	}
-
-	// Update the colony record with the Locations array
-	if err := tx.Model(&newColony).Update("Locations", newColony.Locations).Error; err != nil {
-		return handleError("Error updating colony locations", err, true, locationIDMap, transformIDs, &newColony)
-	}
-
-	// Insert colony paths using locationIDMap
-	if err := colony.InitializeColonyPaths(tx, newColony.ID, locationIDMap); err != nil {
-		return handleError("Error initializing colony paths", err, true, locationIDMap, transformIDs, &newColony)
-	}
-
-	// Insert colony assets
-	assetIDs, err := colony.InsertColonyAssets(tx, newColony.ID, boundingBox)
-	if err != nil {
-		return handleError("Error inserting colony assets", err, true, locationIDMap, transformIDs, &newColony)
-	}
-
-	// Update the Assets array in newColony
-	newColony.Assets = assetIDs
-
-	// Update the colony record with the Assets array
-	if err := tx.Model(&newColony).Update("Assets", newColony.Assets).Error; err != nil {
-		return handleError("Error updating colony assets", err, true, locationIDMap, transformIDs, &newColony)
-	}
-
-	// Commit everything
-	if err := tx.Commit().Error; err != nil {
-		return handleError("Error committing colony creation", err, true, locationIDMap, transformIDs, &newColony)
+	if err := appContext.Colonies.Create(c.UserContext(), &newColony); err != nil {
+		log.Printf("[Player API] Error creating colony for player %d: %v\n", playerId, err)
+		c.Response().Header.Set(appContext.DDH, "Error creating colony")
+		return fiber.NewError(fiber.StatusInternalServerError, "Error creating colony")
 	}
 
 	// Return the newly created colony details
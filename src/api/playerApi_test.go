@@ -1,205 +1,185 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http/httptest"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"strings"
 	"testing"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gofiber/fiber/v2"
-	"github.com/lib/pq"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
 )
 
-// Utility function to create and return a mocked GORM database
-func createPlayerGormMock(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, error) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Error("failed to open sqlmock database:", err)
-		return nil, nil, err
-	}
-
-	gormDB, err := gorm.Open(postgres.New(postgres.Config{
-		Conn: db,
-	}), &gorm.Config{})
-	if err != nil {
-		t.Error("failed to initialize gorm DB:", err)
-		return nil, nil, err
-	}
+// A player repository where every lookup fails, as when the database is down
+type failingPlayerRepository struct {
+	repository.PlayerRepository
+	err error
+}
 
-	return gormDB, mock, nil
+func (r failingPlayerRepository) FindByID(ctx context.Context, id uint32) (*repository.Player, error) {
+	return nil, r.err
 }
 
-// Utility function to create GORM mock and Fiber app
-func setupPlayerTest(t *testing.T) (sqlmock.Sqlmock, *fiber.App, *meta.ApplicationContext, error) {
-	gormDB, mock, err := createPlayerGormMock(t)
-	if err != nil {
-		t.Error("Setup failed:", err)
-		return nil, nil, nil, err
-	}
+// Utility function to create the Fiber app with the player API applied
+func setupPlayerTest(t *testing.T) (*fiber.App, *meta.ApplicationContext, *repository.MemoryPlayerRepository) {
+	appContext := newTestAppContext(t)
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
 
 	app := fiber.New()
-	appContext := &meta.ApplicationContext{
-		ColonyAssetDB:                    gormDB,
-		LanguageDB:                       gormDB,
-		PlayerDB:                         gormDB,
-		VitecIntegration:                 nil,
-		DDH:                              "Test-DDH",
-		AuthTokenName:                    "Test-Auth-Token-Name",
-		InternalMultiplayerServerAddress: "notset",
+	if err := applyPlayerApi(app, appContext); err != nil {
+		t.Fatal("failed to apply player API:", err)
 	}
-
-	err = applyPlayerApi(app, appContext)
-	if err != nil {
-		t.Error("failed to apply player API:", err)
-		return nil, nil, nil, err
-	}
-
-	return mock, app, appContext, nil
+	return app, appContext, players
 }
 
-// Utility function to test a request and validate the status code
-func testPlayerRequest(t *testing.T, app *fiber.App, method, path string, expectedStatusCode int) error {
-	req := httptest.NewRequest(method, path, nil)
-	req.Header.Set("OTTE-Token", "OTTE-Token") // Set OTTE-Token for authentication
+// Utility function to test a request, validate the status code and return the body
+func testPlayerRequest(t *testing.T, app *fiber.App, method, path string, body string, expectedStatusCode int) []byte {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(testAuthTokenName, "OTTE-Token")
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
 
 	resp, err := app.Test(req)
 	if err != nil {
-		t.Error("failed to process the request:", err)
-		return err
+		t.Fatal("failed to process the request:", err)
 	}
-
 	if resp.StatusCode != expectedStatusCode {
 		t.Errorf("unexpected status code: got %d, expected %d", resp.StatusCode, expectedStatusCode)
-		return errors.New("unexpected status code")
 	}
-
-	return nil
-}
-
-// Utility function to check mock expectations
-func checkPlayerMockExpectations(t *testing.T, mock sqlmock.Sqlmock) error {
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Error("there were unfulfilled expectations:", err)
-		return err
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("failed to read response body:", err)
 	}
-	return nil
+	return raw
 }
 
 func TestGetPlayerByID(t *testing.T) {
-	mock, app, _, err := setupPlayerTest(t) // Capture all 4 return values
-	if err != nil {
-		t.Fatal("Setup failed:", err) // Fatal here as setup failure should stop the test
-	}
+	app, _, players := setupPlayerTest(t)
+	players.PutPlayer(repository.Player{ID: 1, FirstName: "Test", LastName: "Player", Sprite: 100, Achievements: []int{1, 4}})
 
-	// Mocking the expected row
-	rows := sqlmock.NewRows([]string{"id", "IGN", "sprite"}).
-		AddRow(1, "TestPlayer", 100)
-
-	mock.ExpectQuery(`SELECT id, "IGN", sprite FROM "Player" WHERE id = \$1`).
-		WithArgs(1).
-		WillReturnRows(rows)
-
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/1", 200)
-	if err != nil {
-		t.Error("Failed to process the request:", err)
+	raw := testPlayerRequest(t, app, "GET", "/api/v1/player/1", "", 200)
+	var player PlayerDTO
+	if err := json.Unmarshal(raw, &player); err != nil {
+		t.Fatalf("failed to decode response body %q: %v", raw, err)
 	}
 
-	err = checkPlayerMockExpectations(t, mock)
-	if err != nil {
-		t.Error("Mock expectations not met:", err)
+	if player.ID != 1 || player.FirstName != "Test" || player.Sprite != 100 {
+		t.Errorf("unexpected player: %+v", player)
+	}
+	if !player.HasCompletedTutorial {
+		t.Error("expected a player with achievement 1 to have completed the tutorial")
 	}
 }
 
 func TestGetPlayerNotFound(t *testing.T) {
-	mock, app, _, err := setupPlayerTest(t)
-	if err != nil {
-		t.Fatal("Setup failed:", err)
-	}
+	app, _, _ := setupPlayerTest(t)
 
-	mock.ExpectQuery(`SELECT id, "IGN", sprite FROM "Player" WHERE id = \$1`).
-		WithArgs(999).
-		WillReturnError(gorm.ErrRecordNotFound)
+	testPlayerRequest(t, app, "GET", "/api/v1/player/999", "", 404)
+}
 
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/999", 404)
-	if err != nil {
-		t.Error("Failed to process the request:", err)
-	}
+func TestGetPlayerDatabaseError(t *testing.T) {
+	app, appContext, _ := setupPlayerTest(t)
+	appContext.Players = failingPlayerRepository{err: errors.New("connection error")}
 
-	err = checkPlayerMockExpectations(t, mock)
-	if err != nil {
-		t.Error("Mock expectations not met:", err)
-	}
+	testPlayerRequest(t, app, "GET", "/api/v1/player/1", "", 500)
 }
 
 func TestInvalidPlayerID(t *testing.T) {
-	_, app, _, err := setupPlayerTest(t)
-	if err != nil {
-		t.Fatal("Setup failed:", err)
-	}
+	app, _, _ := setupPlayerTest(t)
 
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/abc", 400)
-	if err != nil {
-		t.Error("Failed to process the request:", err)
-	}
+	testPlayerRequest(t, app, "GET", "/api/v1/player/abc", "", 400)
 }
 
 func TestGetPlayerPreferences(t *testing.T) {
-	mock, app, _, err := setupPlayerTest(t)
-	if err != nil {
-		t.Fatal("Setup failed:", err)
+	app, _, players := setupPlayerTest(t)
+	players.PutPlayer(repository.Player{ID: 1})
+	players.PutAvailablePreference(repository.AvailablePreference{ID: 1, PreferenceKey: "Language", AvailableValues: []string{"EN", "DK", "NO"}})
+	if err := players.SetPreference(context.Background(), 1, "Language", "DK"); err != nil {
+		t.Fatal("failed to set preference:", err)
 	}
 
-	// Mocking the preferences row
-	preferenceRows := sqlmock.NewRows([]string{"id", "preferenceKey", "chosenValue", "availableValues"}).
-		AddRow(1, "Language", "EN", pq.Array([]string{"EN", "DK", "NO"}))
-
-	mock.ExpectQuery(`SELECT "PlayerPreference".id, "PlayerPreference"."preferenceKey", "PlayerPreference"."chosenValue", "AvailablePreference"."availableValues" FROM "PlayerPreference" JOIN "AvailablePreference"`).
-		WithArgs(1).
-		WillReturnRows(preferenceRows)
-
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/1/preferences", 200)
-	if err != nil {
-		t.Error("Failed to process the request:", err)
+	raw := testPlayerRequest(t, app, "GET", "/api/v1/player/1/preferences", "", 200)
+	var response PlayerPreferencesResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		t.Fatalf("failed to decode response body %q: %v", raw, err)
 	}
 
-	err = checkPlayerMockExpectations(t, mock)
-	if err != nil {
-		t.Error("Mock expectations not met:", err)
+	if len(response.Preferences) != 1 || response.Preferences[0].PreferenceKey != "Language" || response.Preferences[0].ChosenValue != "DK" {
+		t.Errorf("unexpected preferences: %+v", response.Preferences)
 	}
 }
 
 func TestGetPlayerPreferencesNotFound(t *testing.T) {
-	mock, app, _, err := setupPlayerTest(t)
-	if err != nil {
-		t.Fatal("Setup failed:", err)
-	}
+	app, _, _ := setupPlayerTest(t)
 
-	mock.ExpectQuery(`SELECT "PlayerPreference".id, "PlayerPreference"."preferenceKey", "PlayerPreference"."chosenValue", "AvailablePreference"."availableValues" FROM "PlayerPreference" JOIN "AvailablePreference"`).
-		WithArgs(999).
-		WillReturnError(gorm.ErrRecordNotFound)
+	testPlayerRequest(t, app, "GET", "/api/v1/player/999/preferences", "", 404)
+}
 
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/999/preferences", 404)
-	if err != nil {
-		t.Error("Failed to process the request:", err)
-	}
+func TestInvalidPlayerIDPreferences(t *testing.T) {
+	app, _, _ := setupPlayerTest(t)
 
-	err = checkPlayerMockExpectations(t, mock)
-	if err != nil {
-		t.Error("Mock expectations not met:", err)
-	}
+	testPlayerRequest(t, app, "GET", "/api/v1/player/abc/preferences", "", 400)
 }
 
-func TestInvalidPlayerIDPreferences(t *testing.T) {
-	_, app, _, err := setupPlayerTest(t)
+func TestGrantPlayerAchievement(t *testing.T) {
+	app, _, players := setupPlayerTest(t)
+	players.PutPlayer(repository.Player{ID: 1})
+	players.PutAchievement(repository.Achievement{ID: 1, Title: "Tutorial"})
+
+	testPlayerRequest(t, app, "POST", "/api/v1/player/1/achievement/1", "", 200)
+	// Granting is idempotent
+	testPlayerRequest(t, app, "POST", "/api/v1/player/1/achievement/1", "", 200)
+
+	player, err := players.FindByID(context.Background(), 1)
 	if err != nil {
-		t.Fatal("Setup failed:", err)
+		t.Fatal("failed to find player:", err)
+	}
+	if len(player.Achievements) != 1 || player.Achievements[0] != 1 {
+		t.Errorf("expected achievement 1 to be granted once, got: %v", player.Achievements)
 	}
 
-	err = testPlayerRequest(t, app, "GET", "/api/v1/player/abc/preferences", 400)
+	testPlayerRequest(t, app, "POST", "/api/v1/player/1/achievement/2", "", 404)
+}
+
+func TestCreateColony(t *testing.T) {
+	app, appContext, players := setupPlayerTest(t)
+	players.PutPlayer(repository.Player{ID: 1})
+
+	raw := testPlayerRequest(t, app, "POST", "/api/v1/player/1/colony/create", `{"name":"Home"}`, 200)
+	var created struct {
+		ID   uint32 `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &created); err != nil {
+		t.Fatal("failed to parse response:", err)
+	}
+	colony, err := appContext.Colonies.FindByID(context.Background(), created.ID)
 	if err != nil {
-		t.Error("Failed to process the request:", err)
+		t.Fatal("expected the colony to be stored:", err)
 	}
+	if colony.Owner != 1 || colony.Name != "Home" || created.Name != "Home" {
+		t.Errorf("unexpected colony: %+v", colony)
+	}
+}
+
+func TestCompleteMinigame(t *testing.T) {
+	app, appContext, players := setupPlayerTest(t)
+	players.PutPlayer(repository.Player{ID: 1})
+	appContext.Minigames.(*repository.MemoryMinigameRepository).PutMinigame(repository.Minigame{
+		ID:           3,
+		Difficulties: []repository.MinigameDifficulty{{ID: 7, Name: "Easy"}},
+	})
+	path := "/api/v1/player/1/minigame/3/complete"
+
+	testPlayerRequest(t, app, "POST", path, `{"difficultyId":7,"score":80}`, fiber.StatusOK)
+	testPlayerRequest(t, app, "POST", path, `{"difficultyId":7,"score":101}`, fiber.StatusBadRequest)
+	testPlayerRequest(t, app, "POST", path, `{"difficultyId":7,"score":-1}`, fiber.StatusBadRequest)
+	testPlayerRequest(t, app, "POST", path, `{"difficultyId":7}`, fiber.StatusBadRequest)
+	testPlayerRequest(t, app, "POST", path, `{"difficultyId":8,"score":80}`, fiber.StatusBadRequest)
+	testPlayerRequest(t, app, "POST", "/api/v1/player/1/minigame/4/complete", `{"difficultyId":7,"score":80}`, fiber.StatusBadRequest)
 }
@@ -11,7 +11,7 @@ import (
 // Proxying some calls to get around browser pre-flight checks (CORS on non TLS or self-signed TLS)
 func ApplyProxyAPI(app *fiber.App, context *meta.ApplicationContext) error {
 	app.Get("/proxy/v1/multiplayer/lobby/:id", func(c *fiber.Ctx) error {
-		return getLobbyStateProxyHandler(c, context)
+		return getLobbyStateProxyHandler(c, meta.ForRequest(c, context))
 	})
 
 	return nil
@@ -25,7 +25,7 @@ func getLobbyStateProxyHandler(c *fiber.Ctx, context *meta.ApplicationContext) e
 		middleware.LogRequests(c)
 		return c.SendStatus(fiber.StatusBadRequest)
 	}
-	resp, err := multiplayer.GetLobbyState(uint32(lobbyID), context)
+	resp, err := multiplayer.GetLobbyState(c.UserContext(), uint32(lobbyID), context)
 	if err != nil {
 		c.Response().Header.Set(context.DDH, "Failed to get lobby state: "+err.Error())
 		c.Status(fiber.StatusBadGateway)
@@ -1,16 +1,19 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"log"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/meta"
 	"otte_main_backend/src/middleware"
+	"otte_main_backend/src/ratelimit"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
 	"otte_main_backend/src/vitec"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"gorm.io/gorm"
 )
 
 type SessionInitiationResponseDTO struct {
@@ -28,8 +31,30 @@ func newSessionInitiationResponseDTO(token string, internalID uint32) SessionIni
 func applySessionApi(app *fiber.App, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
 	log.Println("[Session API] Applying session API")
 
+	rateLimits := appContext.Config.RateLimit
+	ipLimiter := ratelimit.NewLimiterFromConfig("SESSION_CREATE_IP", rateLimits, rateLimits.SessionCreateIP)
+	routeLimiter := ratelimit.NewLimiterFromConfig("SESSION_CREATE_ROUTE", rateLimits, rateLimits.SessionCreateRoute)
+
 	//No Auth required
-	app.Post("/api/v1/session", func(c *fiber.Ctx) error { return initiateSessionHandler(c, appContext, authService) })
+	app.Post("/api/v1/session",
+		ipLimiter.Middleware(appContext, ratelimit.ByIP),
+		routeLimiter.Middleware(appContext, ratelimit.ByRoute),
+		func(c *fiber.Ctx) error {
+			return initiateSessionHandler(c, meta.ForRequest(c, appContext), authService)
+		})
+
+	// Lists the active sessions (devices) of the player owning the token used in the request
+	app.Get("/api/v1/session/list", auth.PrefixOn(appContext, listSessionsHandler))
+
+	// Revokes the session of the token used in the request
+	app.Delete("/api/v1/session", auth.PrefixOn(appContext, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		return revokeCurrentSessionHandler(c, appContext, authService)
+	}))
+
+	// Revokes all sessions of some player. Admins only, so a session can't be used to log out other players
+	app.Delete("/api/v1/session/player/:playerId", auth.RequireRole(appContext, auth.RoleAdmin, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		return revokeAllSessionsForPlayerHandler(c, appContext, authService)
+	}))
 
 	return nil
 }
@@ -43,81 +68,229 @@ func initiateSessionHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, a
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	//First of all, check if the auth header is present. If so, lookup in the cache and return if found
-	existingAuthHeader := c.Request().Header.Peek(appContext.AuthTokenName)
-	if len(existingAuthHeader) > 0 {
-		if cacheEntry, exists := authService.SessionCache.Load(auth.SessionToken(existingAuthHeader)); exists && auth.IsSessionStillValid(cacheEntry.Entry) {
-
-			//update last checkin async
-			go auth.UpdateLastPlayerCheckin(cacheEntry.Entry, appContext)
-			//No need to check for cache entry expiry here, as the cache is checked for expiry on subsequent request
-			//Even if the cache is expired, the session is still valid
-			c.Status(fiber.StatusOK)
-			middleware.LogRequests(c)
-			return c.JSON(newSessionInitiationResponseDTO(string(cacheEntry.Entry.Token), cacheEntry.Entry.Player))
-		}
-	}
-
-	var player PlayerModel
-	var idOfPreviousSession int = -1
+	var isNewPlayer = false
 	//Check if player exists in PlayerDB - if so, all is well
-	if err := appContext.PlayerDB.Where(`"referenceID" = ?`, body.UserIdentifier).First(&player).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
+	player, err := appContext.Players.FindByReferenceID(c.UserContext(), body.UserIdentifier)
+	if err != nil {
+		isNewPlayer = true
+		if !errors.Is(err, repository.ErrNotFound) {
 			c.Status(fiber.StatusInternalServerError)
 			middleware.LogRequests(c)
 			return fiber.NewError(fiber.StatusInternalServerError, "Something went wrong when trying to lookup the user")
 		}
 		//If the player doesn't exist, check with Vitec
 		if crossVerificationError := appContext.VitecIntegration.VerifyUser(&body); crossVerificationError != nil {
-			c.Status(fiber.StatusUnauthorized)
-			middleware.LogRequests(c)
-			return fiber.NewError(fiber.StatusUnauthorized, "Unable to cross-verify")
+			return respondToFailedCrossVerification(c, appContext, crossVerificationError)
 		}
 		//If the user is cross verified but doesn't exist in our system, a new player is created
-		player = PlayerModel{
-			ReferenceID:  body.UserIdentifier,
-			FirstName:    body.FirstName,
-			LastName:     body.LastName,
-			Sprite:       1,
-			Achievements: util.PGIntArray{},
+		player = &PlayerModel{
+			ReferenceID:             body.UserIdentifier,
+			FirstName:               body.FirstName,
+			LastName:                body.LastName,
+			Sprite:                  1,
+			Achievements:            util.PGIntArray{},
+			Role:                    auth.RolePlayer,
+			LastVerificationOutcome: vitec.VerificationOutcomeSkipped,
+		}
+		//Only a verification Vitec was actually asked about counts, so enabling it later still verifies the player
+		if appContext.VitecIntegration.ContactsVitec() {
+			verifiedAt := time.Now()
+			player.LastVerifiedAt = &verifiedAt
+			player.LastVerificationAttemptAt = &verifiedAt
+			player.LastVerificationOutcome = vitec.VerificationOutcomeVerified
 		}
 
-		if createPlayerError := appContext.PlayerDB.Create(&player).Error; createPlayerError != nil {
+		if createPlayerError := appContext.Players.Create(c.UserContext(), player); createPlayerError != nil {
 			c.Status(fiber.StatusInternalServerError)
 			middleware.LogRequests(c)
 			return fiber.NewError(fiber.StatusInternalServerError, "Unable to create player")
 		}
-	} else {
-		//If the user exists, grab any earlier session
-		var session auth.Session
-		if err := appContext.PlayerDB.Where("player = ?", player.ID).First(&session).Error; err == nil {
-			//A session exists, if still valid, just return the token
-			idOfPreviousSession = int(session.ID)
-			if auth.IsSessionStillValid(&session) {
-				//update last checkin async
-				go auth.UpdateLastPlayerCheckin(&session, appContext)
-				c.Status(fiber.StatusOK)
-				middleware.LogRequests(c)
-				return c.JSON(newSessionInitiationResponseDTO(string(session.Token), session.Player))
-			}
-		} else {
-			//If the error is not the expected "record not found", something might have gone horribly wrong, so log it
-			if !errors.Is(err, gorm.ErrRecordNotFound) {
-				c.Status(fiber.StatusInternalServerError)
-				middleware.LogRequests(c)
-				return fiber.NewError(fiber.StatusInternalServerError, "Something went wrong when trying to lookup the session")
+	} else if player.Disabled {
+		c.Response().Header.Set(appContext.DDH, "Player disabled by Vitec")
+		c.Status(fiber.StatusForbidden)
+		middleware.LogRequests(c)
+		return fiber.NewError(fiber.StatusForbidden, "Player disabled")
+	} else if appContext.VitecIntegration.NeedsReverification(player.LastVerifiedAt, time.Now()) {
+		//Existing players are verified again once in a while (periodic), so users deactivated at Vitec are refused
+		crossVerificationError := appContext.VitecIntegration.VerifyUser(&body)
+		if recordErr := recordVerificationOutcome(c.UserContext(), player, crossVerificationError, appContext); recordErr != nil {
+			log.Println("[Session API] Unable to record verification outcome: " + recordErr.Error())
+		}
+		if crossVerificationError != nil {
+			if vitec.VerificationOutcome(crossVerificationError) == string(vitec.FailureRejected) {
+				//Sessions from before the user was deactivated are revoked as well
+				if _, revokeErr := auth.RevokeAllSessionsForPlayer(c.UserContext(), player.ID, appContext, authService); revokeErr != nil {
+					log.Println("[Session API] Unable to revoke sessions of unverifiable player: " + revokeErr.Error())
+				}
 			}
+			return respondToFailedCrossVerification(c, appContext, crossVerificationError)
 		}
 	}
-
+	if !isNewPlayer {
+		//Vitec is the source of truth for profile data. A failed sync doesn't prevent logging in.
+		if changes, syncErr := vitec.SyncProfile(c.UserContext(), appContext.Players, player.ID, &body); syncErr != nil {
+			log.Println("[Session API] Unable to sync profile of player: " + syncErr.Error())
+		} else if len(changes) > 0 {
+			log.Printf("[Session API] Synced %d profile field(s) of player %d from Vitec\n", len(changes), player.ID)
+		}
+	}
+	//If the auth header holds a cached session of the player, it is reused. Only checked now, so disabled players
+	//and players due for re-verification can't skip those checks by presenting a session they already have.
+	existingAuthHeader := c.Request().Header.Peek(appContext.AuthTokenName)
+	if !isNewPlayer && len(existingAuthHeader) > 0 {
+		if cacheEntry, exists := authService.SessionCache.Load(authService.HashToken(string(existingAuthHeader))); exists && cacheEntry.Entry.Player == player.ID && auth.IsSessionStillValid(cacheEntry.Entry) {
+			//Written to the DB on the next flush of the check-in recorder
+			authService.RecordCheckIn(cacheEntry.Entry, appContext)
+			//No need to check for cache entry expiry here, as the cache is checked for expiry on subsequent request
+			//Even if the cache is expired, the session is still valid
+			//The client already holds the raw token, as it is the one in the header
+			session := *cacheEntry.Entry
+			session.RawToken = auth.SessionToken(existingAuthHeader)
+			return respondWithSession(c, &session, authService)
+		}
+	}
+	//If the user exists, a new session is created regardless of any earlier sessions, as those may belong to other devices
 	// If this point is reached, the player now exists in the system and is cross-verified (or has been cross-verified before)
-	session, sessionErr := auth.CreateSessionForPlayer(uint32(player.ID), appContext, idOfPreviousSession, authService)
+	device := auth.DeviceInfo{
+		Label:     body.DeviceLabel,
+		UserAgent: string(c.Request().Header.UserAgent()),
+	}
+	session, sessionErr := auth.CreateSessionForPlayer(c.UserContext(), uint32(player.ID), appContext, device, authService)
 	if sessionErr != nil {
 		c.Status(fiber.StatusInternalServerError)
 		middleware.LogRequests(c)
 		return fiber.NewError(fiber.StatusInternalServerError, "Unable to initialize session: "+sessionErr.Error())
 	}
+	return respondWithSession(c, session, authService)
+}
+
+// Stores when the player was attempted verified and the outcome. The time of the last successful verification
+// is only moved on success.
+func recordVerificationOutcome(ctx context.Context, player *PlayerModel, verificationErr error, appContext *meta.ApplicationContext) error {
+	attempt := repository.VerificationAttempt{
+		AttemptedAt: time.Now(),
+		Outcome:     vitec.VerificationOutcome(verificationErr),
+		Verified:    verificationErr == nil,
+	}
+	if attempt.Verified {
+		player.LastVerifiedAt = &attempt.AttemptedAt
+	}
+	player.LastVerificationAttemptAt = &attempt.AttemptedAt
+	player.LastVerificationOutcome = attempt.Outcome
+	return appContext.Players.RecordVerification(ctx, player.ID, attempt)
+}
+
+// The user is only refused outright if Vitec rejected them, other failures mean Vitec couldn't be asked
+func respondToFailedCrossVerification(c *fiber.Ctx, appContext *meta.ApplicationContext, crossVerificationError error) error {
+	var verificationErr *vitec.VerificationError
+	if errors.As(crossVerificationError, &verificationErr) && verificationErr.Reason != vitec.FailureRejected {
+		c.Response().Header.Set(appContext.DDH, "Vitec cross-verification failed: "+string(verificationErr.Reason))
+		c.Status(fiber.StatusServiceUnavailable)
+		middleware.LogRequests(c)
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Unable to cross-verify, try again later")
+	}
+	c.Status(fiber.StatusUnauthorized)
+	middleware.LogRequests(c)
+	return fiber.NewError(fiber.StatusUnauthorized, "Unable to cross-verify")
+}
+
+func respondWithSession(c *fiber.Ctx, session *auth.Session, authService *auth.AuthService) error {
+	token, err := authService.ClientTokenFor(session)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		middleware.LogRequests(c)
+		return fiber.NewError(fiber.StatusInternalServerError, "Unable to issue session token")
+	}
 	c.Status(fiber.StatusOK)
 	middleware.LogRequests(c)
-	return c.JSON(newSessionInitiationResponseDTO(string(session.Token), session.Player))
+	return c.JSON(newSessionInitiationResponseDTO(token, session.Player))
+}
+
+type SessionRevocationResponseDTO struct {
+	RevokedCount int64 `json:"revokedCount"`
+}
+
+func revokeCurrentSessionHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
+	session, err := auth.GetSessionFromContext(c, appContext)
+	if err != nil {
+		c.Response().Header.Set(appContext.DDH, "No session found for token")
+		return fiber.NewError(fiber.StatusNotFound, "No such session")
+	}
+	if err := auth.RevokeSession(c.UserContext(), session.Token, appContext, authService); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.Response().Header.Set(appContext.DDH, "No such session")
+			return fiber.NewError(fiber.StatusNotFound, "No such session")
+		}
+		// Gorm exposes secrets in err when DB is down, so it can't be included in the response
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(SessionRevocationResponseDTO{RevokedCount: 1})
+}
+
+func revokeAllSessionsForPlayerHandler(c *fiber.Ctx, appContext *meta.ApplicationContext, authService *auth.AuthService) error {
+	playerId, parseErr := c.ParamsInt("playerId")
+	if parseErr != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid player ID "+parseErr.Error())
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+	}
+
+	revokedCount, err := auth.RevokeAllSessionsForPlayer(c.UserContext(), uint32(playerId), appContext, authService)
+	if err != nil {
+		log.Printf("[Session API] Failed to revoke sessions for player %d: %s\n", playerId, err.Error())
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+
+	log.Printf("[Session API] Revoked %d session(s) for player %d\n", revokedCount, playerId)
+	c.Status(fiber.StatusOK)
+	return c.JSON(SessionRevocationResponseDTO{RevokedCount: revokedCount})
+}
+
+type ActiveSessionDTO struct {
+	ID          uint32    `json:"id"`
+	DeviceLabel string    `json:"deviceLabel"`
+	UserAgent   string    `json:"userAgent"`
+	CreatedAt   time.Time `json:"createdAt"`
+	LastCheckIn time.Time `json:"lastCheckIn"`
+	// Whether this is the session used to make the request
+	Current bool `json:"current"`
+}
+
+type ActiveSessionsResponseDTO struct {
+	Sessions []ActiveSessionDTO `json:"sessions"`
+}
+
+func listSessionsHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	currentSession, err := auth.GetSessionFromContext(c, appContext)
+	if err != nil {
+		c.Response().Header.Set(appContext.DDH, "No session found for token")
+		return fiber.NewError(fiber.StatusUnauthorized, "No session found for token")
+	}
+
+	sessions, err := auth.GetActiveSessionsForPlayer(c.UserContext(), currentSession.Player, appContext)
+	if err != nil {
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+
+	//Tokens are deliberately left out, as they would allow taking over the other sessions
+	response := ActiveSessionsResponseDTO{
+		Sessions: util.ArrayMap(sessions, func(session auth.Session) ActiveSessionDTO {
+			return ActiveSessionDTO{
+				ID:          session.ID,
+				DeviceLabel: session.DeviceLabel,
+				UserAgent:   session.UserAgent,
+				CreatedAt:   session.CreatedAt,
+				LastCheckIn: session.LastCheckIn,
+				//Matched on the (hashed) token, as the session of a signed token carries no ID
+				Current: session.Token == currentSession.Token,
+			}
+		}),
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(response)
 }
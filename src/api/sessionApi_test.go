@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"otte_main_backend/src/vitec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Utility function to create the Fiber app with the session API applied, without cross verification
+func setupSessionTest(t *testing.T) (*fiber.App, *meta.ApplicationContext, *auth.AuthService) {
+	appContext := newTestAppContext(t)
+	authService, err := auth.InitializeAuth(appContext)
+	if err != nil {
+		t.Fatal("failed to initialize auth:", err)
+	}
+	appContext.VitecIntegration, err = vitec.CreateNewVitecIntegration(config.VitecConfig{CrossVerification: string(vitec.CrossVerificationNever)})
+	if err != nil {
+		t.Fatal("failed to create Vitec integration:", err)
+	}
+	app := fiber.New()
+	if err := applySessionApi(app, appContext, authService); err != nil {
+		t.Fatal("failed to apply session API:", err)
+	}
+	return app, appContext, authService
+}
+
+// Creates a session for the player, whose raw token is the given token
+func putTestSession(t *testing.T, appContext *meta.ApplicationContext, authService *auth.AuthService, playerID uint32, token string) {
+	now := time.Now()
+	session := repository.Session{Player: playerID, Token: authService.HashToken(token), ValidDuration: auth.DEFAULT_VALID_DURATION, CreatedAt: now, LastCheckIn: now}
+	if err := appContext.Sessions.Create(context.Background(), &session); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+}
+
+func testSessionRequest(t *testing.T, app *fiber.App, method, path string, token string, body string, expectedStatusCode int) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set(testAuthTokenName, token)
+	}
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal("failed to process the request:", err)
+	}
+	if resp.StatusCode != expectedStatusCode {
+		t.Errorf("%s %s: got %d, expected %d", method, path, resp.StatusCode, expectedStatusCode)
+	}
+}
+
+func TestExistingPlayerStartsSessionAndSyncsProfile(t *testing.T) {
+	app, appContext, _ := setupSessionTest(t)
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1, ReferenceID: "vitec-1", FirstName: "Alice", LastName: "Smith"})
+
+	testSessionRequest(t, app, "POST", "/api/v1/session", "",
+		`{"userIdentifier":"vitec-1","currentSessionToken":"vitec-token","firstName":"Alice","lastName":"Jones"}`, 200)
+
+	if sessions, _ := appContext.Sessions.FindByPlayer(context.Background(), 1); len(sessions) != 1 {
+		t.Errorf("expected a session to be created, got %+v", sessions)
+	}
+	if player, _ := players.FindByID(context.Background(), 1); player.LastName != "Jones" {
+		t.Errorf("expected the profile to be synced from Vitec, got %+v", player)
+	}
+}
+
+func TestRevokingSessionsOfAnotherPlayerRequiresAdmin(t *testing.T) {
+	app, appContext, authService := setupSessionTest(t)
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1})
+	players.PutPlayer(repository.Player{ID: 2})
+	putTestSession(t, appContext, authService, 1, "OTTE-Token")
+	putTestSession(t, appContext, authService, 2, "other")
+
+	testSessionRequest(t, app, "DELETE", "/api/v1/session/player/2", "OTTE-Token", "", fiber.StatusForbidden)
+	if sessions, _ := appContext.Sessions.FindByPlayer(context.Background(), 2); len(sessions) != 1 {
+		t.Fatalf("expected the sessions of player 2 to be kept, got %+v", sessions)
+	}
+
+	players.PutPlayer(repository.Player{ID: 1, Role: repository.RoleAdmin})
+	testSessionRequest(t, app, "DELETE", "/api/v1/session/player/2", "OTTE-Token", "", fiber.StatusOK)
+	if sessions, _ := appContext.Sessions.FindByPlayer(context.Background(), 2); len(sessions) != 0 {
+		t.Errorf("expected the sessions of player 2 to be revoked, got %+v", sessions)
+	}
+}
+
+func listTestSessions(t *testing.T, app *fiber.App, token string) ActiveSessionsResponseDTO {
+	req := httptest.NewRequest("GET", "/api/v1/session/list", nil)
+	req.Header.Set(testAuthTokenName, token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal("failed to process the request:", err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET /api/v1/session/list: got %d, expected 200", resp.StatusCode)
+	}
+	var response ActiveSessionsResponseDTO
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal("failed to decode response:", err)
+	}
+	return response
+}
+
+func TestListSessionsMarksCurrentSession(t *testing.T) {
+	app, appContext, authService := setupSessionTest(t)
+	putTestSession(t, appContext, authService, 1, "phone")
+	putTestSession(t, appContext, authService, 1, "laptop")
+	putTestSession(t, appContext, authService, 2, "other")
+
+	response := listTestSessions(t, app, "laptop")
+	if len(response.Sessions) != 2 {
+		t.Fatalf("expected only the sessions of the player, got %+v", response.Sessions)
+	}
+	current, _ := appContext.Sessions.FindByToken(context.Background(), authService.HashToken("laptop"))
+	for _, session := range response.Sessions {
+		if session.Current != (session.ID == current.ID) {
+			t.Errorf("expected only the session of the request to be current, got %+v", response.Sessions)
+		}
+	}
+}
+
+func TestListSessionsMarksCurrentSessionOfSignedToken(t *testing.T) {
+	app, appContext, authService := setupSessionTest(t)
+	signed := appContext.Config.Auth
+	signed.Level = string(auth.AuthLevelSigned)
+	signed.SignedTokenKeys = config.Secret("k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("s", auth.MIN_SIGNING_KEY_LENGTH))))
+	signed.SignedTokenValidDuration = time.Hour
+	if err := auth.ApplyAuthConfig(authService, signed); err != nil {
+		t.Fatal("failed to apply signed auth:", err)
+	}
+	defer auth.ApplyAuthConfig(authService, appContext.Config.Auth)
+
+	putTestSession(t, appContext, authService, 1, "phone")
+	current, err := auth.CreateSessionForPlayer(context.Background(), 1, appContext, auth.DeviceInfo{Label: "laptop"}, authService)
+	if err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+	token, err := authService.ClientTokenFor(current)
+	if err != nil {
+		t.Fatal("failed to sign token:", err)
+	}
+
+	response := listTestSessions(t, app, token)
+	if len(response.Sessions) != 2 {
+		t.Fatalf("expected both sessions of the player, got %+v", response.Sessions)
+	}
+	for _, session := range response.Sessions {
+		if session.Current != (session.ID == current.ID) {
+			t.Errorf("expected only the session wrapped by the signed token to be current, got %+v", response.Sessions)
+		}
+	}
+}
+
+// Caches a session for the player, as if a request had been made with the given token
+func putCachedTestSession(t *testing.T, appContext *meta.ApplicationContext, authService *auth.AuthService, playerID uint32, token string) {
+	putTestSession(t, appContext, authService, playerID, token)
+	session, err := appContext.Sessions.FindByToken(context.Background(), authService.HashToken(token))
+	if err != nil {
+		t.Fatal("failed to find session:", err)
+	}
+	authService.SessionCache.Store(session.Token, auth.CacheEntry[auth.Session]{Entry: session, CreatedAt: time.Now()})
+	t.Cleanup(func() { authService.SessionCache.Delete(session.Token) })
+}
+
+func TestCachedSessionIsReusedUnlessPlayerDisabled(t *testing.T) {
+	app, appContext, authService := setupSessionTest(t)
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1, ReferenceID: "vitec-1", FirstName: "Alice", LastName: "Smith"})
+	putCachedTestSession(t, appContext, authService, 1, "cached")
+	body := `{"userIdentifier":"vitec-1","currentSessionToken":"vitec-token","firstName":"Alice","lastName":"Smith"}`
+
+	testSessionRequest(t, app, "POST", "/api/v1/session", "cached", body, fiber.StatusOK)
+	if sessions, _ := appContext.Sessions.FindByPlayer(context.Background(), 1); len(sessions) != 1 {
+		t.Errorf("expected the cached session to be reused, got %+v", sessions)
+	}
+
+	players.PutPlayer(repository.Player{ID: 1, ReferenceID: "vitec-1", FirstName: "Alice", LastName: "Smith", Disabled: true})
+	testSessionRequest(t, app, "POST", "/api/v1/session", "cached", body, fiber.StatusForbidden)
+}
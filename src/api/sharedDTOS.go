@@ -2,29 +2,13 @@ package api
 
 import (
 	"fmt"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
 )
 
-type AchievementModel struct {
-	ID          uint32 `json:"id" gorm:"column:id;primaryKey"`
-	Description string `json:"description" gorm:"column:description"`
-	Icon        uint32 `json:"icon" gorm:"column:icon"`
-	Title       string `json:"title" gorm:"column:title"`
-}
+type AchievementModel = repository.Achievement
 
-func (a *AchievementModel) TableName() string {
-	return "Achievement"
-}
-
-type LODDetails struct {
-	ID             uint32 `json:"id"`
-	DetailLevel    uint32 `json:"detailLevel" gorm:"column:detailLevel"`
-	GraphicalAsset uint32 `json:"graphicalAsset" gorm:"column:graphicalAsset"`
-}
-
-func (l LODDetails) TableName() string {
-	return "LOD"
-}
+type LODDetails = repository.LODDetails
 
 type LODDetailsDTO struct {
 	ID          uint32 `json:"id"`
@@ -35,19 +19,6 @@ func (l LODDetailsDTO) TableName() string {
 	return "LOD"
 }
 
-type TransformModel struct {
-	ID      uint32  `gorm:"column:id;primaryKey"`
-	XScale  float32 `gorm:"column:xScale"`
-	YScale  float32 `gorm:"column:yScale"`
-	XOffset float32 `gorm:"column:xOffset"`
-	YOffset float32 `gorm:"column:yOffset"`
-	ZIndex  int     `gorm:"column:zIndex"`
-}
-
-func (tm *TransformModel) TableName() string {
-	return "Transform"
-}
-
 type TransformDTO struct {
 	XOffset float32 `json:"xOffset" gorm:"column:xOffset"`
 	YOffset float32 `json:"yOffset" gorm:"column:yOffset"`
@@ -57,6 +28,16 @@ type TransformDTO struct {
 }
 type CLIFormatTransform = string
 
+func toTransformDTO(transform *repository.Transform) *TransformDTO {
+	return &TransformDTO{
+		XOffset: transform.XOffset,
+		YOffset: transform.YOffset,
+		ZIndex:  transform.ZIndex,
+		XScale:  transform.XScale,
+		YScale:  transform.YScale,
+	}
+}
+
 func (t *TransformDTO) ToShortCLINotation() CLIFormatTransform {
 	//CLI shortHand: "xOff yOff zIndex, xScale yScale"
 	return fmt.Sprintf("%f %f %d, %f %f", t.XOffset, t.YOffset, t.ZIndex, t.XScale, t.YScale)
@@ -89,15 +70,4 @@ func (p PlayerDTO) TableName() string {
 }
 
 // PlayerModel represents the database model for a player.
-type PlayerModel struct {
-	ID           uint32          `json:"id"`
-	ReferenceID  string          `json:"referenceID" gorm:"column:referenceID"`
-	FirstName    string          `json:"firstName" gorm:"column:firstName"`
-	LastName     string          `json:"lastName" gorm:"column:lastName"`
-	Sprite       uint32          `json:"sprite"`
-	Achievements util.PGIntArray `json:"achievements"`
-}
-
-func (p PlayerModel) TableName() string {
-	return "Player"
-}
+type PlayerModel = repository.Player
@@ -1,14 +1,18 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"otte_main_backend/src/api/local"
 	"otte_main_backend/src/config"
+	db "otte_main_backend/src/database"
 	"otte_main_backend/src/meta"
 	"otte_main_backend/src/middleware"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -17,20 +21,39 @@ import (
 
 var errorUnauthorized error = fmt.Errorf("unauthorized")
 
+// How long a SessionCache entry is trusted before the session is looked up again
+const SESSION_CACHE_MAX_AGE = time.Minute
+
 type CacheEntry[T any] struct {
 	Entry     *T
 	CreatedAt time.Time
 }
 type AuthService struct {
-	//Valid for a minute before requiring looking up again
+	//Valid for a minute before requiring looking up again. Keyed by the hashed token.
 	SessionCache util.ConcurrentTypedMap[SessionToken, CacheEntry[Session]]
-	//Function to call when authenticating a request
-	Method func(c *fiber.Ctx) *fiber.Error
+	//Set in InitializeAuth, replaced as a whole by a config reload, see Mode
+	mode atomic.Pointer[AuthMode]
+	//Amount of concurrently active sessions (devices) a player may have before the oldest is evicted
+	MaxSessionsPerPlayer int
+	//Set when the session reaper is started
+	Reaper *SessionReaper
+	//Set when the check-in recorder is started
+	CheckIns *CheckInRecorder
+	//Vitec referenceID to internal player ID, see GetSessionByReferenceID
+	ReferenceCache util.ConcurrentTypedMap[string, uint32]
+	//Shared secret for internal endpoints, internal endpoints are disabled if empty
+	internalAPIToken string
+	//Revoked session tokens and until when they are to be denied. Only used with signed auth.
+	Denylist util.ConcurrentTypedMap[SessionToken, time.Time]
+	//Key used for HashToken
+	tokenHashKey []byte
 }
 
 var authSingleton *AuthService = &AuthService{
-	SessionCache: util.ConcurrentTypedMap[SessionToken, CacheEntry[Session]]{},
-	Method:       nil, //Set in InitializeAuth
+	SessionCache:         util.ConcurrentTypedMap[SessionToken, CacheEntry[Session]]{},
+	Denylist:             util.ConcurrentTypedMap[SessionToken, time.Time]{},
+	ReferenceCache:       util.ConcurrentTypedMap[string, uint32]{},
+	MaxSessionsPerPlayer: DEFAULT_MAX_SESSIONS_PER_PLAYER,
 }
 
 type AuthLevel string
@@ -38,38 +61,109 @@ type AuthLevel string
 const (
 	AuthLevelStrict AuthLevel = "strict"
 	AuthLevelNaive  AuthLevel = "naive"
+	AuthLevelSigned AuthLevel = "signed"
 )
 
+// Everything derived from the auth level, see ApplyAuthConfig
+type AuthMode struct {
+	Level AuthLevel
+	//Function to call when authenticating a request, given the application context of the request
+	Method func(c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error
+	//Only set when using signed auth
+	Signer *TokenSigner
+}
+
+// The auth mode currently in use. Read it once and keep using that, as a config reload may replace it at any time.
+func (authService *AuthService) Mode() *AuthMode {
+	if mode := authService.mode.Load(); mode != nil {
+		return mode
+	}
+	return &AuthMode{}
+}
+
 func InitializeAuth(appContext *meta.ApplicationContext) (*AuthService, error) {
-	level := config.GetOr("INTERNAL_AUTH_LEVEL", "strict")
+	cfg := appContext.Config.Auth
+	authSingleton.MaxSessionsPerPlayer = cfg.MaxSessionsPerPlayer
+
+	tokenHashKey, err := loadTokenHashKey(cfg.SessionTokenHashKey)
+	if err != nil {
+		return nil, err
+	}
+	authSingleton.tokenHashKey = tokenHashKey
+	if !appContext.DBMonitor.IsHealthy(db.PlayerDBName) {
+		//Sessions are looked up by hash only, so the player DB is kept degraded until the tokens are hashed
+		log.Println("[AUTH] Player DB degraded, existing session tokens are hashed once it is reachable")
+		appContext.DBMonitor.RequireBeforeHealthy(db.PlayerDBName, func(*gorm.DB) error {
+			return migrateUnhashedSessionTokens(appContext, authSingleton)
+		})
+	} else if err := migrateUnhashedSessionTokens(appContext, authSingleton); err != nil {
+		return nil, fmt.Errorf("Unable to hash existing session tokens: %s", err.Error())
+	}
+
+	authSingleton.internalAPIToken = cfg.InternalAPIToken.Reveal()
+	if authSingleton.internalAPIToken == "" {
+		log.Println("[AUTH] INTERNAL_API_TOKEN not set, internal endpoints are disabled")
+	}
+
+	if err := ApplyAuthConfig(authSingleton, cfg); err != nil {
+		return nil, err
+	}
+	if authSingleton.Mode().Signer != nil {
+		if !appContext.DBMonitor.IsHealthy(db.PlayerDBName) {
+			log.Println("[AUTH] Player DB degraded, denied session tokens are loaded by the session reaper once it recovers")
+		} else if denied, err := loadDenylist(context.Background(), appContext, authSingleton); err != nil {
+			return nil, fmt.Errorf("Unable to load denied session tokens: %s", err.Error())
+		} else {
+			log.Printf("[AUTH] Loaded %d denied session token(s)\n", denied)
+		}
+	}
+	return authSingleton, nil
+}
+
+// Sets the auth level, replacing the auth mode. Nothing is changed on error.
+// Also used to apply a config reload, requests already being authenticated keep using the mode they started with.
+func ApplyAuthConfig(authService *AuthService, cfg config.AuthConfig) error {
+	mode, err := NewAuthMode(authService, cfg)
+	if err != nil {
+		return err
+	}
+	authService.UseMode(mode)
+	return nil
+}
 
-	switch AuthLevel(level) {
+// Replaces the auth mode in one step, see NewAuthMode
+func (authService *AuthService) UseMode(mode *AuthMode) {
+	authService.mode.Store(mode)
+}
+
+// Derives the auth mode from the config without applying it, see ApplyAuthConfig
+func NewAuthMode(authService *AuthService, cfg config.AuthConfig) (*AuthMode, error) {
+	mode := &AuthMode{Level: AuthLevel(cfg.Level)}
+	switch mode.Level {
 	case AuthLevelStrict:
-		authSingleton.Method = func(c *fiber.Ctx) *fiber.Error {
-			return fullSessionCheckAuth(authSingleton, c, appContext)
+		mode.Method = func(c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error {
+			return fullSessionCheckAuth(authService, c, appContext)
 		}
 		log.Println("[AUTH] Level set to strict")
 	case AuthLevelNaive:
-		authSingleton.Method = func(c *fiber.Ctx) *fiber.Error {
+		mode.Method = func(c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error {
 			return naiveCheckForHeaderAuth(c, appContext.AuthTokenName, appContext.DDH)
 		}
-		log.Println("[AUTH] Level set to naive")
+		log.Println("[AUTH] Level set to naive, ownership policies will not be enforced")
+	case AuthLevelSigned:
+		signer, err := NewTokenSigner(cfg.SignedTokenKeys.Reveal(), cfg.SignedTokenValidDuration)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to initialize signed auth: %s", err.Error())
+		}
+		mode.Signer = signer
+		mode.Method = func(c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error {
+			return signedTokenCheckAuth(authService, signer, c, appContext)
+		}
+		log.Printf("[AUTH] Level set to signed, signing with key: %s, accepting %d key(s)\n", signer.signingKeyID, len(signer.keys))
 	default:
-		return nil, fmt.Errorf("Invalid auth level: %s", level)
+		return nil, fmt.Errorf("Invalid auth level: %s", cfg.Level)
 	}
-
-	return authSingleton, nil
-}
-
-func GetSessionByReferenceID(referenceID string, appContext *meta.ApplicationContext) (*Session, error) {
-	//First check cache
-	authSingleton.SessionCache.Load(SessionToken(referenceID))
-	//If not in cache, check DB
-
-	//If in DB, add to cache
-
-	//If not in DB either, return error
-	return nil, errorUnauthorized
+	return mode, nil
 }
 
 // Expands the original handler function's inputs (adding in the appContext) and prefixes an authcheck function.
@@ -79,9 +173,19 @@ func GetSessionByReferenceID(referenceID string, appContext *meta.ApplicationCon
 // # Also sets debug header and status code on auth error
 //
 // Also also adds request logging
-func PrefixOn(appContext *meta.ApplicationContext, existingHandler func(c *fiber.Ctx, appContext *meta.ApplicationContext) error) func(*fiber.Ctx) error {
+// and checks any policies given in order after the auth check, see Policy
+//
+// The handler is given the application context of the request, see meta.ForRequest
+func PrefixOn(appContext *meta.ApplicationContext, existingHandler func(c *fiber.Ctx, appContext *meta.ApplicationContext) error, policies ...Policy) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		if err := authSingleton.Method(c); err != nil {
+		appContext := meta.ForRequest(c, appContext)
+		mode := authSingleton.Mode()
+		if err := mode.Method(c, appContext); err != nil {
+			c.Status(err.Code)
+			middleware.LogRequests(c)
+			return err
+		}
+		if err := checkPolicies(c, appContext, mode, policies); err != nil {
 			c.Status(err.Code)
 			middleware.LogRequests(c)
 			return err
@@ -106,39 +210,81 @@ func fullSessionCheckAuth(authService *AuthService, c *fiber.Ctx, appContext *me
 		c.Response().Header.Set(appContext.DDH, "Missing auth header, expected "+appContext.AuthTokenName+" to be present")
 		return ErrorUnauthorized
 	}
-	if cacheEntry, exists := authService.SessionCache.Load(SessionToken(authHeaderContent)); exists {
+	tokenHash := authService.HashToken(authHeaderContent)
+	if cacheEntry, exists := authService.SessionCache.Load(tokenHash); exists {
 		//If cache entry
-		if time.Since(cacheEntry.CreatedAt) < time.Minute {
+		if time.Since(cacheEntry.CreatedAt) < SESSION_CACHE_MAX_AGE {
 			//If cache entry is valid (within 1 minute)
 			appendLocalsToContext(c, cacheEntry.Entry)
 			return nil
 		}
+		//Stale entries are removed, so the cache doesn't grow with sessions never checked again
+		authService.SessionCache.CompareAndDelete(tokenHash, cacheEntry)
 	}
 	//If no cache entry OR cache entry is expired
-	var session Session
-	var dbErr error
-	if dbErr = appContext.PlayerDB.Where("token = ?", authHeaderContent).First(&session).Error; dbErr != nil {
-		if !errors.Is(dbErr, gorm.ErrRecordNotFound) {
+	session, dbErr := appContext.Sessions.FindByToken(c.UserContext(), tokenHash)
+	if dbErr != nil {
+		if !errors.Is(dbErr, repository.ErrNotFound) {
+			//Not a reason to log the client out, the session may well be valid once the PlayerDB is reachable
 			log.Println("[AUTH] INTERNAL ERROR: " + dbErr.Error())
+			c.Response().Header.Set(appContext.DDH, "Unable to verify session")
+			return fiber.NewError(fiber.StatusServiceUnavailable, "Unable to verify session")
 		}
 		c.Response().Header.Set(appContext.DDH, "Invalid session token")
 		return ErrorUnauthorized
 	}
-	if !IsSessionStillValid(&session) {
+	if !IsSessionStillValid(session) {
 		//If the session is expired
 		c.Response().Header.Set(appContext.DDH, "Session expired")
 		return ErrorUnauthorized
 	}
+	role, roleErr := loadRoleForPlayer(c.UserContext(), session.Player, appContext)
+	if roleErr != nil {
+		log.Println("[AUTH] INTERNAL ERROR: " + roleErr.Error())
+		c.Response().Header.Set(appContext.DDH, "Internal error")
+		return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+	}
+	session.Role = role
 	//Session exists and is valid:
-	authService.SessionCache.Store(session.Token, CacheEntry[Session]{Entry: &session, CreatedAt: time.Now()})
-	// update last checkin async
-	go UpdateLastPlayerCheckin(&session, appContext)
-	appendLocalsToContext(c, &session)
+	authService.RecordCheckIn(session, appContext)
+	session.LastCheckIn = time.Now()
+	authService.SessionCache.Store(session.Token, CacheEntry[Session]{Entry: session, CreatedAt: time.Now()})
+	appendLocalsToContext(c, session)
 	return nil
 }
 
 func appendLocalsToContext(c *fiber.Ctx, session *Session) {
 	c.Locals(local.Session, session)
+	c.Locals(local.Role, session.Role)
+}
+
+// Returns the session of the request. Uses the session placed in the locals by the auth check if present (strict),
+// else falls back to looking up the token of the auth header (naive).
+func GetSessionFromContext(c *fiber.Ctx, appContext *meta.ApplicationContext) (*Session, error) {
+	if session, ok := c.Locals(local.Session).(*Session); ok && session != nil {
+		return session, nil
+	}
+	authHeaderContent := string(c.Request().Header.Peek(appContext.AuthTokenName))
+	if len(authHeaderContent) == 0 {
+		return nil, errorUnauthorized
+	}
+	session, err := appContext.Sessions.FindByToken(c.UserContext(), authSingleton.HashToken(authHeaderContent))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, errorUnauthorized
+		}
+		return nil, err
+	}
+	if !IsSessionStillValid(session) {
+		return nil, errorUnauthorized
+	}
+	role, err := loadRoleForPlayer(c.UserContext(), session.Player, appContext)
+	if err != nil {
+		return nil, err
+	}
+	session.Role = role
+	appendLocalsToContext(c, session)
+	return session, nil
 }
 
 func naiveCheckForHeaderAuth(context *fiber.Ctx, tokenName string, defaultDebugHeader string) *fiber.Error {
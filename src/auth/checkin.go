@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/util"
+	"sync"
+	"time"
+)
+
+// Postgres allows at most 65535 parameters per statement, each row uses 2
+const MAX_CHECKINS_PER_UPDATE = 1000
+
+type recordedCheckIn struct {
+	At      time.Time
+	Flushed bool
+}
+
+// Collects the latest check-in per session in memory and writes them to the PlayerDB as one bulk UPDATE on an interval,
+// instead of a write per authenticated request.
+//
+// Flushed check-ins are kept around for SESSION_CACHE_MAX_AGE, so sessions cached before the check-in
+// are still seen as checked in, see LatestCheckIn
+type CheckInRecorder struct {
+	interval   time.Duration
+	appContext *meta.ApplicationContext
+	checkIns   util.ConcurrentTypedMap[uint32, recordedCheckIn]
+	//Flushes may be triggered by both the recorder itself and the reaper
+	flushLock sync.Mutex
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Starts the recorder in the background. Interval is taken from CHECKIN_FLUSH_INTERVAL_S
+func StartCheckInRecorder(appContext *meta.ApplicationContext, authService *AuthService) *CheckInRecorder {
+	recorder := &CheckInRecorder{
+		interval:   appContext.Config.Auth.CheckInFlushInterval,
+		appContext: appContext,
+		checkIns:   util.ConcurrentTypedMap[uint32, recordedCheckIn]{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	authService.CheckIns = recorder
+
+	log.Printf("[AUTH] Check-in recorder started, flush interval: %s\n", recorder.interval)
+	go recorder.loop()
+	return recorder
+}
+
+// Stops the recorder and flushes any remaining check-ins
+func (r *CheckInRecorder) Stop() {
+	close(r.stop)
+	<-r.done
+	if _, err := r.Flush(); err != nil {
+		log.Println("[AUTH] INTERNAL ERROR: unable to flush check-ins on shutdown: " + err.Error())
+	}
+	log.Println("[AUTH] Check-in recorder stopped")
+}
+
+func (r *CheckInRecorder) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if _, err := r.Flush(); err != nil {
+				log.Println("[AUTH] INTERNAL ERROR: unable to flush check-ins: " + err.Error())
+			}
+		}
+	}
+}
+
+// Records a check-in for the session. Only the latest check-in per session is kept.
+func (r *CheckInRecorder) Record(sessionID uint32, at time.Time) {
+	for {
+		existing, loaded := r.checkIns.LoadOrStore(sessionID, recordedCheckIn{At: at})
+		if !loaded || !existing.At.Before(at) {
+			return
+		}
+		if r.checkIns.CompareAndSwap(sessionID, existing, recordedCheckIn{At: at}) {
+			return
+		}
+	}
+}
+
+// Returns the latest recorded check-in of the session, if any
+func (r *CheckInRecorder) Latest(sessionID uint32) (time.Time, bool) {
+	checkIn, exists := r.checkIns.Load(sessionID)
+	return checkIn.At, exists
+}
+
+// Writes all check-ins recorded since the last flush to the PlayerDB. Returns the amount of check-ins written.
+// Check-ins that fail to be written are kept for the next flush.
+func (r *CheckInRecorder) Flush() (int, error) {
+	r.flushLock.Lock()
+	defer r.flushLock.Unlock()
+
+	now := time.Now()
+	pending := make(map[uint32]recordedCheckIn)
+	r.checkIns.Range(func(sessionID uint32, checkIn recordedCheckIn) bool {
+		if !checkIn.Flushed {
+			pending[sessionID] = checkIn
+		} else if now.Sub(checkIn.At) >= SESSION_CACHE_MAX_AGE {
+			//Any cache entry older than the check-in has been evicted by now
+			r.checkIns.CompareAndDelete(sessionID, checkIn)
+		}
+		return true
+	})
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	var sessionIDs = make([]uint32, 0, len(pending))
+	for sessionID := range pending {
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	var written = 0
+	for start := 0; start < len(sessionIDs); start += MAX_CHECKINS_PER_UPDATE {
+		end := min(start+MAX_CHECKINS_PER_UPDATE, len(sessionIDs))
+		if err := writeCheckIns(r.appContext, sessionIDs[start:end], pending); err != nil {
+			return written, err
+		}
+		for _, sessionID := range sessionIDs[start:end] {
+			//If a newer check-in was recorded in the meantime, it is left for the next flush
+			checkIn := pending[sessionID]
+			r.checkIns.CompareAndSwap(sessionID, checkIn, recordedCheckIn{At: checkIn.At, Flushed: true})
+		}
+		written += end - start
+	}
+	return written, nil
+}
+
+// Updates lastCheckIn of all given sessions in one statement, see repository.SessionRepository.BulkCheckIn
+func writeCheckIns(appContext *meta.ApplicationContext, sessionIDs []uint32, checkIns map[uint32]recordedCheckIn) error {
+	var batch = make(map[uint32]time.Time, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		batch[sessionID] = checkIns[sessionID].At
+	}
+	return appContext.Sessions.BulkCheckIn(context.Background(), batch)
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"otte_main_backend/src/util"
+	"testing"
+	"time"
+)
+
+func TestCheckInRecorderCoalescesAndFlushes(t *testing.T) {
+	repositories := repository.NewMemoryRepositories()
+	recorder := &CheckInRecorder{
+		appContext: &meta.ApplicationContext{Repositories: repositories},
+		checkIns:   util.ConcurrentTypedMap[uint32, recordedCheckIn]{},
+	}
+	now := time.Now()
+	session := Session{Player: 1, Token: "token", ValidDuration: 1000, LastCheckIn: now.Add(-time.Hour)}
+	if err := repositories.Sessions.Create(context.Background(), &session); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+
+	recorder.Record(session.ID, now.Add(-time.Second))
+	recorder.Record(session.ID, now)
+	recorder.Record(session.ID, now.Add(-2*time.Second))
+	if latest, _ := recorder.Latest(session.ID); !latest.Equal(now) {
+		t.Errorf("expected only the latest check-in to be kept, got: %s", latest)
+	}
+
+	if written, err := recorder.Flush(); err != nil || written != 1 {
+		t.Fatalf("unexpected flush result: %d, %v", written, err)
+	}
+	stored, _ := repositories.Sessions.FindByToken(context.Background(), "token")
+	if !stored.LastCheckIn.Equal(now) {
+		t.Errorf("expected the latest check-in to be written, got: %s", stored.LastCheckIn)
+	}
+	//Nothing new has been recorded, so nothing is written
+	if written, err := recorder.Flush(); err != nil || written != 0 {
+		t.Fatalf("unexpected second flush result: %d, %v", written, err)
+	}
+
+	//Flushed check-ins are still visible to sessions cached before the check-in
+	authSingleton.CheckIns = recorder
+	defer func() { authSingleton.CheckIns = nil }()
+	cached := &Session{ID: session.ID, ValidDuration: 1000, LastCheckIn: now.Add(-time.Hour)}
+	if !IsSessionStillValid(cached) {
+		t.Error("expected recorded check-in to keep the session valid")
+	}
+}
+
+func TestCheckInRecorderNeverMovesCheckInsBackwards(t *testing.T) {
+	repositories := repository.NewMemoryRepositories()
+	recorder := &CheckInRecorder{
+		appContext: &meta.ApplicationContext{Repositories: repositories},
+		checkIns:   util.ConcurrentTypedMap[uint32, recordedCheckIn]{},
+	}
+	now := time.Now()
+	session := Session{Player: 1, Token: "token", ValidDuration: 1000, LastCheckIn: now}
+	if err := repositories.Sessions.Create(context.Background(), &session); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+
+	//Written by another instance in the meantime, or the session revoked before the flush
+	recorder.Record(session.ID, now.Add(-time.Minute))
+	recorder.Record(session.ID+1, now)
+	if _, err := recorder.Flush(); err != nil {
+		t.Fatal("unexpected flush error:", err)
+	}
+	stored, _ := repositories.Sessions.FindByToken(context.Background(), "token")
+	if !stored.LastCheckIn.Equal(now) {
+		t.Errorf("expected the newer stored check-in to be kept, got: %s", stored.LastCheckIn)
+	}
+	if sessions, _ := repositories.Sessions.FindByPlayer(context.Background(), 0); len(sessions) != 0 {
+		t.Errorf("expected check-ins of unknown sessions to be skipped, got: %+v", sessions)
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Header the multiplayer backend and other internal services put INTERNAL_API_TOKEN in
+const INTERNAL_API_TOKEN_HEADER = "X-Internal-Token"
+
+// Like PrefixOn, but for service-to-service endpoints: instead of a player session,
+// the request must carry INTERNAL_API_TOKEN. Responds 404 if internal endpoints are disabled.
+func InternalOnly(appContext *meta.ApplicationContext, existingHandler func(c *fiber.Ctx, appContext *meta.ApplicationContext) error) func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		appContext := meta.ForRequest(c, appContext)
+		if err := checkInternalToken(c, appContext); err != nil {
+			c.Status(err.Code)
+			middleware.LogRequests(c)
+			return err
+		}
+		handlerErr := existingHandler(c, appContext)
+		if fiberErr, ok := handlerErr.(*fiber.Error); ok {
+			c.Status(fiberErr.Code)
+		}
+		middleware.LogRequests(c)
+		return handlerErr
+	}
+}
+
+func checkInternalToken(c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error {
+	if authSingleton.internalAPIToken == "" {
+		return fiber.ErrNotFound
+	}
+	provided := c.Request().Header.Peek(INTERNAL_API_TOKEN_HEADER)
+	if len(provided) == 0 {
+		c.Response().Header.Set(appContext.DDH, "Missing internal token, expected "+INTERNAL_API_TOKEN_HEADER+" to be present")
+		return ErrorUnauthorized
+	}
+	if subtle.ConstantTimeCompare(provided, []byte(authSingleton.internalAPIToken)) != 1 {
+		c.Response().Header.Set(appContext.DDH, "Invalid internal token")
+		return ErrorUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// A policy is checked by PrefixOn after the request is authenticated, but before the handler is run.
+// Returning an error stops the request, and the error code is used as the status code.
+//
+// Policies are declared per route, e.g.:
+//
+//	app.Get("/api/v1/player/:playerId", auth.PrefixOn(appContext, handler, auth.PlayerParamMatchesSession("playerId")))
+type Policy func(c *fiber.Ctx, appContext *meta.ApplicationContext, session *Session) *fiber.Error
+
+var ErrorForbidden *fiber.Error = fiber.NewError(fiber.StatusForbidden, "forbidden")
+
+// Requires the path parameter to be the ID of the player owning the session
+func PlayerParamMatchesSession(paramName string) Policy {
+	return func(c *fiber.Ctx, appContext *meta.ApplicationContext, session *Session) *fiber.Error {
+		playerID, err := c.ParamsInt(paramName)
+		if err != nil {
+			c.Response().Header.Set(appContext.DDH, "Invalid player ID "+err.Error())
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid player ID")
+		}
+		if uint32(playerID) != session.Player {
+			c.Response().Header.Set(appContext.DDH, "Session does not belong to player "+strconv.Itoa(playerID))
+			return ErrorForbidden
+		}
+		return nil
+	}
+}
+
+// Requires the (numeric) field of the JSON request body to be the ID of the player owning the session
+func PlayerBodyFieldMatchesSession(fieldName string) Policy {
+	return func(c *fiber.Ctx, appContext *meta.ApplicationContext, session *Session) *fiber.Error {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			c.Response().Header.Set(appContext.DDH, "Invalid request body")
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+		var playerID uint32
+		if err := json.Unmarshal(body[fieldName], &playerID); err != nil {
+			c.Response().Header.Set(appContext.DDH, "Invalid or missing "+fieldName+" in request body")
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+		if playerID != session.Player {
+			c.Response().Header.Set(appContext.DDH, "Session does not belong to player "+strconv.FormatUint(uint64(playerID), 10))
+			return ErrorForbidden
+		}
+		return nil
+	}
+}
+
+// Requires the colony of the path parameter to be owned by the player owning the session
+func SessionOwnsColonyParam(paramName string) Policy {
+	return func(c *fiber.Ctx, appContext *meta.ApplicationContext, session *Session) *fiber.Error {
+		colonyID, err := c.ParamsInt(paramName)
+		if err != nil {
+			c.Response().Header.Set(appContext.DDH, "Invalid colony ID "+err.Error())
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid colony ID")
+		}
+		colony, err := appContext.Colonies.FindByID(c.UserContext(), uint32(colonyID))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				c.Response().Header.Set(appContext.DDH, "Colony not found")
+				return fiber.NewError(fiber.StatusNotFound, "Colony not found")
+			}
+			log.Println("[AUTH] INTERNAL ERROR: " + err.Error())
+			c.Response().Header.Set(appContext.DDH, "Internal error")
+			return fiber.NewError(fiber.StatusInternalServerError, "Internal error")
+		}
+		if colony.Owner != session.Player {
+			c.Response().Header.Set(appContext.DDH, "Colony not owned by session player")
+			return ErrorForbidden
+		}
+		return nil
+	}
+}
+
+// Runs the policies in order, stopping at the first failing one.
+// Policies are only enforced when sessions are (strict auth), as there is no session to check against otherwise.
+func checkPolicies(c *fiber.Ctx, appContext *meta.ApplicationContext, mode *AuthMode, policies []Policy) *fiber.Error {
+	if len(policies) == 0 || mode.Level == AuthLevelNaive {
+		return nil
+	}
+	session, err := GetSessionFromContext(c, appContext)
+	if err != nil {
+		c.Response().Header.Set(appContext.DDH, "No session found for token")
+		return ErrorUnauthorized
+	}
+	for _, policy := range policies {
+		if policyErr := policy(c, appContext, session); policyErr != nil {
+			return policyErr
+		}
+	}
+	return nil
+}
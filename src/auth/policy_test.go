@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testAuthTokenName = "OTTE-Token"
+
+// Switches the auth singleton to strict auth for the duration of the test, so policies are enforced
+func setupStrictAuthTest(t *testing.T) (*fiber.App, *meta.ApplicationContext) {
+	appContext := &meta.ApplicationContext{
+		Repositories:  repository.NewMemoryRepositories(),
+		DDH:           "Test-DDH",
+		AuthTokenName: testAuthTokenName,
+	}
+	previous := authSingleton.Mode()
+	if err := ApplyAuthConfig(authSingleton, config.AuthConfig{Level: string(AuthLevelStrict)}); err != nil {
+		t.Fatal("failed to apply strict auth:", err)
+	}
+	t.Cleanup(func() {
+		authSingleton.UseMode(previous)
+		authSingleton.SessionCache.Range(func(token SessionToken, _ CacheEntry[Session]) bool {
+			authSingleton.SessionCache.Delete(token)
+			return true
+		})
+	})
+	return fiber.New(), appContext
+}
+
+// Creates a session for the player, whose raw token is the given token
+func putTestSession(t *testing.T, appContext *meta.ApplicationContext, playerID uint32, token string) {
+	now := time.Now()
+	session := Session{Player: playerID, Token: authSingleton.HashToken(token), ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now, LastCheckIn: now}
+	if err := appContext.Sessions.Create(context.Background(), &session); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+}
+
+func expectStatus(t *testing.T, app *fiber.App, method, path string, token string, body string, expectedStatusCode int) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(testAuthTokenName, token)
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal("failed to process the request:", err)
+	}
+	if resp.StatusCode != expectedStatusCode {
+		t.Errorf("%s %s %s: got %d, expected %d", method, path, body, resp.StatusCode, expectedStatusCode)
+	}
+}
+
+func okHandler(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func TestPlayerParamMatchesSession(t *testing.T) {
+	app, appContext := setupStrictAuthTest(t)
+	app.Get("/player/:playerId", PrefixOn(appContext, okHandler, PlayerParamMatchesSession("playerId")))
+	putTestSession(t, appContext, 1, "token")
+
+	expectStatus(t, app, "GET", "/player/1", "token", "", fiber.StatusOK)
+	expectStatus(t, app, "GET", "/player/2", "token", "", fiber.StatusForbidden)
+	expectStatus(t, app, "GET", "/player/abc", "token", "", fiber.StatusBadRequest)
+	expectStatus(t, app, "GET", "/player/1", "unknown", "", fiber.StatusUnauthorized)
+}
+
+func TestPlayerBodyFieldMatchesSession(t *testing.T) {
+	app, appContext := setupStrictAuthTest(t)
+	app.Post("/open", PrefixOn(appContext, okHandler, PlayerBodyFieldMatchesSession("playerId")))
+	putTestSession(t, appContext, 1, "token")
+
+	expectStatus(t, app, "POST", "/open", "token", `{"playerId":1}`, fiber.StatusOK)
+	expectStatus(t, app, "POST", "/open", "token", `{"playerId":2}`, fiber.StatusForbidden)
+	expectStatus(t, app, "POST", "/open", "token", `{"playerId":"one"}`, fiber.StatusBadRequest)
+	expectStatus(t, app, "POST", "/open", "token", `{}`, fiber.StatusBadRequest)
+	expectStatus(t, app, "POST", "/open", "token", `not json`, fiber.StatusBadRequest)
+}
+
+func TestSessionOwnsColonyParam(t *testing.T) {
+	app, appContext := setupStrictAuthTest(t)
+	app.Get("/colony/:colonyId", PrefixOn(appContext, okHandler, SessionOwnsColonyParam("colonyId")))
+	putTestSession(t, appContext, 1, "token")
+	colonies := appContext.Colonies.(*repository.MemoryColonyRepository)
+	colonies.PutColony(repository.Colony{ID: 10, Owner: 1})
+	colonies.PutColony(repository.Colony{ID: 20, Owner: 2})
+
+	expectStatus(t, app, "GET", "/colony/10", "token", "", fiber.StatusOK)
+	expectStatus(t, app, "GET", "/colony/20", "token", "", fiber.StatusForbidden)
+	expectStatus(t, app, "GET", "/colony/30", "token", "", fiber.StatusNotFound)
+	expectStatus(t, app, "GET", "/colony/abc", "token", "", fiber.StatusBadRequest)
+}
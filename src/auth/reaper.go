@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"otte_main_backend/src/meta"
+	"sync"
+	"time"
+)
+
+type SessionReaperStats struct {
+	LastRunAt                time.Time `json:"lastRunAt"`
+	LastRemovedSessions      int64     `json:"lastRemovedSessions"`
+	LastEvictedCacheEntries  int64     `json:"lastEvictedCacheEntries"`
+	TotalRemovedSessions     int64     `json:"totalRemovedSessions"`
+	TotalEvictedCacheEntries int64     `json:"totalEvictedCacheEntries"`
+	//Only relevant when using signed auth
+	LastEvictedDenylistEntries int64 `json:"lastEvictedDenylistEntries"`
+}
+
+// Periodically deletes expired sessions from the PlayerDB and evicts expired or over-age entries from the SessionCache
+// (and expired entries from the denylist, which is synced with the PlayerDB when using signed auth)
+type SessionReaper struct {
+	interval    time.Duration
+	appContext  *meta.ApplicationContext
+	authService *AuthService
+	stop        chan struct{}
+	done        chan struct{}
+	statsLock   sync.Mutex
+	stats       SessionReaperStats
+}
+
+// Starts the reaper in the background. Interval is taken from SESSION_REAPER_INTERVAL_S
+func StartSessionReaper(appContext *meta.ApplicationContext, authService *AuthService) *SessionReaper {
+	reaper := &SessionReaper{
+		interval:    appContext.Config.Auth.SessionReaperInterval,
+		appContext:  appContext,
+		authService: authService,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	authService.Reaper = reaper
+
+	log.Printf("[AUTH] Session reaper started, interval: %s\n", reaper.interval)
+	go reaper.loop()
+	return reaper
+}
+
+// Stops the reaper and waits for any ongoing run to finish
+func (r *SessionReaper) Stop() {
+	close(r.stop)
+	<-r.done
+	log.Println("[AUTH] Session reaper stopped")
+}
+
+func (r *SessionReaper) Stats() SessionReaperStats {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+	return r.stats
+}
+
+func (r *SessionReaper) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.RunOnce()
+		}
+	}
+}
+
+// Performs a single reaping. Exposed so it can be triggered outside of the regular interval.
+func (r *SessionReaper) RunOnce() {
+	//Pending check-ins are written first, so sessions kept alive by them aren't deleted
+	if r.authService.CheckIns != nil {
+		if _, err := r.authService.CheckIns.Flush(); err != nil {
+			log.Println("[AUTH] INTERNAL ERROR: session reaper unable to flush check-ins: " + err.Error())
+		}
+	}
+	removedSessions, dbErr := deleteExpiredSessions(r.appContext)
+	if dbErr != nil {
+		log.Println("[AUTH] INTERNAL ERROR: session reaper unable to delete expired sessions: " + dbErr.Error())
+	}
+	evictedEntries := evictStaleCacheEntries(r.authService, time.Now())
+	evictedDenylistEntries := evictExpiredDenylistEntries(r.authService, time.Now())
+	if r.authService.Mode().Signer != nil {
+		syncDenylist(r.appContext, r.authService)
+	}
+
+	r.statsLock.Lock()
+	r.stats.LastRunAt = time.Now()
+	r.stats.LastRemovedSessions = removedSessions
+	r.stats.LastEvictedCacheEntries = evictedEntries
+	r.stats.TotalRemovedSessions += removedSessions
+	r.stats.TotalEvictedCacheEntries += evictedEntries
+	r.stats.LastEvictedDenylistEntries = evictedDenylistEntries
+	r.statsLock.Unlock()
+
+	log.Printf("[AUTH] Session reaper removed %d expired session(s) and evicted %d cache entries\n", removedSessions, evictedEntries)
+}
+
+func deleteExpiredSessions(appContext *meta.ApplicationContext) (int64, error) {
+	return appContext.Sessions.DeleteExpired(context.Background(), time.Now())
+}
+
+// Picks up tokens denied by other instances, and deletes denials expired by now from the PlayerDB
+func syncDenylist(appContext *meta.ApplicationContext, authService *AuthService) {
+	if _, err := loadDenylist(context.Background(), appContext, authService); err != nil {
+		log.Println("[AUTH] INTERNAL ERROR: session reaper unable to load denied session tokens: " + err.Error())
+	}
+	if _, err := appContext.Sessions.DeleteExpiredDenials(context.Background(), time.Now()); err != nil {
+		log.Println("[AUTH] INTERNAL ERROR: session reaper unable to delete expired denials: " + err.Error())
+	}
+}
+
+// Evicts entries whose session has expired or which are older than SESSION_CACHE_MAX_AGE
+func evictStaleCacheEntries(authService *AuthService, now time.Time) int64 {
+	var evicted int64 = 0
+	authService.SessionCache.Range(func(token SessionToken, entry CacheEntry[Session]) bool {
+		if entry.Entry == nil || now.Sub(entry.CreatedAt) >= SESSION_CACHE_MAX_AGE || !IsSessionStillValid(entry.Entry) {
+			if authService.SessionCache.CompareAndDelete(token, entry) {
+				evicted++
+			}
+		}
+		return true
+	})
+	return evicted
+}
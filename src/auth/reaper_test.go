@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"otte_main_backend/src/util"
+	"testing"
+	"time"
+)
+
+func TestEvictStaleCacheEntries(t *testing.T) {
+	authService := &AuthService{SessionCache: util.ConcurrentTypedMap[SessionToken, CacheEntry[Session]]{}}
+	now := time.Now()
+
+	fresh := &Session{Token: "fresh", ValidDuration: DEFAULT_VALID_DURATION, LastCheckIn: now}
+	overAge := &Session{Token: "overAge", ValidDuration: DEFAULT_VALID_DURATION, LastCheckIn: now}
+	expired := &Session{Token: "expired", ValidDuration: 1000, LastCheckIn: now.Add(-time.Hour)}
+
+	authService.SessionCache.Store(fresh.Token, CacheEntry[Session]{Entry: fresh, CreatedAt: now})
+	authService.SessionCache.Store(overAge.Token, CacheEntry[Session]{Entry: overAge, CreatedAt: now.Add(-2 * SESSION_CACHE_MAX_AGE)})
+	authService.SessionCache.Store(expired.Token, CacheEntry[Session]{Entry: expired, CreatedAt: now})
+
+	if evicted := evictStaleCacheEntries(authService, now); evicted != 2 {
+		t.Errorf("unexpected amount of evicted entries: got %d, expected 2", evicted)
+	}
+	if _, exists := authService.SessionCache.Load(fresh.Token); !exists {
+		t.Error("fresh entry was evicted")
+	}
+	if _, exists := authService.SessionCache.Load(overAge.Token); exists {
+		t.Error("over-age entry was not evicted")
+	}
+	if _, exists := authService.SessionCache.Load(expired.Token); exists {
+		t.Error("expired entry was not evicted")
+	}
+}
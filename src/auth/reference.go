@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+)
+
+// Returned when a referenceID doesn't belong to any player, or the player has no active session
+var ErrNoActiveSession = errors.New("no active session")
+
+// Resolves a Vitec referenceID to all active sessions of the player, newest first.
+//
+// The sessions are read from the Session table, as the SessionCache only holds the sessions recently used
+func GetSessionsByReferenceID(ctx context.Context, referenceID string, appContext *meta.ApplicationContext) ([]Session, error) {
+	playerID, err := getPlayerIDByReferenceID(ctx, referenceID, appContext)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := GetActiveSessionsForPlayer(ctx, playerID, appContext)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNoActiveSession
+	}
+	role, err := loadRoleForPlayer(ctx, playerID, appContext)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Role = role
+	}
+	return sessions, nil
+}
+
+// The referenceID of a player never changes, so the mapping is cached indefinitely.
+// Use ForgetReferenceID when the player is deleted.
+func getPlayerIDByReferenceID(ctx context.Context, referenceID string, appContext *meta.ApplicationContext) (uint32, error) {
+	if playerID, exists := authSingleton.ReferenceCache.Load(referenceID); exists {
+		return playerID, nil
+	}
+	player, err := appContext.Players.FindByReferenceID(ctx, referenceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return 0, ErrNoActiveSession
+		}
+		return 0, err
+	}
+	authSingleton.ReferenceCache.Store(referenceID, player.ID)
+	return player.ID, nil
+}
+
+func ForgetReferenceID(referenceID string) {
+	authSingleton.ReferenceCache.Delete(referenceID)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"testing"
+	"time"
+)
+
+func TestGetSessionsByReferenceIDReturnsAllActiveSessionsNewestFirst(t *testing.T) {
+	repositories := repository.NewMemoryRepositories()
+	appContext := &meta.ApplicationContext{Repositories: repositories}
+	players := repositories.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 7, ReferenceID: "vitec-7", Role: RoleTeacher})
+	players.PutPlayer(repository.Player{ID: 8, ReferenceID: "vitec-8"})
+
+	now := time.Now()
+	for _, session := range []Session{
+		{Player: 7, Token: "older", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-time.Minute), LastCheckIn: now},
+		{Player: 7, Token: "newer", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now, LastCheckIn: now},
+		{Player: 7, Token: "expired", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-3 * time.Hour), LastCheckIn: now.Add(-2 * time.Hour)},
+		{Player: 8, Token: "other", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(time.Minute), LastCheckIn: now},
+	} {
+		if err := repositories.Sessions.Create(context.Background(), &session); err != nil {
+			t.Fatal("failed to create session:", err)
+		}
+	}
+	defer func() {
+		ForgetReferenceID("vitec-7")
+		ForgetReferenceID("unknown")
+	}()
+
+	sessions, err := GetSessionsByReferenceID(context.Background(), "vitec-7", appContext)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(sessions) != 2 || sessions[0].Token != "newer" || sessions[1].Token != "older" {
+		t.Fatalf("expected the two active sessions of player 7, newest first, got %+v", sessions)
+	}
+	if sessions[0].Role != RoleTeacher || sessions[1].Role != RoleTeacher {
+		t.Errorf("expected the role of the player on every session, got %q and %q", sessions[0].Role, sessions[1].Role)
+	}
+
+	if _, err := GetSessionsByReferenceID(context.Background(), "unknown", appContext); !errors.Is(err, ErrNoActiveSession) {
+		t.Errorf("expected ErrNoActiveSession for an unknown referenceID, got %v", err)
+	}
+}
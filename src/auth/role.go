@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"otte_main_backend/src/api/local"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Stored in the "role" column of the Player table, see repository.Role
+type Role = repository.Role
+
+const (
+	RolePlayer  = repository.RolePlayer
+	RoleTeacher = repository.RoleTeacher
+	RoleAdmin   = repository.RoleAdmin
+)
+
+// Players with no role set are regular players
+func loadRoleForPlayer(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext) (Role, error) {
+	player, err := appContext.Players.FindByID(ctx, playerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return RolePlayer, nil
+		}
+		return RolePlayer, err
+	}
+	if player.Role == "" {
+		return RolePlayer, nil
+	}
+	return player.Role, nil
+}
+
+// Returns the role placed in the locals by the auth check, defaults to RolePlayer
+func GetRoleFromContext(c *fiber.Ctx) Role {
+	if role, ok := c.Locals(local.Role).(Role); ok {
+		return role
+	}
+	return RolePlayer
+}
+
+// Same as PrefixOn, but additionally requires the session's player to have at least the given role.
+// Unlike policies, this is enforced regardless of auth level, so management routes are never left open.
+func RequireRole(appContext *meta.ApplicationContext, minimumRole Role, existingHandler func(c *fiber.Ctx, appContext *meta.ApplicationContext) error, policies ...Policy) func(*fiber.Ctx) error {
+	return PrefixOn(appContext, func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		session, err := GetSessionFromContext(c, appContext)
+		if err != nil {
+			c.Response().Header.Set(appContext.DDH, "No session found for token")
+			return ErrorUnauthorized
+		}
+		if !session.Role.IsAtLeast(minimumRole) {
+			c.Response().Header.Set(appContext.DDH, "Requires role "+string(minimumRole))
+			return ErrorForbidden
+		}
+		return existingHandler(c, appContext)
+	}, policies...)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"otte_main_backend/src/repository"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireRole(t *testing.T) {
+	app, appContext := setupStrictAuthTest(t)
+	app.Get("/admin", RequireRole(appContext, RoleAdmin, okHandler))
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1})
+	players.PutPlayer(repository.Player{ID: 2, Role: RoleTeacher})
+	players.PutPlayer(repository.Player{ID: 3, Role: RoleAdmin})
+	putTestSession(t, appContext, 1, "player")
+	putTestSession(t, appContext, 2, "teacher")
+	putTestSession(t, appContext, 3, "admin")
+
+	expectStatus(t, app, "GET", "/admin", "player", "", fiber.StatusForbidden)
+	expectStatus(t, app, "GET", "/admin", "teacher", "", fiber.StatusForbidden)
+	expectStatus(t, app, "GET", "/admin", "admin", "", fiber.StatusOK)
+	expectStatus(t, app, "GET", "/admin", "unknown", "", fiber.StatusUnauthorized)
+}
+
+func TestApplyRoleChangeUpdatesCachedSessions(t *testing.T) {
+	app, appContext := setupStrictAuthTest(t)
+	app.Get("/admin", RequireRole(appContext, RoleAdmin, okHandler))
+	players := appContext.Players.(*repository.MemoryPlayerRepository)
+	players.PutPlayer(repository.Player{ID: 1})
+	putTestSession(t, appContext, 1, "token")
+
+	//Caches the session along with the role
+	expectStatus(t, app, "GET", "/admin", "token", "", fiber.StatusForbidden)
+
+	if err := appContext.Players.SetRole(context.Background(), 1, RoleAdmin); err != nil {
+		t.Fatal("failed to set role:", err)
+	}
+	expectStatus(t, app, "GET", "/admin", "token", "", fiber.StatusForbidden)
+
+	if err := ApplyRoleChange(context.Background(), 1, appContext, authSingleton); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	expectStatus(t, app, "GET", "/admin", "token", "", fiber.StatusOK)
+}
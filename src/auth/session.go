@@ -1,59 +1,121 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base32"
 	"fmt"
 	"log"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/util"
 	"time"
 )
 
-type SessionToken string
-type Session struct {
-	ID     uint32       `json:"id" gorm:"primaryKey"` // ID
-	Player uint32       `json:"player" gorm:"foreignKey:player;references:ID"`
-	Token  SessionToken `json:"token"`
-	//MS
-	ValidDuration uint32    `json:"validDuration" gorm:"column:validDuration"` //Column defaults to 1h, or 3600000ms
-	CreatedAt     time.Time `json:"createdAt" gorm:"column:createdAt"`         //Column defaults to NOW()
-	LastCheckIn   time.Time `json:"lastCheckIn" gorm:"column:lastCheckIn"`     //Column defaults to NOW()
-}
-
-func (s *Session) TableName() string {
-	return "Session"
-}
+// Shared with the repository package, where sessions are stored
+type SessionToken = repository.SessionToken
+type Session = repository.Session
 
 // MS
 const DEFAULT_VALID_DURATION = 3600000 //1 hour
 
+const DEFAULT_MAX_SESSIONS_PER_PLAYER = 5
+
 func IsSessionStillValid(session *Session) bool {
-	return time.Since(session.LastCheckIn).Milliseconds() < int64(session.ValidDuration)
+	return time.Since(LatestCheckIn(session)).Milliseconds() < int64(session.ValidDuration)
+}
+
+// Returns the latest check-in of the session, including check-ins not yet written to the PlayerDB
+func LatestCheckIn(session *Session) time.Time {
+	if authSingleton.CheckIns != nil {
+		if recorded, exists := authSingleton.CheckIns.Latest(session.ID); exists && recorded.After(session.LastCheckIn) {
+			return recorded
+		}
+	}
+	return session.LastCheckIn
+}
+
+// Optional information about the device a session is created from
+type DeviceInfo struct {
+	Label     string
+	UserAgent string
 }
 
-func CreateSessionForPlayer(playerID uint32, appContext *meta.ApplicationContext, idOfPreviousSession int, authService *AuthService) (*Session, error) {
+// Creates a new session for the player. Existing sessions of the player (other devices) are kept,
+// unless the amount of active sessions exceeds AuthService.MaxSessionsPerPlayer, in which case the oldest are evicted.
+func CreateSessionForPlayer(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext, device DeviceInfo, authService *AuthService) (*Session, error) {
 	var token, generationErr = generateBase32String(64)
 	if generationErr != nil {
 		return nil, fmt.Errorf("unable to generate session token")
 	}
 
+	var validDuration uint32 = DEFAULT_VALID_DURATION
+	if signer := authService.Mode().Signer; signer != nil {
+		//Signed tokens can't be extended by checking in, so the row expires with the token
+		validDuration = uint32(signer.validFor.Milliseconds())
+	}
+
 	var session = Session{
-		ID:            uint32(util.Ternary(idOfPreviousSession == -1, 0, idOfPreviousSession)),
-		Token:         SessionToken(token),
+		Token:         authService.HashToken(token),
+		RawToken:      SessionToken(token),
 		Player:        playerID,
-		ValidDuration: DEFAULT_VALID_DURATION,
+		ValidDuration: validDuration,
 		CreatedAt:     time.Now(),
 		LastCheckIn:   time.Now(),
+		DeviceLabel:   device.Label,
+		UserAgent:     device.UserAgent,
 	}
 
-	if createSessionError := appContext.PlayerDB.Save(&session).Error; createSessionError != nil {
+	if createSessionError := appContext.Sessions.Create(ctx, &session); createSessionError != nil {
 		return nil, fmt.Errorf("unable to save session")
 	}
-	authService.SessionCache.Store(session.Token, CacheEntry[Session]{Entry: &session, CreatedAt: time.Now()})
+	role, roleErr := loadRoleForPlayer(ctx, playerID, appContext)
+	if roleErr != nil {
+		return nil, fmt.Errorf("unable to load player role")
+	}
+	session.Role = role
+	//The raw token is kept out of the cache
+	cachedSession := session
+	cachedSession.RawToken = ""
+	authService.SessionCache.Store(session.Token, CacheEntry[Session]{Entry: &cachedSession, CreatedAt: time.Now()})
+
+	if evictionErr := enforceSessionCap(ctx, playerID, appContext, authService); evictionErr != nil {
+		//The new session is valid regardless, so this is only logged
+		log.Println("[AUTH] INTERNAL ERROR: unable to evict old sessions: " + evictionErr.Error())
+	}
 	return &session, nil
 }
 
+// Returns all sessions of the player that are still valid, newest first
+func GetActiveSessionsForPlayer(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext) ([]Session, error) {
+	sessions, err := appContext.Sessions.FindByPlayer(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	return util.ArrayFilter(sessions, func(session Session) bool { return IsSessionStillValid(&session) }), nil
+}
+
+// Deletes expired sessions of the player, as well as the oldest sessions exceeding the per-player cap
+func enforceSessionCap(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext, authService *AuthService) error {
+	sessions, err := appContext.Sessions.FindByPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+
+	var toEvict []uint32
+	var activeCount = 0
+	for _, session := range sessions {
+		if IsSessionStillValid(&session) && activeCount < authService.MaxSessionsPerPlayer {
+			activeCount++
+			continue
+		}
+		toEvict = append(toEvict, session.ID)
+		authService.SessionCache.Delete(session.Token)
+		denySessionToken(ctx, session.Token, appContext, authService)
+	}
+	return appContext.Sessions.DeleteByIDs(ctx, toEvict)
+}
+
 func generateBase32String(length int) (string, error) {
 	bytes := make([]byte, length)
 	if _, err := rand.Read(bytes); err != nil {
@@ -62,9 +124,59 @@ func generateBase32String(length int) (string, error) {
 	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes), nil
 }
 
+// Records a check-in for the session, which is written to the PlayerDB on the next flush of the CheckInRecorder.
+// Written immediately if the recorder isn't started.
+func (authService *AuthService) RecordCheckIn(session *Session, appContext *meta.ApplicationContext) {
+	if authService.CheckIns == nil {
+		sessionCopy := *session
+		go UpdateLastPlayerCheckin(&sessionCopy, appContext)
+		return
+	}
+	authService.CheckIns.Record(session.ID, time.Now())
+}
+
+// Not bound to the context of the request checking in, as that request is usually done before the update is made
 func UpdateLastPlayerCheckin(session *Session, appContext *meta.ApplicationContext) {
 	session.LastCheckIn = time.Now()
-	if updateErr := appContext.PlayerDB.Save(session).Error; updateErr != nil {
+	if updateErr := appContext.Sessions.UpdateLastCheckIn(context.Background(), session.ID, session.LastCheckIn); updateErr != nil {
 		log.Println("[AUTH] INTERNAL ERROR: " + updateErr.Error())
 	}
 }
+
+// Removes the session with the given (hashed) token from the PlayerDB and evicts it from the SessionCache.
+// Returns repository.ErrNotFound if no such session exists.
+func RevokeSession(ctx context.Context, token SessionToken, appContext *meta.ApplicationContext, authService *AuthService) error {
+	//Evict first, so the token is rejected even if the DB delete fails
+	authService.SessionCache.Delete(token)
+	denySessionToken(ctx, token, appContext, authService)
+	return appContext.Sessions.DeleteByToken(ctx, token)
+}
+
+// Removes all sessions of the given player from the PlayerDB and evicts them from the SessionCache.
+// Returns the amount of sessions removed from the DB.
+func RevokeAllSessionsForPlayer(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext, authService *AuthService) (int64, error) {
+	EvictCachedSessionsOfPlayer(playerID, authService)
+	//Signed tokens aren't checked against the DB, so every session has to be denied explicitly
+	if err := denySessionTokensOfPlayer(ctx, playerID, appContext, authService); err != nil {
+		return 0, err
+	}
+	return appContext.Sessions.DeleteByPlayer(ctx, playerID)
+}
+
+// Makes the sessions of the player pick up a changed role. Cached sessions are evicted, so the role is read again.
+// Signed tokens carry the role in their claims, so with signed auth they are denied and the player has to create a new session.
+func ApplyRoleChange(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext, authService *AuthService) error {
+	EvictCachedSessionsOfPlayer(playerID, authService)
+	return denySessionTokensOfPlayer(ctx, playerID, appContext, authService)
+}
+
+// Evicts the cached sessions of the player, so the next request of each is checked against the PlayerDB again.
+// Use when something cached along with the session changes, e.g. the role of the player.
+func EvictCachedSessionsOfPlayer(playerID uint32, authService *AuthService) {
+	authService.SessionCache.Range(func(token SessionToken, entry CacheEntry[Session]) bool {
+		if entry.Entry != nil && entry.Entry.Player == playerID {
+			authService.SessionCache.Delete(token)
+		}
+		return true
+	})
+}
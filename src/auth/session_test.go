@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"testing"
+	"time"
+)
+
+func TestSessionCapEvictsExpiredThenOldestSessions(t *testing.T) {
+	appContext := &meta.ApplicationContext{Repositories: repository.NewMemoryRepositories()}
+	authService := &AuthService{MaxSessionsPerPlayer: 2}
+	now := time.Now()
+	existing := []Session{
+		{Player: 1, Token: "oldest", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-2 * time.Hour), LastCheckIn: now},
+		{Player: 1, Token: "expired", ValidDuration: 1000, CreatedAt: now.Add(-30 * time.Minute), LastCheckIn: now.Add(-30 * time.Minute)},
+		{Player: 1, Token: "newer", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-time.Minute), LastCheckIn: now},
+		{Player: 2, Token: "other-player", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-3 * time.Hour), LastCheckIn: now},
+	}
+	for i := range existing {
+		if err := appContext.Sessions.Create(context.Background(), &existing[i]); err != nil {
+			t.Fatal("failed to create session:", err)
+		}
+	}
+	authService.SessionCache.Store("oldest", CacheEntry[Session]{Entry: &existing[0], CreatedAt: now})
+
+	created, err := CreateSessionForPlayer(context.Background(), 1, appContext, DeviceInfo{Label: "phone"}, authService)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	sessions, _ := appContext.Sessions.FindByPlayer(context.Background(), 1)
+	if len(sessions) != 2 || sessions[0].Token != created.Token || sessions[1].Token != "newer" {
+		t.Errorf("expected only the new and the newest existing session to be kept, got: %+v", sessions)
+	}
+	if _, cached := authService.SessionCache.Load("oldest"); cached {
+		t.Error("expected the evicted session to be removed from the cache")
+	}
+	if others, _ := appContext.Sessions.FindByPlayer(context.Background(), 2); len(others) != 1 {
+		t.Errorf("expected sessions of other players to be left alone, got: %+v", others)
+	}
+}
+
+func TestSessionCapDeniesEvictedSignedTokens(t *testing.T) {
+	appContext := &meta.ApplicationContext{Repositories: repository.NewMemoryRepositories()}
+	signer, _ := NewTokenSigner("k1:"+testKey('a'), time.Hour)
+	authService := &AuthService{MaxSessionsPerPlayer: 1}
+	authService.UseMode(&AuthMode{Level: AuthLevelSigned, Signer: signer})
+	now := time.Now()
+	evicted := Session{Player: 1, Token: "evicted", ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now.Add(-time.Hour), LastCheckIn: now}
+	if err := appContext.Sessions.Create(context.Background(), &evicted); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+
+	if _, err := CreateSessionForPlayer(context.Background(), 1, appContext, DeviceInfo{}, authService); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	//Signed tokens are checked without looking up the session, so deleting it isn't enough
+	if _, denied := authService.Denylist.Load("evicted"); !denied {
+		t.Error("expected the token of the evicted session to be denied")
+	}
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"otte_main_backend/src/meta"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Minimum length of signing keys in bytes
+const MIN_SIGNING_KEY_LENGTH = 32
+
+// Claims carried by a signed token. Short json names to keep the header small.
+type SignedTokenClaims struct {
+	//The (hashed) token of the Session row this signed token wraps, used for revocation
+	SessionToken SessionToken `json:"sid"`
+	Player       uint32       `json:"pid"`
+	Role         Role         `json:"rol"`
+	IssuedAt     int64        `json:"iat"` //Unix seconds
+	ExpiresAt    int64        `json:"exp"` //Unix seconds
+}
+
+// Issues and verifies HMAC-SHA256 signed tokens of the form: "<keyID>.<base64 claims>.<base64 signature>"
+//
+// The first key is used for signing, all keys are accepted when verifying, which allows for key rotation:
+// Add the new key in front, wait for tokens signed with the old key to expire, then remove the old key.
+type TokenSigner struct {
+	signingKeyID string
+	keys         map[string][]byte
+	validFor     time.Duration
+}
+
+// Parses keys from the format: "keyID:base64Key,otherKeyID:base64Key"
+func NewTokenSigner(keysStr string, validFor time.Duration) (*TokenSigner, error) {
+	signer := &TokenSigner{keys: map[string][]byte{}, validFor: validFor}
+	for _, keyStr := range strings.Split(keysStr, ",") {
+		id, encodedKey, found := strings.Cut(strings.TrimSpace(keyStr), ":")
+		if !found || id == "" || strings.Contains(id, ".") {
+			return nil, fmt.Errorf("invalid signing key entry, expected keyID:base64Key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s is not valid base64: %s", id, err.Error())
+		}
+		if len(key) < MIN_SIGNING_KEY_LENGTH {
+			return nil, fmt.Errorf("signing key %s too short, must be at least %d bytes", id, MIN_SIGNING_KEY_LENGTH)
+		}
+		if _, exists := signer.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate signing key id: %s", id)
+		}
+		if signer.signingKeyID == "" {
+			signer.signingKeyID = id
+		}
+		signer.keys[id] = key
+	}
+	return signer, nil
+}
+
+func (s *TokenSigner) Sign(session *Session, now time.Time) (string, error) {
+	claims := SignedTokenClaims{
+		SessionToken: session.Token,
+		Player:       session.Player,
+		Role:         session.Role,
+		IssuedAt:     now.Unix(),
+		ExpiresAt:    now.Add(s.validFor).Unix(),
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := s.signingKeyID + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(computeSignature(s.keys[s.signingKeyID], unsigned)), nil
+}
+
+// Checks signature and expiry. Does not check the denylist.
+func (s *TokenSigner) Verify(token string, now time.Time) (*SignedTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	key, exists := s.keys[parts[0]]
+	if !exists {
+		return nil, fmt.Errorf("unknown signing key")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	if !hmac.Equal(signature, computeSignature(key, parts[0]+"."+parts[1])) {
+		return nil, fmt.Errorf("invalid signature")
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed claims")
+	}
+	var claims SignedTokenClaims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims")
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+	return &claims, nil
+}
+
+func computeSignature(key []byte, content string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return mac.Sum(nil)
+}
+
+// Verifies the signed token without any DB lookup. Revoked sessions are rejected through the denylist.
+func signedTokenCheckAuth(authService *AuthService, signer *TokenSigner, c *fiber.Ctx, appContext *meta.ApplicationContext) *fiber.Error {
+	authHeaderContent := string(c.Request().Header.Peek(appContext.AuthTokenName))
+	if len(authHeaderContent) == 0 {
+		c.Response().Header.Set(appContext.DDH, "Missing auth header, expected "+appContext.AuthTokenName+" to be present")
+		return ErrorUnauthorized
+	}
+	now := time.Now()
+	claims, err := signer.Verify(authHeaderContent, now)
+	if err != nil {
+		c.Response().Header.Set(appContext.DDH, "Invalid session token: "+err.Error())
+		return ErrorUnauthorized
+	}
+	if deniedUntil, denied := authService.Denylist.Load(claims.SessionToken); denied && now.Before(deniedUntil) {
+		c.Response().Header.Set(appContext.DDH, "Session revoked")
+		return ErrorUnauthorized
+	}
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	appendLocalsToContext(c, &Session{
+		Token:         claims.SessionToken,
+		Player:        claims.Player,
+		Role:          claims.Role,
+		CreatedAt:     issuedAt,
+		LastCheckIn:   now,
+		ValidDuration: uint32(time.Unix(claims.ExpiresAt, 0).Sub(issuedAt).Milliseconds()),
+	})
+	return nil
+}
+
+// Prevents any signed token wrapping the session token from being accepted until it would have expired anyway.
+// The denial is stored in the PlayerDB as well, so it survives restarts and reaches other instances, see loadDenylist.
+// No-op when not using signed tokens.
+func denySessionToken(ctx context.Context, token SessionToken, appContext *meta.ApplicationContext, authService *AuthService) {
+	signer := authService.Mode().Signer
+	if signer == nil {
+		return
+	}
+	deniedUntil := time.Now().Add(signer.validFor)
+	authService.Denylist.Store(token, deniedUntil)
+	if err := appContext.Sessions.Deny(ctx, token, deniedUntil); err != nil {
+		log.Println("[AUTH] INTERNAL ERROR: unable to store denied session token, only denied by this instance: " + err.Error())
+	}
+}
+
+// Denies every session of the player, see denySessionToken
+func denySessionTokensOfPlayer(ctx context.Context, playerID uint32, appContext *meta.ApplicationContext, authService *AuthService) error {
+	if authService.Mode().Signer == nil {
+		return nil
+	}
+	sessions, err := appContext.Sessions.FindByPlayer(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		denySessionToken(ctx, session.Token, appContext, authService)
+	}
+	return nil
+}
+
+// Adds the tokens denied in the PlayerDB to the denylist, including those denied by other instances.
+// Returns the amount of tokens denied in the PlayerDB.
+func loadDenylist(ctx context.Context, appContext *meta.ApplicationContext, authService *AuthService) (int, error) {
+	denied, err := appContext.Sessions.FindDenied(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range denied {
+		if current, exists := authService.Denylist.Load(entry.Token); !exists || current.Before(entry.DeniedUntil) {
+			authService.Denylist.Store(entry.Token, entry.DeniedUntil)
+		}
+	}
+	return len(denied), nil
+}
+
+// Returns the token to hand to the client for the session.
+// This is the signed token when using signed auth, and the session token itself otherwise.
+func (authService *AuthService) ClientTokenFor(session *Session) (string, error) {
+	signer := authService.Mode().Signer
+	if signer == nil {
+		if session.RawToken == "" {
+			return "", fmt.Errorf("raw session token unknown")
+		}
+		return string(session.RawToken), nil
+	}
+	return signer.Sign(session, time.Now())
+}
+
+// Removes denylist entries for tokens that have expired by now anyway
+func evictExpiredDenylistEntries(authService *AuthService, now time.Time) int64 {
+	var evicted int64 = 0
+	authService.Denylist.Range(func(token SessionToken, deniedUntil time.Time) bool {
+		if !now.Before(deniedUntil) && authService.Denylist.CompareAndDelete(token, deniedUntil) {
+			evicted++
+		}
+		return true
+	})
+	return evicted
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(fill byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(fill), MIN_SIGNING_KEY_LENGTH)))
+}
+
+func TestSignedTokenRoundTrip(t *testing.T) {
+	signer, err := NewTokenSigner("k1:"+testKey('a'), time.Hour)
+	if err != nil {
+		t.Fatal("failed to create signer:", err)
+	}
+	now := time.Now()
+	token, err := signer.Sign(&Session{Token: "opaque", Player: 42, Role: RoleTeacher}, now)
+	if err != nil {
+		t.Fatal("failed to sign:", err)
+	}
+
+	claims, err := signer.Verify(token, now)
+	if err != nil {
+		t.Fatal("failed to verify:", err)
+	}
+	if claims.Player != 42 || claims.SessionToken != "opaque" || claims.Role != RoleTeacher {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	if _, err := signer.Verify(token, now.Add(2*time.Hour)); err == nil {
+		t.Error("expected expired token to be rejected")
+	}
+}
+
+func TestSignedTokenTampering(t *testing.T) {
+	signer, _ := NewTokenSigner("k1:"+testKey('a'), time.Hour)
+	now := time.Now()
+	token, _ := signer.Sign(&Session{Token: "opaque", Player: 42}, now)
+	parts := strings.Split(token, ".")
+
+	forgedClaims := base64.RawURLEncoding.EncodeToString([]byte(`{"sid":"opaque","pid":1,"iat":0,"exp":9999999999}`))
+	if _, err := signer.Verify(parts[0]+"."+forgedClaims+"."+parts[2], now); err == nil {
+		t.Error("expected token with altered claims to be rejected")
+	}
+
+	otherSigner, _ := NewTokenSigner("k1:"+testKey('b'), time.Hour)
+	if _, err := otherSigner.Verify(token, now); err == nil {
+		t.Error("expected token signed with another key to be rejected")
+	}
+}
+
+func TestSignedTokenKeyRotation(t *testing.T) {
+	oldSigner, _ := NewTokenSigner("k1:"+testKey('a'), time.Hour)
+	rotatedSigner, err := NewTokenSigner("k2:"+testKey('b')+",k1:"+testKey('a'), time.Hour)
+	if err != nil {
+		t.Fatal("failed to create signer:", err)
+	}
+	now := time.Now()
+	oldToken, _ := oldSigner.Sign(&Session{Token: "opaque", Player: 1}, now)
+	if _, err := rotatedSigner.Verify(oldToken, now); err != nil {
+		t.Error("expected token signed with old key to still be accepted:", err)
+	}
+	newToken, _ := rotatedSigner.Sign(&Session{Token: "opaque", Player: 1}, now)
+	if !strings.HasPrefix(newToken, "k2.") {
+		t.Error("expected new tokens to be signed with the first key")
+	}
+	if _, err := oldSigner.Verify(newToken, now); err == nil {
+		t.Error("expected token signed with unknown key to be rejected")
+	}
+}
+
+func TestTokenSignerRejectsShortKeys(t *testing.T) {
+	if _, err := NewTokenSigner("k1:"+base64.StdEncoding.EncodeToString([]byte("short")), time.Hour); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+}
+
+func TestDeniedSessionTokensOutliveTheInstance(t *testing.T) {
+	signer, _ := NewTokenSigner("k1:"+testKey('a'), time.Hour)
+	appContext := &meta.ApplicationContext{Repositories: repository.NewMemoryRepositories()}
+	now := time.Now()
+	for _, token := range []SessionToken{"first", "second"} {
+		session := &Session{Player: 7, Token: token, ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now, LastCheckIn: now}
+		if err := appContext.Sessions.Create(context.Background(), session); err != nil {
+			t.Fatal("failed to create session:", err)
+		}
+	}
+
+	//A role change denies every session of the player, as their signed tokens carry the previous role
+	revoking := &AuthService{}
+	revoking.UseMode(&AuthMode{Level: AuthLevelSigned, Signer: signer})
+	if err := ApplyRoleChange(context.Background(), 7, appContext, revoking); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	//Another instance, or this one after a restart, only knows the denials through the PlayerDB
+	restarted := &AuthService{}
+	restarted.UseMode(&AuthMode{Level: AuthLevelSigned, Signer: signer})
+	loaded, err := loadDenylist(context.Background(), appContext, restarted)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if loaded != 2 {
+		t.Errorf("expected 2 denied tokens to be loaded, got %d", loaded)
+	}
+	for _, token := range []SessionToken{"first", "second"} {
+		if deniedUntil, denied := restarted.Denylist.Load(token); !denied || !deniedUntil.After(now) {
+			t.Errorf("expected %s to be denied, got %v until %s", token, denied, deniedUntil)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/meta"
+)
+
+// Length of the hex encoded HMAC-SHA256 stored in the token column.
+// Raw tokens are base32 encoded and much longer, which is how not-yet-hashed rows are recognized.
+const HASHED_TOKEN_LENGTH = sha256.Size * 2
+
+// Session tokens are never stored as is. Only a keyed hash of the token is stored in the DB,
+// used as key in the SessionCache and denylist, so that read access to the DB (or its backups)
+// does not allow taking over sessions.
+func (authService *AuthService) HashToken(rawToken string) SessionToken {
+	mac := hmac.New(sha256.New, authService.tokenHashKey)
+	mac.Write([]byte(rawToken))
+	return SessionToken(hex.EncodeToString(mac.Sum(nil)))
+}
+
+func loadTokenHashKey(encodedKey config.Secret) ([]byte, error) {
+	if encodedKey == "" {
+		return nil, fmt.Errorf("SESSION_TOKEN_HASH_KEY must be set, generate one with: openssl rand -base64 32")
+	}
+	key, err := base64.StdEncoding.DecodeString(encodedKey.Reveal())
+	if err != nil {
+		return nil, fmt.Errorf("SESSION_TOKEN_HASH_KEY is not valid base64: %s", err.Error())
+	}
+	if len(key) < MIN_SIGNING_KEY_LENGTH {
+		return nil, fmt.Errorf("SESSION_TOKEN_HASH_KEY too short, must be at least %d bytes", MIN_SIGNING_KEY_LENGTH)
+	}
+	return key, nil
+}
+
+// Migration path for sessions created before tokens were hashed: hashes any raw token left in the Session table.
+// Idempotent, as rows already hashed are skipped. Changing SESSION_TOKEN_HASH_KEY invalidates all sessions.
+func migrateUnhashedSessionTokens(appContext *meta.ApplicationContext, authService *AuthService) error {
+	sessions, err := appContext.Sessions.FindUnhashed(context.Background(), HASHED_TOKEN_LENGTH)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := appContext.Sessions.ReplaceToken(context.Background(), session.ID, session.Token, authService.HashToken(string(session.Token))); err != nil {
+			return err
+		}
+	}
+	if len(sessions) > 0 {
+		log.Printf("[AUTH] Hashed %d session token(s) previously stored in plain text\n", len(sessions))
+	}
+	return nil
+}
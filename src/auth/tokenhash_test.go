@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/repository"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionsAreOnlyFoundByHashedToken(t *testing.T) {
+	appContext := &meta.ApplicationContext{Repositories: repository.NewMemoryRepositories()}
+	authService := &AuthService{MaxSessionsPerPlayer: 5, tokenHashKey: []byte(strings.Repeat("k", MIN_SIGNING_KEY_LENGTH))}
+
+	session, err := CreateSessionForPlayer(context.Background(), 1, appContext, DeviceInfo{}, authService)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(session.Token) == string(session.RawToken) || len(session.Token) != HASHED_TOKEN_LENGTH {
+		t.Fatalf("expected only the hash of the token to be stored, got %s", session.Token)
+	}
+	if found, err := appContext.Sessions.FindByToken(context.Background(), authService.HashToken(string(session.RawToken))); err != nil || found.ID != session.ID {
+		t.Errorf("expected the session to be found by the hash of its token, got %+v, %v", found, err)
+	}
+	if _, err := appContext.Sessions.FindByToken(context.Background(), session.RawToken); !errors.Is(err, repository.ErrNotFound) {
+		t.Error("expected the raw token not to match, got:", err)
+	}
+
+	otherKey := &AuthService{tokenHashKey: []byte(strings.Repeat("o", MIN_SIGNING_KEY_LENGTH))}
+	if otherKey.HashToken(string(session.RawToken)) == session.Token {
+		t.Error("expected the hash to depend on the key")
+	}
+}
+
+func TestMigrateUnhashedSessionTokensIsIdempotent(t *testing.T) {
+	appContext := &meta.ApplicationContext{Repositories: repository.NewMemoryRepositories()}
+	authService := &AuthService{tokenHashKey: []byte(strings.Repeat("k", MIN_SIGNING_KEY_LENGTH))}
+	rawToken, _ := generateBase32String(64)
+	now := time.Now()
+	legacy := Session{Player: 1, Token: SessionToken(rawToken), ValidDuration: DEFAULT_VALID_DURATION, CreatedAt: now, LastCheckIn: now}
+	if err := appContext.Sessions.Create(context.Background(), &legacy); err != nil {
+		t.Fatal("failed to create session:", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := migrateUnhashedSessionTokens(appContext, authService); err != nil {
+			t.Fatalf("unexpected error on run %d: %s", run, err.Error())
+		}
+		//Hashed once, rows already hashed are left alone
+		if found, err := appContext.Sessions.FindByToken(context.Background(), authService.HashToken(rawToken)); err != nil || found.ID != legacy.ID {
+			t.Errorf("expected the session to be found by the hash of its token after run %d, got %+v, %v", run, found, err)
+		}
+	}
+	if _, err := appContext.Sessions.FindByToken(context.Background(), SessionToken(rawToken)); !errors.Is(err, repository.ErrNotFound) {
+		t.Error("expected the raw token to no longer match after the migration, got:", err)
+	}
+	if unhashed, _ := appContext.Sessions.FindUnhashed(context.Background(), HASHED_TOKEN_LENGTH); len(unhashed) != 0 {
+		t.Errorf("expected no unhashed tokens to be left, got %+v", unhashed)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"otte_main_backend/src/config"
+	db "otte_main_backend/src/database"
+	"otte_main_backend/src/database/migrations"
+	"otte_main_backend/src/database/seed"
+
+	"gorm.io/gorm"
+)
+
+// Runs a command given instead of starting the server, see config.RequestedCommand
+func runCommand(cfg *config.Config, command string, args []string) error {
+	switch command {
+	case "migrate":
+		return runMigrateCommand(cfg, args)
+	case "seed":
+		return runSeedCommand(cfg, args)
+	default:
+		return fmt.Errorf("unknown command: %s, available: migrate, seed", command)
+	}
+}
+
+// migrate [up|down|status] [--db player|colony|language|all] [--to <version>] [--steps <n>]
+//
+//	up      applies pending migrations, up to --to if given (default)
+//	down    reverts the latest --steps migrations (default 1), requires a single --db
+//	status  lists every migration and when it was applied
+func runMigrateCommand(cfg *config.Config, args []string) error {
+	action := "up"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		action = args[0]
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	database := flags.String("db", "all", "player | colony | language | all")
+	to := flags.Int("to", 0, "version to migrate up to, default: latest")
+	steps := flags.Int("steps", 1, "amount of migrations to revert")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if action != "up" && action != "down" && action != "status" {
+		return fmt.Errorf("unknown migrate action: %s, expected up, down or status", action)
+	}
+	if *database == "all" && action == "down" {
+		return fmt.Errorf("migrate down requires a single --db, as versions differ between databases")
+	}
+	if *database == "all" && *to != 0 {
+		return fmt.Errorf("migrate up --to requires a single --db, as versions differ between databases")
+	}
+
+	if *database != "all" && !isKnownDatabase(*database) {
+		return fmt.Errorf("unknown database: %s, expected player, colony, language or all", *database)
+	}
+
+	colonyAssetDB, languageDB, playerDB, _, err := ConnectDatabases(cfg, false)
+	if err != nil {
+		return err
+	}
+	targets := migrationTargets(colonyAssetDB, languageDB, playerDB)
+	for _, name := range migrations.Databases {
+		if *database != "all" && *database != string(name) {
+			continue
+		}
+		migrator, err := migrations.NewMigrator(name, targets[name])
+		if err != nil {
+			return err
+		}
+		switch action {
+		case "up":
+			applied, err := migrator.Up(*to)
+			if err != nil {
+				return err
+			}
+			log.Printf("[migrations] %s: applied %v\n", name, applied)
+		case "down":
+			reverted, err := migrator.Down(*steps)
+			if err != nil {
+				return err
+			}
+			log.Printf("[migrations] %s: reverted %v\n", name, reverted)
+		case "status":
+			status, err := migrator.Status()
+			if err != nil {
+				return err
+			}
+			for _, entry := range status {
+				appliedAt := "pending"
+				if entry.AppliedAt != nil {
+					appliedAt = "applied " + entry.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(os.Stdout, "%-9s %04d_%-30s %s\n", name, entry.Version, entry.Name, appliedAt)
+			}
+		}
+	}
+	return nil
+}
+
+// seed [--db player|colony|language|all]
+//
+// Loads the development fixtures (src/database/seed/fixtures) into the databases. Seeding again updates the fixtures
+// in place, so it is safe to rerun after changing them. Requires the migrations to be applied, and only runs with --dev.
+func runSeedCommand(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	database := flags.String("db", "all", "player | colony | language | all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *database != "all" && !isKnownDatabase(*database) {
+		return fmt.Errorf("unknown database: %s, expected player, colony, language or all", *database)
+	}
+	if config.RequestedMode() != config.RuntimeModeDev {
+		return fmt.Errorf("seed is for development databases only, run it with --dev")
+	}
+	fixtures, err := seed.Load()
+	if err != nil {
+		return err
+	}
+
+	colonyAssetDB, languageDB, playerDB, _, err := ConnectDatabases(cfg, false)
+	if err != nil {
+		return err
+	}
+	targets := migrationTargets(colonyAssetDB, languageDB, playerDB)
+	for _, name := range migrations.Databases {
+		if *database != "all" && *database != string(name) {
+			continue
+		}
+		migrator, err := migrations.NewMigrator(name, targets[name])
+		if err != nil {
+			return err
+		}
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, entry := range status {
+			if entry.AppliedAt == nil {
+				return fmt.Errorf("%s has pending migrations, apply them first with: migrate up --db %s", name, name)
+			}
+		}
+		counts, err := seed.Seed(name, targets[name], fixtures)
+		if err != nil {
+			return err
+		}
+		seed.LogCounts(name, counts)
+	}
+	return nil
+}
+
+func isKnownDatabase(name string) bool {
+	for _, database := range migrations.Databases {
+		if string(database) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Applies every pending migration to each database, see DB_AUTO_MIGRATE. Degraded databases are migrated once
+// reachable again, and kept degraded until then, so they are never used with an outdated schema.
+func autoMigrate(colonyAssetDB *gorm.DB, languageDB *gorm.DB, playerDB *gorm.DB, dbMonitor *db.Monitor) error {
+	targets := migrationTargets(colonyAssetDB, languageDB, playerDB)
+	for _, name := range migrations.Databases {
+		name := name
+		if !dbMonitor.IsHealthy(string(name)) {
+			log.Printf("[migrations] %s: deferred until the database is reachable\n", name)
+			dbMonitor.RequireBeforeHealthy(string(name), func(target *gorm.DB) error {
+				return migrateUp(name, target)
+			})
+			continue
+		}
+		if err := migrateUp(name, targets[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateUp(name migrations.Database, target *gorm.DB) error {
+	migrator, err := migrations.NewMigrator(name, target)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(0)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		log.Printf("[migrations] %s: applied %v\n", name, applied)
+	}
+	return nil
+}
+
+func migrationTargets(colonyAssetDB *gorm.DB, languageDB *gorm.DB, playerDB *gorm.DB) map[migrations.Database]*gorm.DB {
+	return map[migrations.Database]*gorm.DB{
+		migrations.DatabasePlayer:   playerDB,
+		migrations.DatabaseColony:   colonyAssetDB,
+		migrations.DatabaseLanguage: languageDB,
+	}
+}
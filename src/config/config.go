@@ -11,8 +11,6 @@ import (
 	"github.com/joho/godotenv"
 )
 
-var cache = map[string]string{}
-
 type RuntimeMode string
 
 const (
@@ -21,35 +19,22 @@ const (
 	RuntimeModeProd    RuntimeMode = "prod"
 )
 
-// Checks the exec args and loads the first one found. Ignores the rest.
-// Accepts flags: "--dev", "--prod" (prod is handled by the docker-compose file currentyly)
+// Flags parsed from the exec args by DetectAndApplyENV
+var parsedFlags = &Flags{Mode: RuntimeModeUnknown}
+
+// Parses the exec args and applies every config source they name, see Flags.Apply for the precedence.
 func DetectAndApplyENV() (RuntimeMode, error) {
 	wd, wdErr := os.Getwd()
 	if wdErr != nil {
 		return RuntimeModeUnknown, fmt.Errorf("[config] Error getting working directory: %s", wdErr.Error())
 	}
-	var runtimeMode RuntimeMode
 	log.Printf("[config] Working directory: %s\n", wd)
-	args := os.Args
-	var envErr error
-	for _, arg := range args[1:] {
-		if arg == "--dev" {
-			log.Println("[config] --dev flag found, loading dev config")
-			envErr = LoadDevConfig()
-			runtimeMode = RuntimeModeDev
-		}
-		if arg == "--prod" {
-			log.Println("[config] --prod flag found, loading prod config")
-			envErr = LoadProdConfig()
-			runtimeMode = RuntimeModeProd
-		}
-
-		if envErr != nil {
-			return runtimeMode, envErr
-		}
+	flags, err := ParseFlags(os.Args[1:])
+	if err != nil {
+		return RuntimeModeUnknown, err
 	}
-
-	return runtimeMode, nil
+	parsedFlags = flags
+	return flags.Mode, flags.Apply()
 }
 
 // Overwrites any env variables currently set in environment
@@ -69,14 +54,17 @@ func LoadProdConfig() error {
 	return LoadCustomConfig("prod.env")
 }
 
-// Overwrites any env variables currently set in environment
+// Overwrites any env variables currently set in environment. Relative paths are resolved from the working directory.
 func LoadCustomConfig(nameOfFile string) error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("[config] Error getting working directory: %s", err.Error())
+	qualifiedPath := nameOfFile
+	if !filepath.IsAbs(nameOfFile) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("[config] Error getting working directory: %s", err.Error())
+		}
+		qualifiedPath = filepath.Join(wd, nameOfFile)
 	}
-	qualifiedPath := filepath.Join(wd, nameOfFile)
-	err = godotenv.Overload(qualifiedPath) // Overwrites all env variables with the ones in the .env file
+	err := godotenv.Overload(qualifiedPath) // Overwrites all env variables with the ones in the .env file
 	if err != nil {
 		return fmt.Errorf("[config] Error loading .env file into environment: %s", err.Error())
 	}
@@ -85,15 +73,12 @@ func LoadCustomConfig(nameOfFile string) error {
 
 // LoudGet func to get env value, will return an error on empty string
 // The value of the key will be trimmed/stripped/whitespace removed
+// Always reads the environment, so values changed by ReloadFromSources are seen
 func LoudGet(key string) (string, error) {
-	if val, exists := cache[key]; exists {
-		return val, nil
-	}
 	val := strings.TrimSpace(os.Getenv(key))
 	if val == "" {
 		return "", fmt.Errorf("[config] Tried to access env: %s but failed", key)
 	}
-	cache[key] = val
 	return val, nil
 }
 
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Appended to any key to read its value from a file instead, e.g. PLAYER_DB_PASSWORD_FILE=/run/secrets/player_db_password
+const FILE_SUFFIX = "_FILE"
+
+type override struct {
+	key   string
+	value string
+}
+
+// The exec args, see ParseFlags
+type Flags struct {
+	Mode RuntimeMode
+	// --config <file>, in the order given
+	ConfigFiles []string
+	// --set KEY=value, in the order given
+	overrides   []override
+	PrintConfig bool
+	// First argument not starting with "-", e.g. migrate. Empty to run the server
+	Command string
+	// Every argument after Command, left for the command to parse
+	CommandArgs []string
+}
+
+// Accepts:
+//
+//	--dev                 load dev.env and dev.credentials
+//	--prod                load prod.env
+//	--config <file>       load an env file, may be repeated
+//	--set KEY=value       set a single key, may be repeated
+//	--print-config        print the effective configuration and exit
+//	<command> [args...]   run a command instead of the server, e.g. migrate up
+//
+// Unknown arguments before the command are an error, arguments after it are left to the command.
+func ParseFlags(args []string) (*Flags, error) {
+	flags := &Flags{Mode: RuntimeModeUnknown}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			flags.Command = arg
+			flags.CommandArgs = args[i+1:]
+			break
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "--dev", "--prod", "--print-config":
+			if hasValue {
+				return nil, fmt.Errorf("[config] %s does not take a value", name)
+			}
+		case "--config", "--set":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return nil, fmt.Errorf("[config] %s requires a value", name)
+				}
+				i++
+				value = args[i]
+			}
+		default:
+			return nil, fmt.Errorf("[config] Unknown argument: %s", arg)
+		}
+
+		switch name {
+		case "--dev", "--prod":
+			mode := RuntimeModeDev
+			if name == "--prod" {
+				mode = RuntimeModeProd
+			}
+			if flags.Mode != RuntimeModeUnknown && flags.Mode != mode {
+				return nil, fmt.Errorf("[config] --dev and --prod are mutually exclusive")
+			}
+			flags.Mode = mode
+		case "--print-config":
+			flags.PrintConfig = true
+		case "--config":
+			flags.ConfigFiles = append(flags.ConfigFiles, value)
+		case "--set":
+			key, keyValue, ok := strings.Cut(value, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("[config] --set expects KEY=value, got: %s", value)
+			}
+			flags.overrides = append(flags.overrides, override{key: strings.TrimSpace(key), value: keyValue})
+		}
+	}
+	return flags, nil
+}
+
+// Applies every source named by the flags to the environment. Later sources take precedence, in order:
+//
+//  1. The process environment
+//  2. dev.env then dev.credentials (--dev), or prod.env (--prod)
+//  3. Each --config file, in the order given
+//  4. Each --set override, in the order given
+//
+// A key may instead be read from a file through <KEY>_FILE, see FILE_SUFFIX. Setting both is an error.
+func (flags *Flags) Apply() error {
+	switch flags.Mode {
+	case RuntimeModeDev:
+		log.Println("[config] --dev flag found, loading dev config")
+		if err := LoadDevConfig(); err != nil {
+			return err
+		}
+	case RuntimeModeProd:
+		log.Println("[config] --prod flag found, loading prod config")
+		if err := LoadProdConfig(); err != nil {
+			return err
+		}
+	}
+	for _, file := range flags.ConfigFiles {
+		log.Printf("[config] Loading config file: %s\n", file)
+		if err := LoadCustomConfig(file); err != nil {
+			return err
+		}
+	}
+	for _, override := range flags.overrides {
+		log.Printf("[config] Overriding %s from the command line\n", override.key)
+		if err := os.Setenv(override.key, override.value); err != nil {
+			return fmt.Errorf("[config] Error setting %s: %s", override.key, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	flags, err := ParseFlags([]string{"--dev", "--config", "a.env", "--config=b.env", "--set", "SERVICE_PORT=1234", "--set=AUTH_TOKEN_NAME=a=b", "--print-config"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if flags.Mode != RuntimeModeDev || !flags.PrintConfig {
+		t.Errorf("expected dev mode and print config, got: %+v", flags)
+	}
+	if len(flags.ConfigFiles) != 2 || flags.ConfigFiles[0] != "a.env" || flags.ConfigFiles[1] != "b.env" {
+		t.Errorf("expected config files in order, got: %v", flags.ConfigFiles)
+	}
+	if len(flags.overrides) != 2 || flags.overrides[1] != (override{key: "AUTH_TOKEN_NAME", value: "a=b"}) {
+		t.Errorf("expected overrides in order, got: %v", flags.overrides)
+	}
+
+	flags, err = ParseFlags([]string{"--dev", "migrate", "down", "--steps", "2"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if flags.Command != "migrate" || len(flags.CommandArgs) != 3 || flags.CommandArgs[1] != "--steps" {
+		t.Errorf("expected the migrate command with its args, got: %q %v", flags.Command, flags.CommandArgs)
+	}
+
+	for _, invalid := range [][]string{{"--verbose"}, {"--config"}, {"--set", "NOVALUE"}, {"--dev", "--prod"}, {"--dev=true"}} {
+		if _, err := ParseFlags(invalid); err == nil {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+}
+
+func TestApplyPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.env")
+	second := filepath.Join(dir, "second.env")
+	os.WriteFile(first, []byte("LAYER_A=first\nLAYER_B=first\nLAYER_C=first\n"), 0600)
+	os.WriteFile(second, []byte("LAYER_B=second\nLAYER_C=second\n"), 0600)
+	t.Setenv("LAYER_A", "process")
+	t.Setenv("LAYER_B", "process")
+	t.Setenv("LAYER_C", "process")
+	t.Setenv("LAYER_D", "process")
+
+	flags, err := ParseFlags([]string{"--config", first, "--config", second, "--set", "LAYER_C=cli"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := flags.Apply(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	expected := map[string]string{"LAYER_A": "first", "LAYER_B": "second", "LAYER_C": "cli", "LAYER_D": "process"}
+	for key, value := range expected {
+		if actual := os.Getenv(key); actual != value {
+			t.Errorf("expected %s=%s, got %s", key, value, actual)
+		}
+	}
+}
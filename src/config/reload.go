@@ -0,0 +1,29 @@
+package config
+
+// Keys that may change while running, see IsReloadable. Anything else requires a restart.
+var reloadableKeys = map[string]bool{
+	"INTERNAL_AUTH_LEVEL":               true,
+	"SIGNED_TOKEN_KEYS":                 true,
+	"SIGNED_TOKEN_VALID_DURATION_MS":    true,
+	"DEFAULT_DEBUG_HEADER":              true,
+	"MULTIPLAYER_BACKEND_HOST_INTERNAL": true,
+	"MULTIPLAYER_BACKEND_PORT_INTERNAL": true,
+	"MULTIPLAYER_BACKEND_HOST_EXTERNAL": true,
+	"MULTIPLAYER_BACKEND_PORT_EXTERNAL": true,
+	"PLAYER_DB_LOGGING_LEVEL":           true,
+	"COLONY_ASSET_DB_LOGGING_LEVEL":     true,
+	"LANGUAGE_DB_LOGGING_LEVEL":         true,
+}
+
+func IsReloadable(key string) bool {
+	return reloadableKeys[key]
+}
+
+// Applies the sources named by the exec args again and loads the result, see DetectAndApplyENV.
+// Env files only overwrite, so a key removed from a file keeps its previous value until restarted.
+func ReloadFromSources() (*Config, error) {
+	if err := parsedFlags.Apply(); err != nil {
+		return nil, err
+	}
+	return Load()
+}
@@ -0,0 +1,596 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A value that must never be printed or logged. Use Reveal to get the actual value.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+type DatabaseConfig struct {
+	Host     string
+	Port     int
+	Name     string
+	Username string
+	Password Secret
+	// verbose | minimal
+	LoggingLevel string
+	// disable | allow | prefer | require | verify-ca | verify-full
+	SSLMode string
+	// PEM files, all optional
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+	// Connection pool, see database/sql.DB. A ConnMaxLifetime of 0 keeps connections open indefinitely.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// Only read when ENABLE_TLS is true
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// 1.2 | 1.3
+	MinVersion string
+	// none | optional | require. Client certificates are verified against ClientCAFile.
+	ClientAuth   string
+	ClientCAFile string
+	// Generate a self-signed certificate if CertFile is missing, by default only with --dev
+	SelfSigned bool
+	// Hostnames and IPs of the generated certificate, defaults to localhost and the LAN IPs if empty
+	SelfSignedSANs []string
+	// Where to write the generated certificate and key, so it can be trusted in the browser. Not written if empty
+	SelfSignedWriteDir string
+}
+
+// Behind a reverse proxy, the remote address of every request is that of the proxy
+type ProxyConfig struct {
+	// IPs or CIDR ranges of the reverse proxies in front of the service, empty if clients connect directly
+	TrustedProxies []string
+	// Set by the trusted proxies to the IP of the client, X-Real-IP by default. Only set if TrustedProxies is.
+	Header string
+}
+
+// Requests per period, read as <requests>/<seconds>
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (limit RateLimit) String() string {
+	return fmt.Sprintf("%d/%d", limit.Requests, int(limit.Period.Seconds()))
+}
+
+type LockoutConfig struct {
+	// Failures allowed before locking out
+	Threshold int
+	// Duration of the first lockout, doubled with each subsequent lockout up to MaxDuration
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	// Failures older than this are forgotten, and so is the escalation
+	Window time.Duration
+}
+
+// The limits are only read when enabled
+type RateLimitConfig struct {
+	Enabled            bool
+	SessionCreateIP    RateLimit
+	SessionCreateRoute RateLimit
+	ColonyJoinIP       RateLimit
+	ColonyJoinSession  RateLimit
+	ColonyJoinLockout  LockoutConfig
+}
+
+type AuthConfig struct {
+	// strict | naive | signed
+	Level string
+	// Base64, at least 32 bytes
+	SessionTokenHashKey Secret
+	// keyID:base64Key, comma separated. Only required when signed.
+	SignedTokenKeys          Secret
+	SignedTokenValidDuration time.Duration
+	MaxSessionsPerPlayer     int
+	SessionReaperInterval    time.Duration
+	CheckInFlushInterval     time.Duration
+	// Internal endpoints are disabled if empty
+	InternalAPIToken Secret
+}
+
+type MultiplayerConfig struct {
+	InternalHost string
+	InternalPort int
+	ExternalHost string
+	ExternalPort int
+}
+
+func (cfg MultiplayerConfig) InternalAddress() string {
+	return fmt.Sprintf("http://%s:%d", cfg.InternalHost, cfg.InternalPort)
+}
+
+func (cfg MultiplayerConfig) ExternalAddress() string {
+	return fmt.Sprintf("http://%s:%d", cfg.ExternalHost, cfg.ExternalPort)
+}
+
+type VitecConfig struct {
+	// always | periodic | never
+	CrossVerification string
+	// Auth* only set when cross verifying
+	AuthIP                 string
+	AuthPort               int
+	AuthScheme             string
+	AuthTimeout            time.Duration
+	AuthCACert             string
+	AuthClientCert         string
+	AuthClientKey          string
+	AuthInsecureSkipVerify bool
+	ReverificationWindow   time.Duration
+	// The deprovisioning webhook is disabled if empty
+	WebhookSecret    Secret
+	WebhookTolerance time.Duration
+	// Progress reporting is disabled if empty
+	ProgressURL         string
+	ProgressInterval    time.Duration
+	ProgressBackoffBase time.Duration
+	ProgressBackoffMax  time.Duration
+	ProgressMaxAttempts int
+}
+
+// The configuration of the application, loaded and validated once at startup, see Load
+type Config struct {
+	ServicePort   int
+	AuthTokenName string
+	DebugHeader   string
+	EnableTLS     bool
+	TLS           TLSConfig
+	Proxy         ProxyConfig
+	RateLimit     RateLimitConfig
+	// Deadline of each request, including the database and multiplayer backend calls made for it
+	RequestTimeout time.Duration
+	// Deadline by route prefix, the longest prefix matching the request applies instead of RequestTimeout
+	RequestTimeoutOverrides map[string]time.Duration
+	Auth                    AuthConfig
+	Multiplayer             MultiplayerConfig
+	Vitec                   VitecConfig
+	// Seconds
+	DBMaxTimeout int
+	// Delay between connection attempts, doubled after each failed attempt up to DBBackoffMax
+	DBBackoffBase time.Duration
+	DBBackoffMax  time.Duration
+	// How often the connectivity of each database is checked, see database.Monitor
+	DBMonitorInterval time.Duration
+	// Start serving with the databases that could be reached, instead of exiting, if one can't be reached within DBMaxTimeout
+	DBAllowDegradedStart bool
+	PlayerDB             DatabaseConfig
+	ColonyAssetDB        DatabaseConfig
+	LanguageDB           DatabaseConfig
+	// Apply pending schema migrations to every database at startup, see the migrate command
+	DBAutoMigrate bool
+
+	// Effective value of each key read, in order, for Print and Diff
+	entries []configEntry
+}
+
+type configEntry struct {
+	key    string
+	value  string
+	secret bool
+}
+
+// Every problem found while loading, so they can all be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "[config] Invalid configuration:\n - " + strings.Join(e.Problems, "\n - ")
+}
+
+// Reads and validates the configuration from the environment. On validation errors, the config is still returned
+// (with defaults where values were invalid) along with a *ValidationError listing every problem.
+func Load() (*Config, error) {
+	l := &loader{}
+	cfg := &Config{
+		ServicePort:   l.port("SERVICE_PORT", 0),
+		AuthTokenName: l.required("AUTH_TOKEN_NAME"),
+		DebugHeader:   l.required("DEFAULT_DEBUG_HEADER"),
+		EnableTLS:     l.bool("ENABLE_TLS", true),
+	}
+	cfg.Proxy.TrustedProxies = l.list("TRUSTED_PROXIES")
+	for _, proxy := range cfg.Proxy.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			l.problem("TRUSTED_PROXIES entries must be IPs or CIDR ranges, got: %s", proxy)
+		}
+	}
+	if len(cfg.Proxy.TrustedProxies) > 0 {
+		cfg.Proxy.Header = l.string("PROXY_HEADER", "X-Real-IP")
+	}
+	cfg.RateLimit.Enabled = l.bool("RATE_LIMIT_ENABLED", true)
+	if cfg.RateLimit.Enabled {
+		cfg.RateLimit.SessionCreateIP = l.rateLimit("RATE_LIMIT_SESSION_CREATE_IP", RateLimit{Requests: 120, Period: time.Minute})
+		cfg.RateLimit.SessionCreateRoute = l.rateLimit("RATE_LIMIT_SESSION_CREATE_ROUTE", RateLimit{Requests: 600, Period: time.Minute})
+		cfg.RateLimit.ColonyJoinIP = l.rateLimit("RATE_LIMIT_COLONY_JOIN_IP", RateLimit{Requests: 30, Period: time.Minute})
+		cfg.RateLimit.ColonyJoinSession = l.rateLimit("RATE_LIMIT_COLONY_JOIN_SESSION", RateLimit{Requests: 10, Period: time.Minute})
+		cfg.RateLimit.ColonyJoinLockout = LockoutConfig{
+			Threshold:    l.int("LOCKOUT_COLONY_JOIN_THRESHOLD", 5, 1, 1000),
+			BaseDuration: l.seconds("LOCKOUT_COLONY_JOIN_BASE_S", 30, 1, 24*3600),
+			MaxDuration:  l.seconds("LOCKOUT_COLONY_JOIN_MAX_S", 3600, 1, 7*24*3600),
+			Window:       l.seconds("LOCKOUT_COLONY_JOIN_WINDOW_S", 900, 1, 7*24*3600),
+		}
+		if cfg.RateLimit.ColonyJoinLockout.BaseDuration > cfg.RateLimit.ColonyJoinLockout.MaxDuration {
+			l.problem("LOCKOUT_COLONY_JOIN_BASE_S must not be greater than LOCKOUT_COLONY_JOIN_MAX_S")
+		}
+	}
+	cfg.RequestTimeout = l.milliseconds("REQUEST_TIMEOUT_MS", 10000, 100, 300000)
+	cfg.RequestTimeoutOverrides = l.millisecondsByPrefix("REQUEST_TIMEOUT_OVERRIDES", 100, 300000)
+	if cfg.EnableTLS {
+		cfg.TLS = TLSConfig{
+			CertFile:   l.string("TLS_CERT_FILE", "certs/otte_dev_cert.crt"),
+			KeyFile:    l.string("TLS_KEY_FILE", "certs/otte_dev_cert.key"),
+			MinVersion: l.enum("TLS_MIN_VERSION", "1.2", "1.2", "1.3"),
+			ClientAuth: l.enum("TLS_CLIENT_AUTH", "none", "none", "optional", "require"),
+		}
+		if cfg.TLS.ClientAuth != "none" {
+			cfg.TLS.ClientCAFile = l.required("TLS_CLIENT_CA_FILE")
+		}
+		cfg.TLS.SelfSigned = l.bool("TLS_SELF_SIGNED", parsedFlags.Mode == RuntimeModeDev)
+		if cfg.TLS.SelfSigned {
+			cfg.TLS.SelfSignedSANs = l.list("TLS_SELF_SIGNED_SANS")
+			cfg.TLS.SelfSignedWriteDir = l.string("TLS_SELF_SIGNED_WRITE_DIR", "")
+		}
+	}
+
+	cfg.Auth = AuthConfig{
+		Level:                    l.enum("INTERNAL_AUTH_LEVEL", "strict", "strict", "naive", "signed"),
+		SessionTokenHashKey:      l.base64Key("SESSION_TOKEN_HASH_KEY", true),
+		SignedTokenValidDuration: l.milliseconds("SIGNED_TOKEN_VALID_DURATION_MS", 3600000, 1000, 30*24*3600000),
+		MaxSessionsPerPlayer:     l.int("MAX_SESSIONS_PER_PLAYER", 5, 1, 1000),
+		SessionReaperInterval:    l.seconds("SESSION_REAPER_INTERVAL_S", 300, 1, 24*3600),
+		CheckInFlushInterval:     l.seconds("CHECKIN_FLUSH_INTERVAL_S", 10, 1, 3600),
+		InternalAPIToken:         l.secret("INTERNAL_API_TOKEN", false),
+	}
+	if cfg.Auth.Level == "signed" {
+		cfg.Auth.SignedTokenKeys = l.secret("SIGNED_TOKEN_KEYS", true)
+	}
+	//Naive auth accepts any token, so it must never be used in production by accident.
+	//Read regardless of the level, so switching to naive on a reload doesn't count as a change to it.
+	allowNaive := l.bool("ALLOW_NAIVE_AUTH", parsedFlags.Mode != RuntimeModeProd)
+	if cfg.Auth.Level == "naive" && !allowNaive {
+		l.problem("INTERNAL_AUTH_LEVEL naive is refused with --prod, unless ALLOW_NAIVE_AUTH=true")
+	}
+
+	cfg.Multiplayer = MultiplayerConfig{
+		InternalHost: l.required("MULTIPLAYER_BACKEND_HOST_INTERNAL"),
+		InternalPort: l.port("MULTIPLAYER_BACKEND_PORT_INTERNAL", 0),
+		ExternalHost: l.required("MULTIPLAYER_BACKEND_HOST_EXTERNAL"),
+		ExternalPort: l.port("MULTIPLAYER_BACKEND_PORT_EXTERNAL", 0),
+	}
+
+	cfg.Vitec = VitecConfig{
+		CrossVerification: l.enum("VITEC_CROSS_VERIFICATION", "always", "always", "periodic", "never"),
+		WebhookSecret:     l.secret("VITEC_WEBHOOK_SECRET", false),
+		WebhookTolerance:  l.seconds("VITEC_WEBHOOK_TOLERANCE_S", 300, 1, 3600),
+		ProgressURL:       l.string("VITEC_PROGRESS_URL", ""),
+	}
+	if len(cfg.Vitec.WebhookSecret) > 0 && len(cfg.Vitec.WebhookSecret) < 32 {
+		l.problem("VITEC_WEBHOOK_SECRET too short, must be at least 32 characters")
+	}
+	if cfg.Vitec.CrossVerification != "never" || cfg.Vitec.ProgressURL != "" {
+		cfg.Vitec.AuthTimeout = l.milliseconds("VITEC_MV_AUTH_TIMEOUT_MS", 5000, 100, 60000)
+		cfg.Vitec.AuthCACert = l.string("VITEC_MV_AUTH_CA_CERT", "")
+		cfg.Vitec.AuthClientCert = l.string("VITEC_MV_AUTH_CLIENT_CERT", "")
+		cfg.Vitec.AuthClientKey = l.string("VITEC_MV_AUTH_CLIENT_KEY", "")
+		cfg.Vitec.AuthInsecureSkipVerify = l.bool("VITEC_MV_AUTH_INSECURE_SKIP_VERIFY", false)
+		if (cfg.Vitec.AuthClientCert == "") != (cfg.Vitec.AuthClientKey == "") {
+			l.problem("VITEC_MV_AUTH_CLIENT_CERT and VITEC_MV_AUTH_CLIENT_KEY must be set together")
+		}
+	}
+	if cfg.Vitec.CrossVerification != "never" {
+		cfg.Vitec.AuthIP = l.required("VITEC_MV_AUTH_IP")
+		cfg.Vitec.AuthPort = l.port("VITEC_MV_AUTH_PORT", 0)
+		cfg.Vitec.AuthScheme = l.enum("VITEC_MV_AUTH_SCHEME", "https", "https", "http")
+	}
+	if cfg.Vitec.CrossVerification == "periodic" {
+		cfg.Vitec.ReverificationWindow = l.seconds("VITEC_REVERIFICATION_WINDOW_S", 86400, 60, 365*86400)
+	}
+	if cfg.Vitec.ProgressURL != "" {
+		cfg.Vitec.ProgressInterval = l.seconds("VITEC_PROGRESS_INTERVAL_S", 10, 1, 3600)
+		cfg.Vitec.ProgressBackoffBase = l.seconds("VITEC_PROGRESS_BACKOFF_BASE_S", 30, 1, 3600)
+		cfg.Vitec.ProgressBackoffMax = l.seconds("VITEC_PROGRESS_BACKOFF_MAX_S", 3600, 1, 7*86400)
+		cfg.Vitec.ProgressMaxAttempts = l.int("VITEC_PROGRESS_MAX_ATTEMPTS", 10, 1, 1000)
+	}
+
+	cfg.DBMaxTimeout = l.int("DB_MAX_TIMEOUT", 30, 1, 3600)
+	cfg.DBBackoffBase = l.milliseconds("DB_BACKOFF_BASE_MS", 500, 10, 60000)
+	cfg.DBBackoffMax = l.milliseconds("DB_BACKOFF_MAX_MS", 10000, 10, 300000)
+	if cfg.DBBackoffBase > cfg.DBBackoffMax {
+		l.problem("DB_BACKOFF_BASE_MS must not be greater than DB_BACKOFF_MAX_MS")
+	}
+	cfg.DBMonitorInterval = l.seconds("DB_MONITOR_INTERVAL_S", 10, 1, 3600)
+	cfg.DBAllowDegradedStart = l.bool("DB_ALLOW_DEGRADED_START", parsedFlags.Mode != RuntimeModeProd)
+	cfg.PlayerDB = l.database("PLAYER_DB")
+	cfg.ColonyAssetDB = l.database("COLONY_ASSET_DB")
+	cfg.LanguageDB = l.database("LANGUAGE_DB")
+	cfg.DBAutoMigrate = l.bool("DB_AUTO_MIGRATE", parsedFlags.Mode == RuntimeModeDev)
+
+	cfg.entries = l.entries
+	if len(l.problems) > 0 {
+		return cfg, &ValidationError{Problems: l.problems}
+	}
+	return cfg, nil
+}
+
+// Writes the effective value of every key read as KEY=value, with secrets redacted
+func (cfg *Config) Print(w io.Writer) {
+	for _, entry := range cfg.entries {
+		value := entry.value
+		if entry.secret {
+			value = Secret(value).String()
+		}
+		fmt.Fprintf(w, "%s=%s\n", entry.key, value)
+	}
+}
+
+// Keys whose value differs between the two configs, including keys only read by one of them, in the order read
+func (cfg *Config) Diff(other *Config) []string {
+	var values = make(map[string]string, len(other.entries))
+	for _, entry := range other.entries {
+		values[entry.key] = entry.value
+	}
+	var changed []string
+	for _, entry := range cfg.entries {
+		if value, exists := values[entry.key]; !exists || value != entry.value {
+			changed = append(changed, entry.key)
+		}
+		delete(values, entry.key)
+	}
+	for _, entry := range other.entries {
+		if _, remaining := values[entry.key]; remaining {
+			changed = append(changed, entry.key)
+		}
+	}
+	return changed
+}
+
+// Whether --print-config was among the exec args, see DetectAndApplyENV
+func PrintConfigRequested() bool {
+	return parsedFlags.PrintConfig
+}
+
+// The mode given in the exec args, RuntimeModeUnknown if neither --dev nor --prod was
+func RequestedMode() RuntimeMode {
+	return parsedFlags.Mode
+}
+
+// The command given in the exec args and its arguments, empty if the server should be run, see Flags
+func RequestedCommand() (string, []string) {
+	return parsedFlags.Command, parsedFlags.CommandArgs
+}
+
+// Reads keys, recording their effective value and any problems instead of stopping at the first
+type loader struct {
+	problems []string
+	entries  []configEntry
+}
+
+func (l *loader) problem(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) record(key string, value string) {
+	l.entries = append(l.entries, configEntry{key: key, value: value})
+}
+
+// If <key>_FILE is set, the value is read from that file instead, e.g. a Docker or Kubernetes secret mount.
+func (l *loader) lookup(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	path := strings.TrimSpace(os.Getenv(key + FILE_SUFFIX))
+	if path == "" {
+		return value, value != ""
+	}
+	if value != "" {
+		l.problem("%s and %s%s are both set, only one is allowed", key, key, FILE_SUFFIX)
+		return value, true
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		l.problem("unable to read %s%s: %s", key, FILE_SUFFIX, err.Error())
+		return "", false
+	}
+	value = strings.TrimSpace(string(content))
+	return value, value != ""
+}
+
+func (l *loader) string(key string, defaultValue string) string {
+	value, exists := l.lookup(key)
+	if !exists {
+		value = defaultValue
+	}
+	l.record(key, value)
+	return value
+}
+
+func (l *loader) required(key string) string {
+	value, exists := l.lookup(key)
+	if !exists {
+		l.problem("%s is required", key)
+	}
+	l.record(key, value)
+	return value
+}
+
+func (l *loader) secret(key string, required bool) Secret {
+	value, exists := l.lookup(key)
+	if !exists && required {
+		l.problem("%s is required", key)
+	}
+	l.entries = append(l.entries, configEntry{key: key, value: value, secret: true})
+	return Secret(value)
+}
+
+// A base64 encoded key of at least 32 bytes
+func (l *loader) base64Key(key string, required bool) Secret {
+	secret := l.secret(key, required)
+	if secret == "" {
+		return secret
+	}
+	decoded, err := base64.StdEncoding.DecodeString(secret.Reveal())
+	if err != nil {
+		l.problem("%s is not valid base64", key)
+	} else if len(decoded) < 32 {
+		l.problem("%s too short, must be at least 32 bytes", key)
+	}
+	return secret
+}
+
+func (l *loader) enum(key string, defaultValue string, allowed ...string) string {
+	value, exists := l.lookup(key)
+	if !exists {
+		value = defaultValue
+	}
+	for _, option := range allowed {
+		if value == option {
+			l.record(key, value)
+			return value
+		}
+	}
+	l.problem("%s must be one of %s, got: %s", key, strings.Join(allowed, " | "), value)
+	l.record(key, defaultValue)
+	return defaultValue
+}
+
+func (l *loader) bool(key string, defaultValue bool) bool {
+	value := l.enum(key, strconv.FormatBool(defaultValue), "true", "false")
+	return value == "true"
+}
+
+// Comma separated, empty entries are dropped
+func (l *loader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(l.string(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// A default of 0 makes the key required
+func (l *loader) int(key string, defaultValue int, min int, max int) int {
+	value, exists := l.lookup(key)
+	if !exists {
+		if defaultValue == 0 {
+			l.problem("%s is required", key)
+		}
+		l.record(key, strconv.Itoa(defaultValue))
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.problem("%s must be a whole number, got: %s", key, value)
+		parsed = defaultValue
+	} else if parsed < min || parsed > max {
+		l.problem("%s must be between %d and %d, got: %d", key, min, max, parsed)
+		parsed = defaultValue
+	}
+	l.record(key, strconv.Itoa(parsed))
+	return parsed
+}
+
+// A default of 0 makes the key required
+func (l *loader) port(key string, defaultValue int) int {
+	return l.int(key, defaultValue, 1, 65535)
+}
+
+func (l *loader) seconds(key string, defaultValue int, min int, max int) time.Duration {
+	return time.Duration(l.int(key, defaultValue, min, max)) * time.Second
+}
+
+func (l *loader) milliseconds(key string, defaultValue int, min int, max int) time.Duration {
+	return time.Duration(l.int(key, defaultValue, min, max)) * time.Millisecond
+}
+
+// Comma separated prefix=milliseconds pairs, e.g. /api/v1/location=30000,/api/v1/health=2000
+func (l *loader) millisecondsByPrefix(key string, min int, max int) map[string]time.Duration {
+	var values = map[string]time.Duration{}
+	for _, pair := range l.list(key) {
+		prefix, value, found := strings.Cut(pair, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !found || prefix == "" {
+			l.problem("%s entries must be formatted as prefix=milliseconds, got: %s", key, pair)
+			continue
+		}
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || parsed < min || parsed > max {
+			l.problem("%s: %s must be a whole number between %d and %d, got: %s", key, prefix, min, max, value)
+			continue
+		}
+		values[prefix] = time.Duration(parsed) * time.Millisecond
+	}
+	return values
+}
+
+// <requests>/<seconds>, e.g. 10/60 for 10 requests per minute
+func (l *loader) rateLimit(key string, defaultValue RateLimit) RateLimit {
+	value, exists := l.lookup(key)
+	if !exists {
+		l.record(key, defaultValue.String())
+		return defaultValue
+	}
+	requestsStr, secondsStr, found := strings.Cut(value, "/")
+	requests, requestsErr := strconv.Atoi(strings.TrimSpace(requestsStr))
+	seconds, secondsErr := strconv.Atoi(strings.TrimSpace(secondsStr))
+	if !found || requestsErr != nil || secondsErr != nil || requests < 1 || seconds < 1 {
+		l.problem("%s must be formatted as <requests>/<seconds> with both positive, got: %s", key, value)
+		l.record(key, defaultValue.String())
+		return defaultValue
+	}
+	limit := RateLimit{Requests: requests, Period: time.Duration(seconds) * time.Second}
+	l.record(key, limit.String())
+	return limit
+}
+
+// Reads <prefix>_HOST, _PORT, _NAME, _USERNAME, _PASSWORD, _LOGGING_LEVEL and _SSL_MODE, _SSL_ROOT_CERT, _SSL_CERT, _SSL_KEY
+// and the pool sizes _MAX_OPEN_CONNS, _MAX_IDLE_CONNS, _CONN_MAX_LIFETIME_S
+func (l *loader) database(prefix string) DatabaseConfig {
+	cfg := DatabaseConfig{
+		Host:         l.required(prefix + "_HOST"),
+		Port:         l.port(prefix+"_PORT", 0),
+		Name:         l.required(prefix + "_NAME"),
+		Username:     l.required(prefix + "_USERNAME"),
+		Password:     l.secret(prefix+"_PASSWORD", true),
+		LoggingLevel: l.enum(prefix+"_LOGGING_LEVEL", "verbose", "verbose", "minimal"),
+		SSLMode:      l.enum(prefix+"_SSL_MODE", "disable", "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+		SSLRootCert:  l.string(prefix+"_SSL_ROOT_CERT", ""),
+		SSLCert:      l.string(prefix+"_SSL_CERT", ""),
+		SSLKey:       l.string(prefix+"_SSL_KEY", ""),
+	}
+	if (cfg.SSLCert == "") != (cfg.SSLKey == "") {
+		l.problem("%s_SSL_CERT and %s_SSL_KEY must be set together", prefix, prefix)
+	}
+	cfg.MaxOpenConns = l.int(prefix+"_MAX_OPEN_CONNS", 20, 1, 1000)
+	cfg.MaxIdleConns = l.int(prefix+"_MAX_IDLE_CONNS", 5, 0, 1000)
+	cfg.ConnMaxLifetime = l.seconds(prefix+"_CONN_MAX_LIFETIME_S", 1800, 0, 24*3600)
+	if cfg.MaxIdleConns > cfg.MaxOpenConns {
+		l.problem("%s_MAX_IDLE_CONNS must not be greater than %s_MAX_OPEN_CONNS", prefix, prefix)
+	}
+	return cfg
+}
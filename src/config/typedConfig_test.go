@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setValidEnv(t *testing.T) {
+	t.Setenv("SERVICE_PORT", "8080")
+	t.Setenv("AUTH_TOKEN_NAME", "URSA-Token")
+	t.Setenv("DEFAULT_DEBUG_HEADER", "URSA-DDH")
+	t.Setenv("SESSION_TOKEN_HASH_KEY", "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=")
+	t.Setenv("MULTIPLAYER_BACKEND_HOST_INTERNAL", "localhost")
+	t.Setenv("MULTIPLAYER_BACKEND_PORT_INTERNAL", "5000")
+	t.Setenv("MULTIPLAYER_BACKEND_HOST_EXTERNAL", "localhost")
+	t.Setenv("MULTIPLAYER_BACKEND_PORT_EXTERNAL", "5001")
+	t.Setenv("VITEC_CROSS_VERIFICATION", "never")
+	for _, prefix := range []string{"PLAYER_DB", "COLONY_ASSET_DB", "LANGUAGE_DB"} {
+		t.Setenv(prefix+"_HOST", "localhost")
+		t.Setenv(prefix+"_PORT", "5432")
+		t.Setenv(prefix+"_NAME", strings.ToLower(prefix))
+		t.Setenv(prefix+"_USERNAME", "otte")
+		t.Setenv(prefix+"_PASSWORD", "hunter2")
+	}
+}
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	setValidEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if cfg.Auth.Level != "strict" || !cfg.EnableTLS || cfg.PlayerDB.LoggingLevel != "verbose" {
+		t.Errorf("expected defaults to be applied, got: %+v", cfg)
+	}
+	if cfg.Auth.SessionReaperInterval != 5*time.Minute {
+		t.Errorf("expected default reaper interval of 5m, got %s", cfg.Auth.SessionReaperInterval)
+	}
+	if cfg.PlayerDB.Password.Reveal() != "hunter2" {
+		t.Error("expected password to be revealable")
+	}
+	if cfg.LanguageDB.MaxOpenConns != 20 || cfg.LanguageDB.MaxIdleConns != 5 || cfg.LanguageDB.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("expected default pool sizes, got: %+v", cfg.LanguageDB)
+	}
+	if cfg.RequestTimeout != 10*time.Second || len(cfg.RequestTimeoutOverrides) != 0 {
+		t.Errorf("expected a default request timeout of 10s without overrides, got %s and %v", cfg.RequestTimeout, cfg.RequestTimeoutOverrides)
+	}
+}
+
+func TestLoadRequestTimeoutOverrides(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("REQUEST_TIMEOUT_OVERRIDES", "/api/v1/location=30000, /api/v1/health = 2000")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if cfg.RequestTimeoutOverrides["/api/v1/location"] != 30*time.Second || cfg.RequestTimeoutOverrides["/api/v1/health"] != 2*time.Second {
+		t.Errorf("unexpected overrides: %v", cfg.RequestTimeoutOverrides)
+	}
+
+	t.Setenv("REQUEST_TIMEOUT_OVERRIDES", "/api/v1/location,/api/v1/health=forever")
+	if _, err := Load(); err == nil || strings.Count(err.Error(), "REQUEST_TIMEOUT_OVERRIDES") != 2 {
+		t.Errorf("expected both malformed overrides to be reported, got: %v", err)
+	}
+}
+
+func TestLoadRateLimits(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("RATE_LIMIT_SESSION_CREATE_IP", " 300 / 60 ")
+	t.Setenv("LOCKOUT_COLONY_JOIN_THRESHOLD", "3")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if cfg.RateLimit.SessionCreateIP != (RateLimit{Requests: 300, Period: time.Minute}) || cfg.RateLimit.ColonyJoinIP != (RateLimit{Requests: 30, Period: time.Minute}) {
+		t.Errorf("unexpected rate limits: %+v", cfg.RateLimit)
+	}
+	if cfg.RateLimit.ColonyJoinLockout.Threshold != 3 || cfg.RateLimit.ColonyJoinLockout.Window != 15*time.Minute {
+		t.Errorf("unexpected lockout: %+v", cfg.RateLimit.ColonyJoinLockout)
+	}
+	var printed bytes.Buffer
+	cfg.Print(&printed)
+	if !strings.Contains(printed.String(), "RATE_LIMIT_SESSION_CREATE_IP=300/60\n") || !strings.Contains(printed.String(), "LOCKOUT_COLONY_JOIN_MAX_S=3600\n") {
+		t.Errorf("expected the limits to be printed, got:\n%s", printed.String())
+	}
+
+	for _, invalid := range []string{"10", "0/60", "10/0", "a/b"} {
+		t.Setenv("RATE_LIMIT_COLONY_JOIN_SESSION", invalid)
+		t.Setenv("LOCKOUT_COLONY_JOIN_BASE_S", "soon")
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_COLONY_JOIN_SESSION") || !strings.Contains(err.Error(), "LOCKOUT_COLONY_JOIN_BASE_S") {
+			t.Errorf("expected %s and the malformed lockout to be reported, got: %v", invalid, err)
+		}
+	}
+
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	if cfg, err := Load(); err != nil || cfg.RateLimit.Enabled {
+		t.Errorf("expected the malformed limits to be ignored when disabled, got: %v", err)
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("SERVICE_PORT", "70000")
+	t.Setenv("INTERNAL_AUTH_LEVEL", "paranoid")
+	t.Setenv("PLAYER_DB_LOGGING_LEVEL", "loud")
+	t.Setenv("VITEC_CROSS_VERIFICATION", "sometimes")
+	t.Setenv("MAX_SESSIONS_PER_PLAYER", "many")
+	t.Setenv("LANGUAGE_DB_HOST", "")
+	t.Setenv("COLONY_ASSET_DB_MAX_OPEN_CONNS", "4")
+	t.Setenv("COLONY_ASSET_DB_MAX_IDLE_CONNS", "8")
+	t.Setenv("DB_BACKOFF_BASE_MS", "20000")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.1,proxy.local")
+
+	_, err := Load()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatal("expected a ValidationError, got:", err)
+	}
+	for _, key := range []string{"SERVICE_PORT", "INTERNAL_AUTH_LEVEL", "PLAYER_DB_LOGGING_LEVEL", "VITEC_CROSS_VERIFICATION", "MAX_SESSIONS_PER_PLAYER", "LANGUAGE_DB_HOST", "COLONY_ASSET_DB_MAX_IDLE_CONNS", "DB_BACKOFF_BASE_MS", "TRUSTED_PROXIES"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected problem with %s to be reported, got: %s", key, err.Error())
+		}
+	}
+}
+
+func TestLoadRefusesNaiveAuthInProd(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("INTERNAL_AUTH_LEVEL", "naive")
+	previous := parsedFlags
+	parsedFlags = &Flags{Mode: RuntimeModeProd}
+	defer func() { parsedFlags = previous }()
+
+	_, err := Load()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), "INTERNAL_AUTH_LEVEL") {
+		t.Fatal("expected naive auth to be refused in prod, got:", err)
+	}
+
+	t.Setenv("ALLOW_NAIVE_AUTH", "true")
+	if _, err := Load(); err != nil {
+		t.Error("expected naive auth to be allowed explicitly, got:", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	setValidEnv(t)
+	t.Setenv("INTERNAL_API_TOKEN", "very-secret-token")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	var out bytes.Buffer
+	cfg.Print(&out)
+	printed := out.String()
+	for _, secret := range []string{"hunter2", "very-secret-token", "MDEyMzQ1"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("expected %s to be redacted, got:\n%s", secret, printed)
+		}
+	}
+	if !strings.Contains(printed, "PLAYER_DB_PASSWORD=********\n") || !strings.Contains(printed, "SERVICE_PORT=8080\n") {
+		t.Errorf("expected every key to be printed, got:\n%s", printed)
+	}
+}
+
+func TestLoadReadsFileIndirection(t *testing.T) {
+	setValidEnv(t)
+	path := filepath.Join(t.TempDir(), "player_db_password")
+	os.WriteFile(path, []byte("from-secret-mount\n"), 0600)
+	t.Setenv("PLAYER_DB_PASSWORD", "")
+	t.Setenv("PLAYER_DB_PASSWORD_FILE", path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if cfg.PlayerDB.Password.Reveal() != "from-secret-mount" {
+		t.Errorf("expected password to be read from file, got: %s", cfg.PlayerDB.Password.Reveal())
+	}
+
+	t.Setenv("PLAYER_DB_PASSWORD", "hunter2")
+	if _, err := Load(); err == nil {
+		t.Error("expected setting both PLAYER_DB_PASSWORD and PLAYER_DB_PASSWORD_FILE to be rejected")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	setValidEnv(t)
+	current, _ := Load()
+	t.Setenv("PLAYER_DB_PASSWORD", "hunter3")
+	t.Setenv("INTERNAL_AUTH_LEVEL", "signed")
+	t.Setenv("SIGNED_TOKEN_KEYS", "key1:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=")
+	next, _ := Load()
+
+	changed := current.Diff(next)
+	expected := []string{"INTERNAL_AUTH_LEVEL", "PLAYER_DB_PASSWORD", "SIGNED_TOKEN_KEYS"}
+	if strings.Join(changed, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected changed keys %v, got %v", expected, changed)
+	}
+}
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Exponential backoff with jitter, used between connection attempts
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Delay after the given failed attempt (starting at 1): Base doubled per attempt, capped at Max.
+// Up to half of the delay is randomized, so instances restarted together don't retry in lockstep.
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay < b.Max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.Max)
+	return delay - time.Duration(rand.Int64N(int64(delay)/2+1))
+}
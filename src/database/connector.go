@@ -1,148 +1,121 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"otte_main_backend/src/config"
-	"strconv"
 	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
-func ConnectPlayerDB() (PlayerDB, error) {
-	port, portErr := strconv.ParseUint(config.Get("PLAYER_DB_PORT"), 10, 32)
-	timeout, timeoutErr := strconv.Atoi(config.Get("DB_MAX_TIMEOUT"))
-	loudness, envErr := getLoggingLoudness("PLAYER_DB_LOGGING_LEVEL")
-	if envErr != nil {
-		return nil, envErr
-	}
-	if portErr != nil {
-		log.Println("Error parsing player db port value from environment")
-	}
-	if timeoutErr != nil {
-		log.Println("Error parsing db connection timeout value from environment")
-	}
+// Returned, wrapped, when a database could not be reached within ConnectOptions.Timeout.
+// The *gorm.DB returned along with it is still usable: queries fail until the database comes up, see Monitor.
+var ErrUnavailable = errors.New("database unavailable")
 
-	// Connection URL to connect to Postgres Database
-	dsn := DBDSN{
-		Host:     config.Get("PLAYER_DB_HOST"),
-		Port:     port,
-		Username: config.Get("PLAYER_DB_USERNAME"),
-		Password: config.Get("PLAYER_DB_PASSWORD"),
-		Database: config.Get("PLAYER_DB_NAME"),
-		SSLMode:  "disable",
-	}
+// Upper bound of a single connection attempt
+const PING_TIMEOUT = 5 * time.Second
 
-	return attemptConnectionWithinTimeout(timeout, dsn, loudness)
+type ConnectOptions struct {
+	// Total time spent on attempts, after which ErrUnavailable is returned
+	Timeout time.Duration
+	Backoff Backoff
 }
 
-func ConnectLanguageDB() (LanguageDB, error) {
-	port, portErr := strconv.ParseUint(config.Get("LANGUAGE_DB_PORT"), 10, 32)
-	timeout, timeoutErr := strconv.Atoi(config.Get("DB_MAX_TIMEOUT"))
-	loudness, envErr := getLoggingLoudness("LANGUAGE_DB_LOGGING_LEVEL")
-	if envErr != nil {
-		return nil, envErr
-	}
-
-	if portErr != nil {
-		log.Println("Error parsing language db port value from environment")
-	}
-	if timeoutErr != nil {
-		log.Println("Error parsing db connection timeout value from environment")
-	}
-	dsn := DBDSN{
-		Host:     config.Get("LANGUAGE_DB_HOST"),
-		Port:     port,
-		Username: config.Get("LANGUAGE_DB_USERNAME"),
-		Password: config.Get("LANGUAGE_DB_PASSWORD"),
-		Database: config.Get("LANGUAGE_DB_NAME"),
-		SSLMode:  "disable",
-	}
+func ConnectPlayerDB(cfg config.DatabaseConfig, options ConnectOptions) (PlayerDB, error) {
+	return connect(cfg, options)
+}
 
-	return attemptConnectionWithinTimeout(timeout, dsn, loudness)
+func ConnectLanguageDB(cfg config.DatabaseConfig, options ConnectOptions) (LanguageDB, error) {
+	return connect(cfg, options)
 }
 
-func ConnectColonyAssetDB() (LanguageDB, error) {
-	port, portErr := strconv.ParseUint(config.Get("COLONY_ASSET_DB_PORT"), 10, 32)
-	timeout, timeoutErr := strconv.Atoi(config.Get("DB_MAX_TIMEOUT"))
-	loudness, envErr := getLoggingLoudness("COLONY_ASSET_DB_LOGGING_LEVEL")
-	if envErr != nil {
-		return nil, envErr
-	}
+func ConnectColonyAssetDB(cfg config.DatabaseConfig, options ConnectOptions) (ColonyAssetDB, error) {
+	return connect(cfg, options)
+}
 
-	if portErr != nil {
-		log.Println("Error parsing colony and asset db port value from environment")
-	}
-	if timeoutErr != nil {
-		log.Println("Error parsing db connection timeout value from environment")
-	}
+func connect(cfg config.DatabaseConfig, options ConnectOptions) (*gorm.DB, error) {
+	// Connection URL to connect to Postgres Database
 	dsn := DBDSN{
-		Host:     config.Get("COLONY_ASSET_DB_HOST"),
-		Port:     port,
-		Username: config.Get("COLONY_ASSET_DB_USERNAME"),
-		Password: config.Get("COLONY_ASSET_DB_PASSWORD"),
-		Database: config.Get("COLONY_ASSET_DB_NAME"),
-		SSLMode:  "disable",
-	}
+		Host:     cfg.Host,
+		Port:     uint64(cfg.Port),
+		Username: cfg.Username,
+		Password: cfg.Password.Reveal(),
+		Database: cfg.Name,
+		SSLMode:  cfg.SSLMode,
 
-	return attemptConnectionWithinTimeout(timeout, dsn, loudness)
-}
+		SSLRootCert: cfg.SSLRootCert,
+		SSLCert:     cfg.SSLCert,
+		SSLKey:      cfg.SSLKey,
+	}
 
-func getLoggingLoudness(envKey string) (DBLoggingLoudness, error) {
-	loudnessStr := config.Get(envKey)
-	var loudness DBLoggingLoudness
-	switch loudnessStr {
-	case "verbose":
-		loudness = DBLoggingVerbose
-	case "minimal":
-		loudness = DBLoggingMinimal
-	default:
-		return loudness, fmt.Errorf("invalid logging level for colony asset db: %s", loudnessStr)
+	db, err := open(dsn, cfg, DBLoggingLoudness(cfg.LoggingLevel))
+	if err != nil {
+		return nil, err
 	}
-	return loudness, nil
+	return db, attemptConnectionWithinTimeout(db, dsn, options)
 }
 
-func attemptConnectionWithinTimeout(timeout int, dsn DBDSN, loggingLoudness DBLoggingLoudness) (*gorm.DB, error) {
-	log.Printf("[database] Trying to establish connection to %s within: %d seconds. \n", dsn.Database, timeout)
+// Opens the pool without connecting, so a database that is down at startup doesn't prevent using it once it's up
+func open(dsn DBDSN, cfg config.DatabaseConfig, loggingLoudness DBLoggingLoudness) (*gorm.DB, error) {
 	log.Println("[database] Using dsn: " + dsn.SafeString())
 	log.Println("[database] Logging level: " + string(loggingLoudness))
 
-	var err error
-	var db *gorm.DB
-
 	var gormConfig = &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
+		DisableAutomaticPing:                     true,
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true,
 		},
-	}
-	if loggingLoudness == DBLoggingMinimal {
-		gormConfig.Logger = logger.Default.LogMode(logger.Silent)
-	}
-
-	for attemptNum := 1; attemptNum < timeout; attemptNum++ {
-
-		var expectedError error
-
-		log.Printf("[database] %s Attempt %d/%d\n", dsn.Database, attemptNum, timeout)
+		Logger: newSwitchableLogger(loggingLoudness),
+		//Driver errors like unique violations become gorm errors, e.g. gorm.ErrDuplicatedKey, so they can be told apart
+		TranslateError: true,
+	}
+	db, err := gorm.Open(postgres.Open(dsn.FullString()), gormConfig)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	log.Printf("[database] %s pool: max open: %d, max idle: %d, max lifetime: %s\n", dsn.Database, cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime)
+	return db, nil
+}
 
-		db, expectedError = gorm.Open(postgres.Open(dsn.FullString()), gormConfig)
+// Attempts until the database responds or the timeout is reached, the last attempt being made at the timeout
+func attemptConnectionWithinTimeout(db *gorm.DB, dsn DBDSN, options ConnectOptions) error {
+	log.Printf("[database] Trying to establish connection to %s within: %s\n", dsn.Database, options.Timeout)
 
-		if expectedError == nil {
-			break
+	deadline := time.Now().Add(options.Timeout)
+	var err error
+	for attemptNum := 1; ; attemptNum++ {
+		log.Printf("[database] %s Attempt %d\n", dsn.Database, attemptNum)
+		if err = ping(db); err == nil {
+			return nil
 		}
-
-		//On last try, return the error
-		if attemptNum == timeout-1 {
-			err = expectedError
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
 		}
-
-		time.Sleep(2 * time.Second)
+		time.Sleep(min(options.Backoff.Delay(attemptNum), remaining))
 	}
+	return fmt.Errorf("%w: %s could not be reached within %s: %s", ErrUnavailable, dsn.Database, options.Timeout, err.Error())
+}
 
-	return db, err
+func ping(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), PING_TIMEOUT)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
 }
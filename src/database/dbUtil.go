@@ -24,13 +24,28 @@ type DBDSN struct {
 	Password string
 	Database string
 	SSLMode  string
+	// Optional paths
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
 }
 
 func (dsn DBDSN) FullString() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		dsn.Host, dsn.Port, dsn.Username, dsn.Password, dsn.Database, dsn.SSLMode)
+		dsn.Host, dsn.Port, dsn.Username, dsn.Password, dsn.Database, dsn.SSLMode) + dsn.sslFiles()
 }
 func (dsn DBDSN) SafeString() string {
 	return fmt.Sprintf("host=%s port=%d user=******** password=******** dbname=%s sslmode=%s",
-		dsn.Host, dsn.Port, dsn.Database, dsn.SSLMode)
+		dsn.Host, dsn.Port, dsn.Database, dsn.SSLMode) + dsn.sslFiles()
+}
+
+func (dsn DBDSN) sslFiles() string {
+	var files = ""
+	if dsn.SSLRootCert != "" {
+		files += " sslrootcert=" + dsn.SSLRootCert
+	}
+	if dsn.SSLCert != "" {
+		files += " sslcert=" + dsn.SSLCert + " sslkey=" + dsn.SSLKey
+	}
+	return files
 }
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Logger whose loudness can be switched while the DB is in use, see SetLoggingLoudness
+type switchableLogger struct {
+	current atomic.Pointer[logger.Interface]
+}
+
+func newSwitchableLogger(loudness DBLoggingLoudness) *switchableLogger {
+	l := &switchableLogger{}
+	l.set(loudness)
+	return l
+}
+
+func (l *switchableLogger) set(loudness DBLoggingLoudness) {
+	var next = logger.Default
+	if loudness == DBLoggingMinimal {
+		next = logger.Default.LogMode(logger.Silent)
+	}
+	l.current.Store(&next)
+}
+
+func (l *switchableLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return (*l.current.Load()).LogMode(level)
+}
+
+func (l *switchableLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	(*l.current.Load()).Info(ctx, msg, data...)
+}
+
+func (l *switchableLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	(*l.current.Load()).Warn(ctx, msg, data...)
+}
+
+func (l *switchableLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	(*l.current.Load()).Error(ctx, msg, data...)
+}
+
+func (l *switchableLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	(*l.current.Load()).Trace(ctx, begin, fc, err)
+}
+
+// Switches the logging loudness of a DB connected through this package
+func SetLoggingLoudness(db *gorm.DB, loudness DBLoggingLoudness) error {
+	switchable, ok := db.Config.Logger.(*switchableLogger)
+	if !ok {
+		return fmt.Errorf("logging loudness of this DB can not be switched")
+	}
+	switchable.set(loudness)
+	return nil
+}
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS "ColonyAsset";
+DROP TABLE IF EXISTS "ColonyLocationPath";
+DROP TABLE IF EXISTS "ColonyLocation";
+DROP TABLE IF EXISTS "ColonyCode";
+DROP TABLE IF EXISTS "Colony";
+DROP TABLE IF EXISTS "LocationAppearance";
+DROP TABLE IF EXISTS "Location";
+DROP TABLE IF EXISTS "MiniGameDifficulty";
+DROP TABLE IF EXISTS "MiniGame";
+DROP TABLE IF EXISTS "CollectionEntry";
+DROP TABLE IF EXISTS "AssetCollection";
+DROP TABLE IF EXISTS "LOD";
+DROP TABLE IF EXISTS "GraphicalAsset";
+DROP TABLE IF EXISTS "Transform";
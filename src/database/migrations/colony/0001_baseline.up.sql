@@ -0,0 +1,122 @@
+-- Tables as they existed before migrations were tracked. IF NOT EXISTS, so existing databases are adopted as is.
+-- Players live in the player database, so owner columns can't reference them.
+
+CREATE TABLE IF NOT EXISTS "Transform" (
+    id        SERIAL PRIMARY KEY,
+    "xScale"  REAL NOT NULL DEFAULT 1,
+    "yScale"  REAL NOT NULL DEFAULT 1,
+    "xOffset" REAL NOT NULL DEFAULT 0,
+    "yOffset" REAL NOT NULL DEFAULT 0,
+    "zIndex"  INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "GraphicalAsset" (
+    id        SERIAL PRIMARY KEY,
+    alias     TEXT NOT NULL,
+    type      TEXT NOT NULL,
+    "useCase" TEXT NOT NULL DEFAULT '',
+    width     INTEGER NOT NULL,
+    height    INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "LOD" (
+    id               SERIAL PRIMARY KEY,
+    "detailLevel"    INTEGER NOT NULL,
+    "graphicalAsset" INTEGER NOT NULL REFERENCES "GraphicalAsset" (id) ON DELETE CASCADE,
+    blob             BYTEA NOT NULL,
+    etag             TEXT NOT NULL DEFAULT '',
+    type             TEXT NOT NULL,
+    UNIQUE ("graphicalAsset", "detailLevel")
+);
+
+CREATE TABLE IF NOT EXISTS "AssetCollection" (
+    id        SERIAL PRIMARY KEY,
+    name      TEXT NOT NULL,
+    "useCase" TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS "CollectionEntry" (
+    id                SERIAL PRIMARY KEY,
+    "assetCollection" INTEGER NOT NULL REFERENCES "AssetCollection" (id) ON DELETE CASCADE,
+    "graphicalAsset"  INTEGER NOT NULL REFERENCES "GraphicalAsset" (id),
+    transform         INTEGER NOT NULL REFERENCES "Transform" (id)
+);
+
+CREATE TABLE IF NOT EXISTS "MiniGame" (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    icon        INTEGER NOT NULL REFERENCES "GraphicalAsset" (id),
+    settings    JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS "MiniGameDifficulty" (
+    id                    SERIAL PRIMARY KEY,
+    name                  TEXT NOT NULL,
+    description           TEXT NOT NULL DEFAULT '',
+    icon                  INTEGER NOT NULL REFERENCES "GraphicalAsset" (id),
+    minigame              INTEGER NOT NULL REFERENCES "MiniGame" (id) ON DELETE CASCADE,
+    "requiredLevel"       INTEGER NOT NULL DEFAULT 0,
+    "overwritingSettings" JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS "Location" (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    minigame    INTEGER REFERENCES "MiniGame" (id)
+);
+
+CREATE TABLE IF NOT EXISTS "LocationAppearance" (
+    id                SERIAL PRIMARY KEY,
+    level             INTEGER NOT NULL,
+    location          INTEGER NOT NULL REFERENCES "Location" (id) ON DELETE CASCADE,
+    "splashArt"       INTEGER NOT NULL REFERENCES "GraphicalAsset" (id),
+    "assetCollection" INTEGER NOT NULL REFERENCES "AssetCollection" (id),
+    UNIQUE (location, level)
+);
+
+CREATE TABLE IF NOT EXISTS "Colony" (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT NOT NULL DEFAULT '',
+    "accLevel"    INTEGER NOT NULL DEFAULT 0,
+    "latestVisit" TEXT NOT NULL DEFAULT '',
+    owner         INTEGER NOT NULL,
+    -- References "ColonyCode", left without a constraint as the two reference each other
+    "colonyCode"  INTEGER,
+    assets        INTEGER[] NOT NULL DEFAULT '{}',
+    locations     INTEGER[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS "ColonyCode" (
+    id                SERIAL PRIMARY KEY,
+    "lobbyId"         INTEGER NOT NULL,
+    "serverAddress"   TEXT NOT NULL,
+    colony            INTEGER NOT NULL REFERENCES "Colony" (id) ON DELETE CASCADE,
+    value             TEXT NOT NULL UNIQUE,
+    owner             INTEGER NOT NULL,
+    "createdAt"       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "validDurationMS" INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "ColonyLocation" (
+    id        SERIAL PRIMARY KEY,
+    colony    INTEGER NOT NULL REFERENCES "Colony" (id) ON DELETE CASCADE,
+    location  INTEGER NOT NULL REFERENCES "Location" (id),
+    transform INTEGER NOT NULL REFERENCES "Transform" (id),
+    level     INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS "ColonyLocationPath" (
+    id          SERIAL PRIMARY KEY,
+    colony      INTEGER NOT NULL REFERENCES "Colony" (id) ON DELETE CASCADE,
+    "locationA" INTEGER NOT NULL REFERENCES "ColonyLocation" (id) ON DELETE CASCADE,
+    "locationB" INTEGER NOT NULL REFERENCES "ColonyLocation" (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "ColonyAsset" (
+    id                SERIAL PRIMARY KEY,
+    "assetCollection" INTEGER NOT NULL REFERENCES "AssetCollection" (id),
+    transform         INTEGER NOT NULL REFERENCES "Transform" (id),
+    colony            INTEGER NOT NULL REFERENCES "Colony" (id) ON DELETE CASCADE
+);
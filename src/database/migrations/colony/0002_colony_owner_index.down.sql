@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "Colony_owner_idx";
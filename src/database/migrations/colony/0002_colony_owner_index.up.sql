@@ -0,0 +1,2 @@
+-- Ownership policies and deprovisioning look up colonies by owner
+CREATE INDEX IF NOT EXISTS "Colony_owner_idx" ON "Colony" (owner);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "LocationAppearance_location_level_idx";
+DROP INDEX IF EXISTS "LOD_graphicalAsset_detailLevel_idx";
@@ -0,0 +1,4 @@
+-- Seeding upserts LODs and location appearances on these columns. The baseline declares them unique, but databases
+-- which already had the tables when it was adopted may lack the constraints
+CREATE UNIQUE INDEX IF NOT EXISTS "LOD_graphicalAsset_detailLevel_idx" ON "LOD" ("graphicalAsset", "detailLevel");
+CREATE UNIQUE INDEX IF NOT EXISTS "LocationAppearance_location_level_idx" ON "LocationAppearance" (location, level);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "Catalogue";
+DROP TABLE IF EXISTS "AvailableLanguages";
@@ -0,0 +1,17 @@
+-- Tables as they existed before migrations were tracked. IF NOT EXISTS, so existing databases are adopted as is.
+
+CREATE TABLE IF NOT EXISTS "AvailableLanguages" (
+    id           SERIAL PRIMARY KEY,
+    code         TEXT NOT NULL UNIQUE,
+    "commonName" TEXT NOT NULL,
+    coverage     REAL NOT NULL DEFAULT 0,
+    icon         INTEGER NOT NULL DEFAULT 0
+);
+
+-- One column per language, named by its code in "AvailableLanguages". New languages are added as columns by a migration.
+CREATE TABLE IF NOT EXISTS "Catalogue" (
+    key  TEXT PRIMARY KEY,
+    "EN" TEXT,
+    "DK" TEXT,
+    "NO" TEXT
+);
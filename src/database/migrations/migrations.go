@@ -0,0 +1,256 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are plain SQL files named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// in a directory per database. Versions start at 1 and must be consecutive.
+// A migration is never edited once released, changes go in a new migration.
+//
+//go:embed player/*.sql colony/*.sql language/*.sql
+var migrationFiles embed.FS
+
+type Database string
+
+const (
+	DatabasePlayer   Database = "player"
+	DatabaseColony   Database = "colony"
+	DatabaseLanguage Database = "language"
+)
+
+var Databases = []Database{DatabasePlayer, DatabaseColony, DatabaseLanguage}
+
+// Table in each database recording which migrations have been applied
+const TRACKING_TABLE = "SchemaMigration"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type AppliedMigration struct {
+	Version   int       `gorm:"column:version;primaryKey"`
+	Name      string    `gorm:"column:name"`
+	AppliedAt time.Time `gorm:"column:appliedAt"`
+}
+
+func (m *AppliedMigration) TableName() string {
+	return TRACKING_TABLE
+}
+
+type MigrationStatusDTO struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+// The embedded migrations of the database, ordered by version
+func Load(database Database) ([]Migration, error) {
+	return loadFrom(migrationFiles, string(database))
+}
+
+func loadFrom(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("[migrations] Unable to read %s migrations: %s", dir, err.Error())
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !ok || !strings.HasSuffix(fileName, ".sql") || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("[migrations] Unexpected file %s/%s, expected <version>_<name>.up.sql or .down.sql", dir, fileName)
+		}
+		versionStr, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version < 1 || name == "" {
+			return nil, fmt.Errorf("[migrations] Unexpected file %s/%s, expected <version>_<name>.up.sql or .down.sql", dir, fileName)
+		}
+		content, err := fs.ReadFile(files, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("[migrations] Unable to read %s/%s: %s", dir, fileName, err.Error())
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("[migrations] Version %d of %s is used by both %s and %s", version, dir, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("[migrations] %s migration %d_%s requires both an up and a down file", dir, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("[migrations] %s migrations must be numbered consecutively from 1, found %d at position %d", dir, migration.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Applies and reverts the migrations of a single database.
+// Every run holds a postgres advisory lock, so concurrently starting instances don't migrate the same database at once.
+type Migrator struct {
+	database   Database
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(database Database, db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(database)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{database: database, db: db, migrations: migrations}, nil
+}
+
+// The newest version available
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Applies every pending migration up to and including target, or all of them if target is 0.
+// Each migration is applied in its own transaction. Returns the versions applied.
+func (m *Migrator) Up(target int) ([]int, error) {
+	if target == 0 {
+		target = m.Latest()
+	}
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("[migrations] No %s migration with version %d, latest is %d", m.database, target, m.Latest())
+	}
+	var applied []int
+	err := m.withLock(func(conn *gorm.DB, current int) error {
+		for _, migration := range m.migrations[current:target] {
+			log.Printf("[migrations] Applying %s %d_%s\n", m.database, migration.Version, migration.Name)
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&AppliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("[migrations] %s %d_%s failed, rolled back: %s", m.database, migration.Version, migration.Name, err.Error())
+			}
+			applied = append(applied, migration.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Reverts the given amount of most recently applied migrations, newest first. Returns the versions reverted.
+func (m *Migrator) Down(steps int) ([]int, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("[migrations] Steps must be at least 1, got %d", steps)
+	}
+	var reverted []int
+	err := m.withLock(func(conn *gorm.DB, current int) error {
+		for version := current; version > 0 && version > current-steps; version-- {
+			migration := m.migrations[version-1]
+			log.Printf("[migrations] Reverting %s %d_%s\n", m.database, migration.Version, migration.Name)
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Where("version = ?", migration.Version).Delete(&AppliedMigration{}).Error
+			}); err != nil {
+				return fmt.Errorf("[migrations] Reverting %s %d_%s failed, rolled back: %s", m.database, migration.Version, migration.Name, err.Error())
+			}
+			reverted = append(reverted, migration.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Every available migration, with when it was applied if it has been
+func (m *Migrator) Status() ([]MigrationStatusDTO, error) {
+	var status []MigrationStatusDTO
+	err := m.withLock(func(conn *gorm.DB, _ int) error {
+		var applied []AppliedMigration
+		if err := conn.Order("version").Find(&applied).Error; err != nil {
+			return err
+		}
+		appliedAt := make(map[int]time.Time, len(applied))
+		for _, migration := range applied {
+			appliedAt[migration.Version] = migration.AppliedAt
+		}
+		for _, migration := range m.migrations {
+			entry := MigrationStatusDTO{Version: migration.Version, Name: migration.Name}
+			if at, ok := appliedAt[migration.Version]; ok {
+				entry.AppliedAt = &at
+			}
+			status = append(status, entry)
+		}
+		return nil
+	})
+	return status, err
+}
+
+// Runs f on a single connection holding the advisory lock of the database, with the current version applied.
+// Blocks until any other runner is done.
+func (m *Migrator) withLock(f func(conn *gorm.DB, current int) error) error {
+	key := m.lockKey()
+	return m.db.Connection(func(conn *gorm.DB) error {
+		//The connection is handed over as a single statement, which would carry over clauses between calls
+		conn = conn.Session(&gorm.Session{})
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", key).Error; err != nil {
+			return fmt.Errorf("[migrations] Unable to acquire lock for %s: %s", m.database, err.Error())
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", key).Error; err != nil {
+				log.Printf("[migrations] Unable to release lock for %s: %s\n", m.database, err.Error())
+			}
+		}()
+
+		if err := conn.Exec(`CREATE TABLE IF NOT EXISTS "` + TRACKING_TABLE + `" (
+            version     INTEGER PRIMARY KEY,
+            name        TEXT NOT NULL,
+            "appliedAt" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        )`).Error; err != nil {
+			return fmt.Errorf("[migrations] Unable to create %s in %s: %s", TRACKING_TABLE, m.database, err.Error())
+		}
+		var current int
+		if err := conn.Model(&AppliedMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&current).Error; err != nil {
+			return fmt.Errorf("[migrations] Unable to read the %s version: %s", m.database, err.Error())
+		}
+		if current > m.Latest() {
+			return fmt.Errorf("[migrations] %s is at version %d, newer than the latest known %d. Is this an old build?", m.database, current, m.Latest())
+		}
+		return f(conn, current)
+	})
+}
+
+// Advisory locks are global to the postgres server, so the key is derived from the database to not block
+// databases sharing a server
+func (m *Migrator) lockKey() int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("otte_main_backend/migrations/" + string(m.database)))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestEmbeddedMigrationsAreWellFormed(t *testing.T) {
+	for _, database := range Databases {
+		migrations, err := Load(database)
+		if err != nil {
+			t.Errorf("%s: %s", database, err)
+			continue
+		}
+		if len(migrations) == 0 || migrations[0].Name != "baseline" {
+			t.Errorf("%s: expected a baseline migration first, got: %v", database, migrations)
+		}
+	}
+}
+
+func TestLoadRejectsMalformedSets(t *testing.T) {
+	sets := map[string]fstest.MapFS{
+		"gap": {
+			"db/0001_a.up.sql": {Data: []byte("SELECT 1")}, "db/0001_a.down.sql": {Data: []byte("SELECT 1")},
+			"db/0003_b.up.sql": {Data: []byte("SELECT 1")}, "db/0003_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"missing down": {
+			"db/0001_a.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"duplicate version": {
+			"db/0001_a.up.sql": {Data: []byte("SELECT 1")}, "db/0001_a.down.sql": {Data: []byte("SELECT 1")},
+			"db/0001_b.up.sql": {Data: []byte("SELECT 1")}, "db/0001_b.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"unexpected name": {
+			"db/first.up.sql": {Data: []byte("SELECT 1")}, "db/first.down.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+	for name, files := range sets {
+		if _, err := loadFrom(files, "db"); err == nil {
+			t.Errorf("expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestUpAppliesPendingMigrationsUnderLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("failed to open sqlmock database:", err)
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatal("failed to initialize gorm DB:", err)
+	}
+	migrator := &Migrator{database: DatabaseLanguage, db: gormDB, migrations: []Migration{
+		{Version: 1, Name: "a", Up: "CREATE TABLE a ()", Down: "DROP TABLE a"},
+		{Version: 2, Name: "b", Up: "CREATE TABLE b ()", Down: "DROP TABLE b"},
+	}}
+
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_lock($1)")).
+		WithArgs(migrator.lockKey()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "SchemaMigration"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(version), 0) FROM "SchemaMigration"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1))
+	//Only the pending migration is applied, in a transaction along with its tracking row
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b ()")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "SchemaMigration"`)).
+		WithArgs("b", sqlmock.AnyArg(), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(migrator.lockKey()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(0)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(applied) != 1 || applied[0] != 2 {
+		t.Errorf("expected only version 2 to be applied, got: %v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
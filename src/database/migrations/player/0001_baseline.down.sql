@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "Session";
+DROP TABLE IF EXISTS "PlayerPreference";
+DROP TABLE IF EXISTS "AvailablePreference";
+DROP TABLE IF EXISTS "Player";
+DROP TABLE IF EXISTS "Achievement";
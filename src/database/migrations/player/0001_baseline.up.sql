@@ -0,0 +1,40 @@
+-- Tables as they existed before migrations were tracked. IF NOT EXISTS, so existing databases are adopted as is.
+
+CREATE TABLE IF NOT EXISTS "Achievement" (
+    id          SERIAL PRIMARY KEY,
+    title       TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    icon        INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS "Player" (
+    id            SERIAL PRIMARY KEY,
+    "referenceID" TEXT NOT NULL UNIQUE,
+    "firstName"   TEXT NOT NULL DEFAULT '',
+    "lastName"    TEXT NOT NULL DEFAULT '',
+    sprite        INTEGER NOT NULL DEFAULT 0,
+    achievements  INTEGER[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS "AvailablePreference" (
+    id                SERIAL PRIMARY KEY,
+    "preferenceKey"   TEXT NOT NULL UNIQUE,
+    "availableValues" TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE IF NOT EXISTS "PlayerPreference" (
+    id              SERIAL PRIMARY KEY,
+    player          INTEGER NOT NULL REFERENCES "Player" (id) ON DELETE CASCADE,
+    "preferenceKey" TEXT NOT NULL REFERENCES "AvailablePreference" ("preferenceKey") ON DELETE CASCADE,
+    "chosenValue"   TEXT NOT NULL,
+    UNIQUE (player, "preferenceKey")
+);
+
+CREATE TABLE IF NOT EXISTS "Session" (
+    id              SERIAL PRIMARY KEY,
+    player          INTEGER NOT NULL REFERENCES "Player" (id) ON DELETE CASCADE,
+    token           TEXT NOT NULL UNIQUE,
+    "validDuration" INTEGER NOT NULL DEFAULT 3600000,
+    "createdAt"     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "lastCheckIn"   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
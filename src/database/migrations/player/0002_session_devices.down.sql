@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "Session_player_idx";
+
+ALTER TABLE "Session"
+    DROP COLUMN IF EXISTS "deviceLabel",
+    DROP COLUMN IF EXISTS "userAgent";
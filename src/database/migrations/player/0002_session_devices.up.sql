@@ -0,0 +1,6 @@
+-- Concurrent sessions per player, listed with the device they were created on
+ALTER TABLE "Session"
+    ADD COLUMN IF NOT EXISTS "deviceLabel" TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "userAgent"   TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS "Session_player_idx" ON "Session" (player);
@@ -0,0 +1,2 @@
+ALTER TABLE "Player"
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'player' CHECK (role IN ('player', 'teacher', 'admin'));
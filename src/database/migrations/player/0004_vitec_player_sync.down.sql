@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "PlayerProfileChange";
+
+ALTER TABLE "Player"
+    DROP COLUMN IF EXISTS disabled,
+    DROP COLUMN IF EXISTS "lastVerifiedAt",
+    DROP COLUMN IF EXISTS "lastVerificationAttemptAt",
+    DROP COLUMN IF EXISTS "lastVerificationOutcome";
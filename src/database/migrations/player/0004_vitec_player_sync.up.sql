@@ -0,0 +1,17 @@
+-- Vitec re-verification, deprovisioning and profile sync
+ALTER TABLE "Player"
+    ADD COLUMN IF NOT EXISTS disabled                    BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS "lastVerifiedAt"            TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS "lastVerificationAttemptAt" TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS "lastVerificationOutcome"   TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS "PlayerProfileChange" (
+    id          SERIAL PRIMARY KEY,
+    player      INTEGER NOT NULL REFERENCES "Player" (id) ON DELETE CASCADE,
+    field       TEXT NOT NULL,
+    "oldValue"  TEXT NOT NULL,
+    "newValue"  TEXT NOT NULL,
+    "changedAt" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS "PlayerProfileChange_player_idx" ON "PlayerProfileChange" (player);
@@ -0,0 +1,17 @@
+-- Progress events waiting to be, or having been, delivered to Vitec
+CREATE TABLE IF NOT EXISTS "VitecProgressOutbox" (
+    id              SERIAL PRIMARY KEY,
+    player          INTEGER NOT NULL REFERENCES "Player" (id) ON DELETE CASCADE,
+    "referenceID"   TEXT NOT NULL,
+    type            TEXT NOT NULL,
+    payload         JSONB NOT NULL DEFAULT '{}',
+    status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    "lastError"     TEXT NOT NULL DEFAULT '',
+    "createdAt"     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "nextAttemptAt" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "deliveredAt"   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "VitecProgressOutbox_due_idx" ON "VitecProgressOutbox" ("nextAttemptAt") WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS "VitecProgressOutbox_player_idx" ON "VitecProgressOutbox" (player);
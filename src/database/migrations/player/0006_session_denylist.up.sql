@@ -0,0 +1,8 @@
+-- Revoked session tokens. Signed tokens aren't checked against the Session table, so these are loaded by every instance
+-- to keep refusing signed tokens wrapping them across restarts, until they would have expired anyway
+CREATE TABLE IF NOT EXISTS "SessionDenylist" (
+    token         TEXT PRIMARY KEY,
+    "deniedUntil" TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS "SessionDenylist_deniedUntil_idx" ON "SessionDenylist" ("deniedUntil");
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Names the databases are watched under, see Monitor.Watch. Same as the names used by migrations.Database.
+const (
+	PlayerDBName      = "player"
+	ColonyAssetDBName = "colony"
+	LanguageDBName    = "language"
+)
+
+type DBStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	// When the database last became healthy or degraded
+	Since time.Time `json:"since"`
+}
+
+// Periodically checks the connectivity of each watched database, marking it healthy or degraded.
+// Degraded databases are checked more often, using the backoff, so recovery is noticed quickly.
+// Reconnecting is left to the pool, which opens new connections as needed.
+type Monitor struct {
+	interval time.Duration
+	backoff  Backoff
+	ping     func(db *gorm.DB) error
+	lock     sync.RWMutex
+	watched  []*watchedDB
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type watchedDB struct {
+	db          *gorm.DB
+	status      DBStatus
+	failures    int
+	nextCheckAt time.Time
+	// Run once the database is reachable again, see RequireBeforeHealthy
+	prepare func(db *gorm.DB) error
+}
+
+func NewMonitor(interval time.Duration, backoff Backoff) *Monitor {
+	return &Monitor{
+		interval: interval,
+		backoff:  backoff,
+		ping:     ping,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Adds a database, healthy being the outcome of connecting to it
+func (m *Monitor) Watch(name string, db *gorm.DB, healthy bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	watched := &watchedDB{db: db, status: DBStatus{Name: name, Healthy: healthy, Since: time.Now()}}
+	if healthy {
+		watched.nextCheckAt = time.Now().Add(m.interval)
+	} else {
+		watched.failures = 1
+		watched.nextCheckAt = time.Now().Add(m.retryDelay(watched.failures))
+	}
+	m.watched = append(m.watched, watched)
+}
+
+// Keeps the named database degraded after it is reachable again until prepare succeeds, e.g. to apply migrations
+// skipped while it was down. Prepare is retried on each check until it succeeds once.
+// Required more than once, the prepares run in the order required, all of them again on a retry.
+func (m *Monitor) RequireBeforeHealthy(name string, prepare func(db *gorm.DB) error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, watched := range m.watched {
+		if watched.status.Name != name {
+			continue
+		}
+		previous := watched.prepare
+		if previous == nil {
+			watched.prepare = prepare
+			continue
+		}
+		watched.prepare = func(db *gorm.DB) error {
+			if err := previous(db); err != nil {
+				return err
+			}
+			return prepare(db)
+		}
+	}
+}
+
+// Starts checking in the background
+func (m *Monitor) Start() {
+	log.Printf("[database] Connectivity monitor started, interval: %s\n", m.interval)
+	go m.loop()
+}
+
+// Stops the monitor and waits for any ongoing check to finish
+func (m *Monitor) Stop() {
+	close(m.stop)
+	<-m.done
+	log.Println("[database] Connectivity monitor stopped")
+}
+
+// Whether the named database responded to the latest check. Nil-safe, databases not watched are considered healthy.
+func (m *Monitor) IsHealthy(name string) bool {
+	if m == nil {
+		return true
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for _, watched := range m.watched {
+		if watched.status.Name == name {
+			return watched.status.Healthy
+		}
+	}
+	return true
+}
+
+// Names of the databases currently degraded
+func (m *Monitor) Degraded() []string {
+	var degraded = []string{}
+	for _, status := range m.Statuses() {
+		if !status.Healthy {
+			degraded = append(degraded, status.Name)
+		}
+	}
+	return degraded
+}
+
+// Status of each watched database, in the order watched. Nil-safe.
+func (m *Monitor) Statuses() []DBStatus {
+	if m == nil {
+		return []DBStatus{}
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var statuses = make([]DBStatus, len(m.watched))
+	for i, watched := range m.watched {
+		statuses[i] = watched.status
+	}
+	return statuses
+}
+
+func (m *Monitor) loop() {
+	defer close(m.done)
+	timer := time.NewTimer(m.untilNextCheck(time.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-timer.C:
+			m.CheckDue(time.Now())
+			timer.Reset(m.untilNextCheck(time.Now()))
+		}
+	}
+}
+
+func (m *Monitor) untilNextCheck(now time.Time) time.Duration {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	next := m.interval
+	for _, watched := range m.watched {
+		next = min(next, watched.nextCheckAt.Sub(now))
+	}
+	return max(next, 0)
+}
+
+// Checks every database due for a check. Exposed so checks can be triggered outside of the regular schedule.
+func (m *Monitor) CheckDue(now time.Time) {
+	m.lock.RLock()
+	var due []*watchedDB
+	for _, watched := range m.watched {
+		if !watched.nextCheckAt.After(now) {
+			due = append(due, watched)
+		}
+	}
+	m.lock.RUnlock()
+
+	// Pinged without holding the lock, so IsHealthy doesn't block on a database that is down
+	for _, watched := range due {
+		err := m.ping(watched.db)
+		m.lock.RLock()
+		prepare := watched.prepare
+		m.lock.RUnlock()
+		if err == nil && prepare != nil {
+			if err = prepare(watched.db); err != nil {
+				log.Printf("[database] %s is reachable, but could not be prepared: %s\n", watched.status.Name, err.Error())
+			}
+		}
+		m.lock.Lock()
+		if err == nil {
+			watched.prepare = nil
+		}
+		m.record(watched, err, now)
+		m.lock.Unlock()
+	}
+}
+
+func (m *Monitor) record(watched *watchedDB, err error, now time.Time) {
+	if err == nil {
+		if !watched.status.Healthy {
+			log.Printf("[database] %s is reachable again after %s, marked healthy\n", watched.status.Name, now.Sub(watched.status.Since).Round(time.Second))
+			watched.status = DBStatus{Name: watched.status.Name, Healthy: true, Since: now}
+		}
+		watched.failures = 0
+		watched.nextCheckAt = now.Add(m.interval)
+		return
+	}
+	if watched.status.Healthy {
+		log.Printf("[database] %s is unreachable, marked degraded: %s\n", watched.status.Name, err.Error())
+		watched.status = DBStatus{Name: watched.status.Name, Healthy: false, Since: now}
+	}
+	watched.failures++
+	watched.nextCheckAt = now.Add(m.retryDelay(watched.failures))
+}
+
+// Never longer than the regular interval
+func (m *Monitor) retryDelay(failures int) time.Duration {
+	return min(m.backoff.Delay(failures), m.interval)
+}
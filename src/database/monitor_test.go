@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestBackoffDelayDoublesWithinBounds(t *testing.T) {
+	backoff := Backoff{Base: 100 * time.Millisecond, Max: time.Second}
+	for attempt, expected := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 10: time.Second} {
+		for i := 0; i < 20; i++ {
+			delay := backoff.Delay(attempt)
+			if delay > expected || delay < expected/2 {
+				t.Fatalf("attempt %d: delay %s outside of [%s, %s]", attempt, delay, expected/2, expected)
+			}
+		}
+	}
+}
+
+func TestMonitorMarksDegradedAndHealthy(t *testing.T) {
+	monitor := NewMonitor(time.Minute, Backoff{Base: time.Second, Max: 10 * time.Second})
+	var pingErr error
+	monitor.ping = func(db *gorm.DB) error { return pingErr }
+	monitor.Watch(PlayerDBName, nil, true)
+	monitor.Watch(LanguageDBName, nil, false)
+
+	if !monitor.IsHealthy(PlayerDBName) || monitor.IsHealthy(LanguageDBName) {
+		t.Fatalf("expected the initial health to be kept, got: %+v", monitor.Statuses())
+	}
+	if !monitor.IsHealthy("unwatched") {
+		t.Error("expected databases not watched to be considered healthy")
+	}
+
+	// Only the degraded database is due before the interval has passed
+	pingErr = errors.New("connection refused")
+	monitor.CheckDue(time.Now().Add(15 * time.Second))
+	if !monitor.IsHealthy(PlayerDBName) {
+		t.Error("expected the healthy database not to be checked before the interval")
+	}
+
+	monitor.CheckDue(time.Now().Add(time.Minute))
+	if degraded := monitor.Degraded(); len(degraded) != 2 {
+		t.Errorf("expected both databases to be degraded, got: %v", degraded)
+	}
+
+	pingErr = nil
+	monitor.CheckDue(time.Now().Add(2 * time.Minute))
+	if degraded := monitor.Degraded(); len(degraded) != 0 {
+		t.Errorf("expected both databases to have recovered, got: %v", degraded)
+	}
+}
+
+func TestMonitorKeepsDegradedUntilPrepared(t *testing.T) {
+	monitor := NewMonitor(time.Minute, Backoff{Base: time.Second, Max: 10 * time.Second})
+	monitor.ping = func(db *gorm.DB) error { return nil }
+	monitor.Watch(ColonyAssetDBName, nil, false)
+	prepareErr := errors.New("migration failed")
+	prepared := 0
+	monitor.RequireBeforeHealthy(ColonyAssetDBName, func(db *gorm.DB) error {
+		prepared++
+		return prepareErr
+	})
+
+	monitor.CheckDue(time.Now().Add(time.Minute))
+	if monitor.IsHealthy(ColonyAssetDBName) {
+		t.Fatal("expected the database to stay degraded while it can't be prepared")
+	}
+
+	prepareErr = nil
+	monitor.CheckDue(time.Now().Add(2 * time.Minute))
+	if !monitor.IsHealthy(ColonyAssetDBName) {
+		t.Fatal("expected the database to be healthy once prepared")
+	}
+
+	// Prepared only once, later checks are plain pings
+	monitor.CheckDue(time.Now().Add(4 * time.Minute))
+	if prepared != 2 {
+		t.Errorf("expected prepare to stop once it succeeded, ran %d times", prepared)
+	}
+}
+
+func TestMonitorRunsEveryRequiredPrepareInOrder(t *testing.T) {
+	monitor := NewMonitor(time.Minute, Backoff{Base: time.Second, Max: 10 * time.Second})
+	monitor.ping = func(db *gorm.DB) error { return nil }
+	monitor.Watch(PlayerDBName, nil, false)
+	var prepared []string
+	monitor.RequireBeforeHealthy(PlayerDBName, func(db *gorm.DB) error {
+		prepared = append(prepared, "migrations")
+		return nil
+	})
+	monitor.RequireBeforeHealthy(PlayerDBName, func(db *gorm.DB) error {
+		prepared = append(prepared, "tokens")
+		return nil
+	})
+
+	monitor.CheckDue(time.Now().Add(time.Minute))
+	if !monitor.IsHealthy(PlayerDBName) || len(prepared) != 2 || prepared[0] != "migrations" || prepared[1] != "tokens" {
+		t.Errorf("expected both prepares to run in order before marking healthy, got %v", prepared)
+	}
+}
+
+func TestMonitorRetriesDegradedWithBackoff(t *testing.T) {
+	monitor := NewMonitor(time.Minute, Backoff{Base: time.Second, Max: 8 * time.Second})
+	pings := 0
+	monitor.ping = func(db *gorm.DB) error {
+		pings++
+		return errors.New("connection refused")
+	}
+	monitor.Watch(ColonyAssetDBName, nil, false)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		now = now.Add(monitor.untilNextCheck(now))
+		monitor.CheckDue(now)
+	}
+	if pings != 10 {
+		t.Errorf("expected a ping per due check, got %d", pings)
+	}
+	if until := monitor.untilNextCheck(now); until > 8*time.Second {
+		t.Errorf("expected retries to be capped by the backoff max, next check in %s", until)
+	}
+}
+
+func TestMonitorIsNilSafe(t *testing.T) {
+	var monitor *Monitor
+	if !monitor.IsHealthy(PlayerDBName) || len(monitor.Degraded()) != 0 {
+		t.Error("expected a nil monitor to consider every database healthy")
+	}
+}
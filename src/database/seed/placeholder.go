@@ -0,0 +1,62 @@
+package seed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const PLACEHOLDER_MIME_TYPE = "image/png"
+
+// A PNG of the asset at the detail level, filled with its color and framed by a darker border so tiles
+// placed next to each other can be told apart. Each detail level halves the size, down to 1x1.
+// The same input always gives the same bytes, so the etag only changes when the fixture does.
+func Placeholder(asset GraphicalAsset, detailLevel int) (*LOD, error) {
+	fill, err := parseColor(asset.Color)
+	if err != nil {
+		return nil, err
+	}
+	border := color.RGBA{R: fill.R / 2, G: fill.G / 2, B: fill.B / 2, A: 255}
+	width := max(asset.Width>>detailLevel, 1)
+	height := max(asset.Height>>detailLevel, 1)
+	borderWidth := max(min(width, height)/32, 1)
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < borderWidth || y < borderWidth || x >= width-borderWidth || y >= height-borderWidth {
+				img.SetRGBA(x, y, border)
+			} else {
+				img.SetRGBA(x, y, fill)
+			}
+		}
+	}
+	var blob bytes.Buffer
+	if err := png.Encode(&blob, img); err != nil {
+		return nil, fmt.Errorf("[seed] Unable to encode placeholder of asset %d: %s", asset.ID, err.Error())
+	}
+	hash := sha256.Sum256(blob.Bytes())
+	return &LOD{
+		DetailLevel:    detailLevel,
+		GraphicalAsset: asset.ID,
+		Blob:           blob.Bytes(),
+		ETag:           hex.EncodeToString(hash[:16]),
+		MIMEType:       PLACEHOLDER_MIME_TYPE,
+	}, nil
+}
+
+// #rrggbb
+func parseColor(value string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(value) != 7 || value[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("color must be formatted as #rrggbb, got: %q", value)
+	}
+	if _, err := fmt.Sscanf(value[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("color must be formatted as #rrggbb, got: %q", value)
+	}
+	return color.RGBA{R: r, G: g, B: b, A: 255}, nil
+}
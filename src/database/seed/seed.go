@@ -0,0 +1,293 @@
+package seed
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"otte_main_backend/src/database/migrations"
+	"otte_main_backend/src/util"
+	"path"
+	"strings"
+)
+
+// Fixtures are declared as JSON, in a file per database. Every row has a fixed ID, so seeding again updates
+// the rows in place instead of duplicating them. The IDs are reserved for fixtures, don't use them for other data.
+// Players are the exception, they are only inserted, see insertPlayer. No admin is seeded, promote a player for that.
+//
+// The colony database requires GraphicalAsset 8001, 8034 and 8035, AssetCollection 10001 to 10035 and Location 10 to 110
+// to create colonies (see api/colony), and achievement 1 is always the tutorial.
+//
+//go:embed fixtures/*.json
+var fixtureFiles embed.FS
+
+type Fixtures struct {
+	Player   PlayerFixtures
+	Colony   ColonyFixtures
+	Language LanguageFixtures
+}
+
+type PlayerFixtures struct {
+	Achievements         []Achievement         `json:"achievements"`
+	AvailablePreferences []AvailablePreference `json:"availablePreferences"`
+	Players              []Player              `json:"players"`
+}
+
+type Achievement struct {
+	ID          uint32 `json:"id" gorm:"column:id;primaryKey"`
+	Title       string `json:"title" gorm:"column:title"`
+	Description string `json:"description" gorm:"column:description"`
+	Icon        uint32 `json:"icon" gorm:"column:icon"`
+}
+
+func (a *Achievement) TableName() string {
+	return "Achievement"
+}
+
+type AvailablePreference struct {
+	ID              uint32             `json:"id" gorm:"column:id;primaryKey"`
+	PreferenceKey   string             `json:"preferenceKey" gorm:"column:preferenceKey"`
+	AvailableValues util.PGStringArray `json:"availableValues" gorm:"column:availableValues"`
+}
+
+func (a *AvailablePreference) TableName() string {
+	return "AvailablePreference"
+}
+
+type Player struct {
+	ID           uint32          `json:"id" gorm:"column:id;primaryKey"`
+	ReferenceID  string          `json:"referenceID" gorm:"column:referenceID"`
+	FirstName    string          `json:"firstName" gorm:"column:firstName"`
+	LastName     string          `json:"lastName" gorm:"column:lastName"`
+	Sprite       uint32          `json:"sprite" gorm:"column:sprite"`
+	Achievements util.PGIntArray `json:"achievements" gorm:"column:achievements"`
+	// player | teacher | admin
+	Role string `json:"role" gorm:"column:role"`
+}
+
+func (p *Player) TableName() string {
+	return "Player"
+}
+
+type LanguageFixtures struct {
+	Languages []Language       `json:"languages"`
+	Catalogue []CatalogueEntry `json:"catalogue"`
+}
+
+type Language struct {
+	ID         uint32  `json:"id" gorm:"column:id;primaryKey"`
+	Code       string  `json:"code" gorm:"column:code"`
+	CommonName string  `json:"commonName" gorm:"column:commonName"`
+	Coverage   float32 `json:"coverage" gorm:"column:coverage"`
+	Icon       uint32  `json:"icon" gorm:"column:icon"`
+}
+
+func (l *Language) TableName() string {
+	return "AvailableLanguages"
+}
+
+// Values by language code. Languages left out are left empty.
+type CatalogueEntry struct {
+	Key    string            `json:"key"`
+	Values map[string]string `json:"values"`
+}
+
+type ColonyFixtures struct {
+	GraphicalAssets []GraphicalAsset `json:"graphicalAssets"`
+	Collections     []Collection     `json:"collections"`
+	Minigames       []Minigame       `json:"minigames"`
+	Locations       []Location       `json:"locations"`
+}
+
+// A LOD is generated per detail level, see Placeholder
+type GraphicalAsset struct {
+	ID      uint32 `json:"id" gorm:"column:id;primaryKey"`
+	Alias   string `json:"alias" gorm:"column:alias"`
+	Type    string `json:"type" gorm:"column:type"`
+	UseCase string `json:"useCase" gorm:"column:useCase"`
+	Width   int    `json:"width" gorm:"column:width"`
+	Height  int    `json:"height" gorm:"column:height"`
+	// Fill of the placeholder, #rrggbb
+	Color        string `json:"color" gorm:"-"`
+	DetailLevels []int  `json:"detailLevels" gorm:"-"`
+}
+
+func (a *GraphicalAsset) TableName() string {
+	return "GraphicalAsset"
+}
+
+type LOD struct {
+	DetailLevel    int    `gorm:"column:detailLevel"`
+	GraphicalAsset uint32 `gorm:"column:graphicalAsset"`
+	Blob           []byte `gorm:"column:blob"`
+	ETag           string `gorm:"column:etag"`
+	MIMEType       string `gorm:"column:type"`
+}
+
+func (l *LOD) TableName() string {
+	return "LOD"
+}
+
+type Collection struct {
+	ID      uint32            `json:"id" gorm:"column:id;primaryKey"`
+	Name    string            `json:"name" gorm:"column:name"`
+	UseCase string            `json:"useCase" gorm:"column:useCase"`
+	Entries []CollectionEntry `json:"entries" gorm:"-"`
+}
+
+func (c *Collection) TableName() string {
+	return "AssetCollection"
+}
+
+type CollectionEntry struct {
+	ID             uint32    `json:"id"`
+	GraphicalAsset uint32    `json:"graphicalAsset"`
+	Transform      Transform `json:"transform"`
+}
+
+type Transform struct {
+	ID      uint32  `json:"-" gorm:"column:id;primaryKey"`
+	XScale  float32 `json:"xScale" gorm:"column:xScale"`
+	YScale  float32 `json:"yScale" gorm:"column:yScale"`
+	XOffset float32 `json:"xOffset" gorm:"column:xOffset"`
+	YOffset float32 `json:"yOffset" gorm:"column:yOffset"`
+	ZIndex  int     `json:"zIndex" gorm:"column:zIndex"`
+}
+
+func (t *Transform) TableName() string {
+	return "Transform"
+}
+
+type Minigame struct {
+	ID           uint32               `json:"id"`
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	Icon         uint32               `json:"icon"`
+	Settings     json.RawMessage      `json:"settings"`
+	Difficulties []MinigameDifficulty `json:"difficulties"`
+}
+
+type MinigameDifficulty struct {
+	ID                  uint32          `json:"id"`
+	Name                string          `json:"name"`
+	Description         string          `json:"description"`
+	Icon                uint32          `json:"icon"`
+	RequiredLevel       int             `json:"requiredLevel"`
+	OverwritingSettings json.RawMessage `json:"overwritingSettings"`
+}
+
+type Location struct {
+	ID          uint32 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Optional
+	Minigame    *uint32              `json:"minigame"`
+	Appearances []LocationAppearance `json:"appearances"`
+}
+
+// Identified by location and level, so these are not given an ID
+type LocationAppearance struct {
+	Level           int    `json:"level"`
+	SplashArt       uint32 `json:"splashArt"`
+	AssetCollection uint32 `json:"assetCollection"`
+}
+
+// The embedded fixtures, validated
+func Load() (*Fixtures, error) {
+	var fixtures Fixtures
+	for database, destination := range map[migrations.Database]interface{}{
+		migrations.DatabasePlayer:   &fixtures.Player,
+		migrations.DatabaseColony:   &fixtures.Colony,
+		migrations.DatabaseLanguage: &fixtures.Language,
+	} {
+		fileName := path.Join("fixtures", string(database)+".json")
+		content, err := fixtureFiles.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("[seed] Unable to read %s: %s", fileName, err.Error())
+		}
+		if err := json.Unmarshal(content, destination); err != nil {
+			return nil, fmt.Errorf("[seed] Unable to parse %s: %s", fileName, err.Error())
+		}
+	}
+	if problems := fixtures.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("[seed] Invalid fixtures:\n - %s", strings.Join(problems, "\n - "))
+	}
+	return &fixtures, nil
+}
+
+// Every reference between fixtures of the same database must resolve, as the foreign keys would otherwise fail
+// halfway through seeding. References across databases, like icons, are not checked.
+func (f *Fixtures) Validate() []string {
+	var problems []string
+	assets := make(map[uint32]bool, len(f.Colony.GraphicalAssets))
+	for _, asset := range f.Colony.GraphicalAssets {
+		assets[asset.ID] = true
+		if asset.Width < 1 || asset.Height < 1 {
+			problems = append(problems, fmt.Sprintf("graphical asset %d: width and height must be positive", asset.ID))
+		}
+		if _, err := parseColor(asset.Color); err != nil {
+			problems = append(problems, fmt.Sprintf("graphical asset %d: %s", asset.ID, err.Error()))
+		}
+	}
+	collections := make(map[uint32]bool, len(f.Colony.Collections))
+	for _, collection := range f.Colony.Collections {
+		collections[collection.ID] = true
+		for _, entry := range collection.Entries {
+			if !assets[entry.GraphicalAsset] {
+				problems = append(problems, fmt.Sprintf("collection %d: unknown graphical asset %d", collection.ID, entry.GraphicalAsset))
+			}
+		}
+	}
+	minigames := make(map[uint32]bool, len(f.Colony.Minigames))
+	for _, minigame := range f.Colony.Minigames {
+		minigames[minigame.ID] = true
+		if len(minigame.Settings) > 0 && !json.Valid(minigame.Settings) {
+			problems = append(problems, fmt.Sprintf("minigame %d: settings are not valid JSON", minigame.ID))
+		}
+		if !assets[minigame.Icon] {
+			problems = append(problems, fmt.Sprintf("minigame %d: unknown icon %d", minigame.ID, minigame.Icon))
+		}
+		for _, difficulty := range minigame.Difficulties {
+			if !assets[difficulty.Icon] {
+				problems = append(problems, fmt.Sprintf("minigame difficulty %d: unknown icon %d", difficulty.ID, difficulty.Icon))
+			}
+		}
+	}
+	for _, location := range f.Colony.Locations {
+		if location.Minigame != nil && !minigames[*location.Minigame] {
+			problems = append(problems, fmt.Sprintf("location %d: unknown minigame %d", location.ID, *location.Minigame))
+		}
+		for _, appearance := range location.Appearances {
+			if !assets[appearance.SplashArt] {
+				problems = append(problems, fmt.Sprintf("location %d: unknown splash art %d", location.ID, appearance.SplashArt))
+			}
+			if !collections[appearance.AssetCollection] {
+				problems = append(problems, fmt.Sprintf("location %d: unknown asset collection %d", location.ID, appearance.AssetCollection))
+			}
+		}
+	}
+
+	achievements := make(map[uint32]bool, len(f.Player.Achievements))
+	for _, achievement := range f.Player.Achievements {
+		achievements[achievement.ID] = true
+	}
+	for _, player := range f.Player.Players {
+		for _, achievement := range player.Achievements {
+			if !achievements[uint32(achievement)] {
+				problems = append(problems, fmt.Sprintf("player %d: unknown achievement %d", player.ID, achievement))
+			}
+		}
+	}
+
+	languages := make(map[string]bool, len(f.Language.Languages))
+	for _, language := range f.Language.Languages {
+		languages[language.Code] = true
+	}
+	for _, entry := range f.Language.Catalogue {
+		for code := range entry.Values {
+			if !languages[code] {
+				problems = append(problems, fmt.Sprintf("catalogue key %s: unknown language %s", entry.Key, code))
+			}
+		}
+	}
+	return problems
+}
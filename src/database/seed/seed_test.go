@@ -0,0 +1,121 @@
+package seed
+
+import (
+	"bytes"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestEmbeddedFixturesProvideWhatColoniesRequire(t *testing.T) {
+	fixtures, err := Load()
+	if err != nil {
+		t.Fatal("failed to load fixtures:", err)
+	}
+
+	assets := map[uint32]GraphicalAsset{}
+	for _, asset := range fixtures.Colony.GraphicalAssets {
+		assets[asset.ID] = asset
+	}
+	for _, id := range []uint32{8001, 8034, 8035} {
+		if asset, exists := assets[id]; !exists || len(asset.DetailLevels) == 0 {
+			t.Errorf("expected graphical asset %d with at least one LOD", id)
+		}
+	}
+	collections := map[uint32]bool{}
+	for _, collection := range fixtures.Colony.Collections {
+		collections[collection.ID] = len(collection.Entries) > 0
+	}
+	for id := uint32(10001); id <= 10035; id++ {
+		if !collections[id] {
+			t.Errorf("expected asset collection %d with at least one entry", id)
+		}
+	}
+	locations := map[uint32]bool{}
+	for _, location := range fixtures.Colony.Locations {
+		locations[location.ID] = len(location.Appearances) > 0
+	}
+	for id := uint32(10); id <= 110; id += 10 {
+		if !locations[id] {
+			t.Errorf("expected location %d with at least one appearance", id)
+		}
+	}
+	if len(fixtures.Player.Achievements) == 0 || fixtures.Player.Achievements[0].ID != 1 || !strings.Contains(fixtures.Player.Achievements[0].Title, "TUTORIAL") {
+		t.Errorf("expected achievement 1 to be the tutorial, got: %+v", fixtures.Player.Achievements)
+	}
+}
+
+func TestValidateReportsUnresolvedReferences(t *testing.T) {
+	minigame := uint32(9)
+	fixtures := &Fixtures{
+		Player: PlayerFixtures{Players: []Player{{ID: 1, Achievements: []int{1}}}},
+		Colony: ColonyFixtures{
+			GraphicalAssets: []GraphicalAsset{{ID: 8001, Width: 10, Height: 10, Color: "green"}},
+			Collections:     []Collection{{ID: 10001, Entries: []CollectionEntry{{ID: 1, GraphicalAsset: 8002}}}},
+			Locations:       []Location{{ID: 10, Minigame: &minigame, Appearances: []LocationAppearance{{Level: 1, SplashArt: 8001, AssetCollection: 10002}}}},
+		},
+		Language: LanguageFixtures{Catalogue: []CatalogueEntry{{Key: "KEY", Values: map[string]string{"SE": "Nyckel"}}}},
+	}
+
+	problems := strings.Join(fixtures.Validate(), "\n")
+	for _, expected := range []string{"#rrggbb", "unknown graphical asset 8002", "unknown minigame 9", "unknown asset collection 10002", "unknown achievement 1", "unknown language SE"} {
+		if !strings.Contains(problems, expected) {
+			t.Errorf("expected %q to be reported, got:\n%s", expected, problems)
+		}
+	}
+}
+
+func TestPlaceholderHalvesPerDetailLevel(t *testing.T) {
+	asset := GraphicalAsset{ID: 8001, Width: 256, Height: 128, Color: "#5b7f3a"}
+	for detailLevel, expectedWidth := range map[int]int{0: 256, 1: 128, 2: 64, 10: 1} {
+		lod, err := Placeholder(asset, detailLevel)
+		if err != nil {
+			t.Fatal("failed to generate placeholder:", err)
+		}
+		img, err := png.Decode(bytes.NewReader(lod.Blob))
+		if err != nil {
+			t.Fatal("failed to decode placeholder:", err)
+		}
+		if img.Bounds().Dx() != expectedWidth || lod.MIMEType != PLACEHOLDER_MIME_TYPE {
+			t.Errorf("detail level %d: expected a %dpx wide PNG, got %dpx of %s", detailLevel, expectedWidth, img.Bounds().Dx(), lod.MIMEType)
+		}
+	}
+
+	first, _ := Placeholder(asset, 1)
+	second, _ := Placeholder(asset, 1)
+	if first.ETag != second.ETag || !bytes.Equal(first.Blob, second.Blob) {
+		t.Error("expected the placeholder to be the same every time, so seeding again doesn't change it")
+	}
+}
+
+func TestInsertPlayerRefusesToOverwriteAnotherPlayer(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("failed to open sqlmock database:", err)
+	}
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatal("failed to initialize gorm DB:", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "referenceID" FROM "Player"`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"referenceID"}).AddRow("real-player"))
+
+	counts := map[string]int{}
+	err = insertPlayer(gormDB, Player{ID: 3, ReferenceID: "dev-teacher", Role: "teacher"}, counts)
+	if err == nil || !strings.Contains(err.Error(), "real-player") {
+		t.Errorf("expected seeding to be refused, got: %v", err)
+	}
+	if counts["Player"] != 0 {
+		t.Errorf("expected no player to be written, got: %v", counts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error("unfulfilled expectations:", err)
+	}
+}
@@ -0,0 +1,260 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"otte_main_backend/src/database/migrations"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Writes the fixtures of a database in a single transaction, so a failed run leaves the database as it was.
+// Returns the amount of rows written per table.
+func Seed(database migrations.Database, db *gorm.DB, fixtures *Fixtures) (map[string]int, error) {
+	counts := map[string]int{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		switch database {
+		case migrations.DatabasePlayer:
+			return seedPlayer(tx, &fixtures.Player, counts)
+		case migrations.DatabaseColony:
+			return seedColony(tx, &fixtures.Colony, counts)
+		case migrations.DatabaseLanguage:
+			return seedLanguage(tx, &fixtures.Language, counts)
+		}
+		return fmt.Errorf("[seed] No fixtures for database %s", database)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("[seed] Seeding %s failed, rolled back: %s", database, err.Error())
+	}
+	return counts, nil
+}
+
+func seedPlayer(tx *gorm.DB, fixtures *PlayerFixtures, counts map[string]int) error {
+	if err := upsertByID(tx, &fixtures.Achievements, counts, "title", "description", "icon"); err != nil {
+		return err
+	}
+	if err := upsertByID(tx, &fixtures.AvailablePreferences, counts, "preferenceKey", "availableValues"); err != nil {
+		return err
+	}
+	for _, player := range fixtures.Players {
+		if err := insertPlayer(tx, player, counts); err != nil {
+			return fmt.Errorf("player %d: %s", player.ID, err.Error())
+		}
+	}
+	return resetSequences(tx, "Achievement", "AvailablePreference", "Player")
+}
+
+func seedLanguage(tx *gorm.DB, fixtures *LanguageFixtures, counts map[string]int) error {
+	if err := upsertByID(tx, &fixtures.Languages, counts, "code", "commonName", "coverage", "icon"); err != nil {
+		return err
+	}
+	for _, entry := range fixtures.Catalogue {
+		row := map[string]interface{}{"key": entry.Key}
+		var codes []string
+		for code, value := range entry.Values {
+			row[code] = value
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		err := tx.Table("Catalogue").
+			Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoUpdates: clause.AssignmentColumns(codes)}).
+			Create(row).Error
+		if err != nil {
+			return fmt.Errorf("catalogue key %s: %s", entry.Key, err.Error())
+		}
+		counts["Catalogue"]++
+	}
+	return resetSequences(tx, "AvailableLanguages")
+}
+
+func seedColony(tx *gorm.DB, fixtures *ColonyFixtures, counts map[string]int) error {
+	if err := upsertByID(tx, &fixtures.GraphicalAssets, counts, "alias", "type", "useCase", "width", "height"); err != nil {
+		return err
+	}
+	for _, asset := range fixtures.GraphicalAssets {
+		for _, detailLevel := range asset.DetailLevels {
+			lod, err := Placeholder(asset, detailLevel)
+			if err != nil {
+				return err
+			}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "graphicalAsset"}, {Name: "detailLevel"}},
+				DoUpdates: clause.AssignmentColumns([]string{"blob", "etag", "type"}),
+			}).Create(lod).Error
+			if err != nil {
+				return fmt.Errorf("LOD %d of asset %d: %s", detailLevel, asset.ID, err.Error())
+			}
+			counts["LOD"]++
+		}
+	}
+
+	if err := upsertByID(tx, &fixtures.Collections, counts, "name", "useCase"); err != nil {
+		return err
+	}
+	for _, collection := range fixtures.Collections {
+		for _, entry := range collection.Entries {
+			if err := upsertCollectionEntry(tx, collection.ID, entry); err != nil {
+				return fmt.Errorf("collection entry %d: %s", entry.ID, err.Error())
+			}
+			counts["CollectionEntry"]++
+		}
+	}
+
+	for _, minigame := range fixtures.Minigames {
+		row := map[string]interface{}{
+			"id": minigame.ID, "name": minigame.Name, "description": minigame.Description, "icon": minigame.Icon,
+			"settings": jsonOrEmpty(minigame.Settings),
+		}
+		if err := upsertRowByID(tx, "MiniGame", row); err != nil {
+			return fmt.Errorf("minigame %d: %s", minigame.ID, err.Error())
+		}
+		counts["MiniGame"]++
+		for _, difficulty := range minigame.Difficulties {
+			row := map[string]interface{}{
+				"id": difficulty.ID, "name": difficulty.Name, "description": difficulty.Description, "icon": difficulty.Icon,
+				"minigame": minigame.ID, "requiredLevel": difficulty.RequiredLevel,
+				"overwritingSettings": jsonOrEmpty(difficulty.OverwritingSettings),
+			}
+			if err := upsertRowByID(tx, "MiniGameDifficulty", row); err != nil {
+				return fmt.Errorf("minigame difficulty %d: %s", difficulty.ID, err.Error())
+			}
+			counts["MiniGameDifficulty"]++
+		}
+	}
+
+	for _, location := range fixtures.Locations {
+		row := map[string]interface{}{"id": location.ID, "name": location.Name, "description": location.Description, "minigame": location.Minigame}
+		if err := upsertRowByID(tx, "Location", row); err != nil {
+			return fmt.Errorf("location %d: %s", location.ID, err.Error())
+		}
+		counts["Location"]++
+		for _, appearance := range location.Appearances {
+			err := tx.Table("LocationAppearance").
+				Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "location"}, {Name: "level"}},
+					DoUpdates: clause.AssignmentColumns([]string{"splashArt", "assetCollection"}),
+				}).
+				Create(map[string]interface{}{
+					"location": location.ID, "level": appearance.Level,
+					"splashArt": appearance.SplashArt, "assetCollection": appearance.AssetCollection,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("appearance %d of location %d: %s", appearance.Level, location.ID, err.Error())
+			}
+			counts["LocationAppearance"]++
+		}
+	}
+	return resetSequences(tx, "GraphicalAsset", "AssetCollection", "CollectionEntry", "MiniGame", "MiniGameDifficulty", "Location")
+}
+
+// Players are only ever inserted. An existing one is left as is, as its name and role may have been changed since,
+// unless its reference ID differs, in which case the ID belongs to a real player and seeding is refused.
+func insertPlayer(tx *gorm.DB, player Player, counts map[string]int) error {
+	var referenceIDs []string
+	if err := tx.Model(&Player{}).Where("id = ?", player.ID).Pluck("referenceID", &referenceIDs).Error; err != nil {
+		return err
+	}
+	if len(referenceIDs) > 0 {
+		if referenceIDs[0] != player.ReferenceID {
+			return fmt.Errorf("id is taken by a player with reference ID %s, refusing to overwrite it", referenceIDs[0])
+		}
+		return nil
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&player)
+	if result.Error != nil {
+		return result.Error
+	}
+	counts["Player"] += int(result.RowsAffected)
+	return nil
+}
+
+// Transforms have no fixed ID, as colonies create them as well. An existing entry keeps its transform, which is updated in place.
+func upsertCollectionEntry(tx *gorm.DB, collectionID uint32, entry CollectionEntry) error {
+	transform := entry.Transform
+	var transformIDs []uint32
+	if err := tx.Table("CollectionEntry").Where("id = ?", entry.ID).Pluck("transform", &transformIDs).Error; err != nil {
+		return err
+	}
+	if len(transformIDs) > 0 {
+		transform.ID = transformIDs[0]
+		if err := tx.Select("*").Save(&transform).Error; err != nil {
+			return err
+		}
+		return tx.Table("CollectionEntry").Where("id = ?", entry.ID).
+			Updates(map[string]interface{}{"assetCollection": collectionID, "graphicalAsset": entry.GraphicalAsset}).Error
+	}
+	if err := tx.Create(&transform).Error; err != nil {
+		return err
+	}
+	return tx.Table("CollectionEntry").Create(map[string]interface{}{
+		"id": entry.ID, "assetCollection": collectionID, "graphicalAsset": entry.GraphicalAsset, "transform": transform.ID,
+	}).Error
+}
+
+// Inserts the rows, or updates the given columns of those with an existing ID
+func upsertByID[T any](tx *gorm.DB, rows *[]T, counts map[string]int, columns ...string) error {
+	if len(*rows) == 0 {
+		return nil
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(rows)
+	if result.Error != nil {
+		return result.Error
+	}
+	counts[result.Statement.Table] += len(*rows)
+	return nil
+}
+
+// Same as upsertByID for a single row given as column -> value, every column but the ID is updated
+func upsertRowByID(tx *gorm.DB, table string, row map[string]interface{}) error {
+	var columns []string
+	for column := range row {
+		if column != "id" {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return tx.Table(table).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(row).Error
+}
+
+// Rows inserted with an explicit ID don't advance the sequence of the table, which would then hand out
+// the IDs of fixtures to rows created later
+func resetSequences(tx *gorm.DB, tables ...string) error {
+	for _, table := range tables {
+		query := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('"%s"', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM "%s"`, table, table)
+		if err := tx.Exec(query).Error; err != nil {
+			return fmt.Errorf("unable to reset the id sequence of %s: %s", table, err.Error())
+		}
+	}
+	return nil
+}
+
+// Compacted, as the fixtures are indented
+func jsonOrEmpty(raw json.RawMessage) string {
+	var compacted bytes.Buffer
+	if len(raw) == 0 || json.Compact(&compacted, raw) != nil {
+		return "{}"
+	}
+	return compacted.String()
+}
+
+// Logs the rows written per table, in a stable order
+func LogCounts(database migrations.Database, counts map[string]int) {
+	var tables []string
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		log.Printf("[seed] %s: %s, %d row(s)\n", database, table, counts[table])
+	}
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"crypto/tls"
+	"errors"
 	"log"
+	"os"
+	"os/signal"
 	api "otte_main_backend/src/api"
 	"otte_main_backend/src/auth"
 	"otte_main_backend/src/config"
 	db "otte_main_backend/src/database"
 	"otte_main_backend/src/meta"
+	"otte_main_backend/src/reload"
+	"otte_main_backend/src/server"
 	"otte_main_backend/src/vitec"
 	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -19,94 +29,181 @@ func main() {
 		panic(envErr)
 	}
 
-	servicePort, err := initServerResources()
-	if err != nil {
-		panic(err)
+	cfg, cfgErr := config.Load()
+	if config.PrintConfigRequested() {
+		cfg.Print(os.Stdout)
+		if cfgErr != nil {
+			log.Fatal(cfgErr)
+		}
+		return
+	}
+	if cfgErr != nil {
+		log.Fatal(cfgErr)
+	}
+	if command, args := config.RequestedCommand(); command != "" {
+		if err := runCommand(cfg, command, args); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
-	colonyDB, languageDB, playerDB, dbErr := ConnectDatabases()
+	colonyDB, languageDB, playerDB, dbMonitor, dbErr := ConnectDatabases(cfg, cfg.DBAllowDegradedStart)
 	if dbErr != nil {
 		panic(dbErr)
 	}
+	if cfg.DBAutoMigrate {
+		if migrateErr := autoMigrate(colonyDB, languageDB, playerDB, dbMonitor); migrateErr != nil {
+			panic(migrateErr)
+		}
+	}
 
-	vitecIntegration, integrationErr := vitec.CreateNewVitecIntegration()
+	vitecIntegration, integrationErr := vitec.CreateNewVitecIntegration(cfg.Vitec)
 	if integrationErr != nil {
 		panic(integrationErr)
 	}
 
-	context, err := meta.CreateApplicationContext(colonyDB, languageDB, playerDB, vitecIntegration)
+	context, err := meta.CreateApplicationContext(colonyDB, languageDB, playerDB, dbMonitor, vitecIntegration, cfg)
 	if err != nil {
 		panic(err)
 	}
+	progressReporter, progressErr := vitec.StartProgressReporter(vitecIntegration, playerDB, cfg.Vitec)
+	if progressErr != nil {
+		panic(progressErr)
+	}
 	authService, authInitErr := auth.InitializeAuth(context)
 	if authInitErr != nil {
 		panic(authInitErr)
 	}
 
-	app := fiber.New()
+	app := fiber.New(server.NewFiberConfig(cfg.Proxy))
+	server.ApplyClientIPResolution(app, cfg.Proxy)
 
 	app.Use(cors.New())
-	if apiErr := api.ApplyEndpoints(app, context, authService); apiErr != nil {
+	configReloader := reload.NewConfigReloader(context, authService)
+	if apiErr := api.ApplyEndpoints(app, context, authService, configReloader); apiErr != nil {
 		panic(apiErr)
 	}
 
+	dbMonitor.Start()
+	checkInRecorder := auth.StartCheckInRecorder(context, authService)
+	sessionReaper := auth.StartSessionReaper(context, authService)
+	go shutdownOnSignal(app)
+
 	log.Println("[server] Starting server...")
-	useTLS := config.GetOr("ENABLE_TLS", "true") == "true"
-	if useTLS {
-		log.Fatal(doTheTLSThing(servicePort, app))
+	var serverErr error
+	if cfg.EnableTLS {
+		serverErr = doTheTLSThing(cfg.ServicePort, cfg.TLS, app, configReloader)
 	} else {
-		log.Fatal(listenHTTP(servicePort, app))
+		go reloadOnSignal(nil, configReloader)
+		serverErr = listenHTTP(cfg.ServicePort, app)
 	}
-}
 
-func doTheTLSThing(port ServicePort, app *fiber.App) error {
-	//Self signed cert generated following:
-	//https://gist.github.com/taoyuan/39d9bc24bafc8cc45663683eae36eb1a
-	//See "OTTE Dev Cert Details" file for details
-	return app.ListenTLS(
-		":"+strconv.FormatInt(port, 10),
-		"certs/otte_dev_cert.crt",
-		"certs/otte_dev_cert.key")
+	// Reached once the server has stopped listening
+	sessionReaper.Stop()
+	checkInRecorder.Stop()
+	dbMonitor.Stop()
+	if progressReporter != nil {
+		progressReporter.Stop()
+	}
+	if serverErr != nil {
+		log.Fatal(serverErr)
+	}
+	log.Println("[server] Shut down gracefully")
 }
 
-func listenHTTP(port ServicePort, app *fiber.App) error {
-	log.Println("[server] TLS DISABLED.")
-	return app.Listen(":" + strconv.FormatInt(port, 10))
+// Blocks until SIGINT or SIGTERM is received, then stops the server from accepting new requests
+func shutdownOnSignal(app *fiber.App) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	log.Printf("[server] Received %s, shutting down...\n", sig)
+	if err := app.Shutdown(); err != nil {
+		log.Println("[server] Error during shutdown: " + err.Error())
+	}
 }
 
-type ServicePort = int64
-
-func initServerResources() (ServicePort, error) {
-	servicePortStr, err := config.LoudGet("SERVICE_PORT")
+func doTheTLSThing(port int, tlsCfg config.TLSConfig, app *fiber.App, configReloader *reload.ConfigReloader) error {
+	//Without a cert at TLS_CERT_FILE, a self signed cert is generated outside of prod, see TLS_SELF_SIGNED
+	tlsConfig, certReloader, err := server.NewTLSConfig(tlsCfg)
 	if err != nil {
-		return -1, err
+		return err
 	}
-	servicePortInt, err := strconv.ParseInt(servicePortStr, 10, 32)
+	go reloadOnSignal(certReloader, configReloader)
+	listener, err := tls.Listen("tcp", ":"+strconv.Itoa(port), tlsConfig)
 	if err != nil {
-		return -1, err
+		return err
 	}
-
-	return servicePortInt, nil
+	log.Printf("[server] TLS enabled, min version: %s, client auth: %s\n", tlsCfg.MinVersion, tlsCfg.ClientAuth)
+	return app.Listener(listener)
 }
 
-func ConnectDatabases() (db.ColonyAssetDB, db.LanguageDB, db.PlayerDB, error) {
-	colonyAssetDB, err := db.ConnectColonyAssetDB()
-	if err != nil {
-		return nil, nil, nil, err
+// On every SIGHUP, reloads the TLS certificate (if any) from disk and applies config changes to reloadable keys,
+// so renewed certificates and config changes are picked up without a restart
+func reloadOnSignal(certReloader *server.CertReloader, configReloader *reload.ConfigReloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		log.Println("[server] Received SIGHUP, reloading")
+		if certReloader != nil {
+			if err := certReloader.Reload(); err != nil {
+				log.Println("[server] Unable to reload TLS certificate, keeping the current one: " + err.Error())
+			}
+		}
+		if _, err := configReloader.Reload(); err != nil {
+			log.Println("[server] Config reload failed, nothing was applied: " + err.Error())
+		}
 	}
-	log.Println("[database] Successfully connected to Colony Asset DB")
+}
 
-	languageDB, err := db.ConnectLanguageDB()
-	if err != nil {
-		return nil, nil, nil, err
-	}
-	log.Println("[database] Successfully connected to Language DB")
+func listenHTTP(port int, app *fiber.App) error {
+	log.Println("[server] TLS DISABLED.")
+	return app.Listen(":" + strconv.Itoa(port))
+}
 
-	playerDB, err := db.ConnectPlayerDB()
-	if err != nil {
-		return nil, nil, nil, err
+// Connects to every database concurrently, see DB_MAX_TIMEOUT. If allowDegraded, databases that can't be reached
+// are marked degraded in the returned monitor (not yet started) instead of failing.
+func ConnectDatabases(cfg *config.Config, allowDegraded bool) (db.ColonyAssetDB, db.LanguageDB, db.PlayerDB, *db.Monitor, error) {
+	options := db.ConnectOptions{
+		Timeout: time.Duration(cfg.DBMaxTimeout) * time.Second,
+		Backoff: db.Backoff{Base: cfg.DBBackoffBase, Max: cfg.DBBackoffMax},
+	}
+	var colonyAssetDB, languageDB, playerDB *gorm.DB
+	var colonyAssetErr, languageErr, playerErr error
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		colonyAssetDB, colonyAssetErr = db.ConnectColonyAssetDB(cfg.ColonyAssetDB, options)
+	}()
+	go func() {
+		defer wg.Done()
+		languageDB, languageErr = db.ConnectLanguageDB(cfg.LanguageDB, options)
+	}()
+	go func() {
+		defer wg.Done()
+		playerDB, playerErr = db.ConnectPlayerDB(cfg.PlayerDB, options)
+	}()
+	wg.Wait()
+
+	monitor := db.NewMonitor(cfg.DBMonitorInterval, options.Backoff)
+	for _, result := range []struct {
+		name     string
+		database *gorm.DB
+		err      error
+	}{
+		{db.ColonyAssetDBName, colonyAssetDB, colonyAssetErr},
+		{db.LanguageDBName, languageDB, languageErr},
+		{db.PlayerDBName, playerDB, playerErr},
+	} {
+		if result.err == nil {
+			log.Printf("[database] Successfully connected to %s DB\n", result.name)
+		} else if allowDegraded && errors.Is(result.err, db.ErrUnavailable) {
+			log.Printf("[database] Starting with %s DB degraded, endpoints depending on it are unavailable until it is reachable: %s\n", result.name, result.err.Error())
+		} else {
+			return nil, nil, nil, nil, result.err
+		}
+		monitor.Watch(result.name, result.database, result.err == nil)
 	}
-	log.Println("[database] Successfully connected to Player DB")
 
-	return colonyAssetDB, languageDB, playerDB, nil
+	return colonyAssetDB, languageDB, playerDB, monitor, nil
 }
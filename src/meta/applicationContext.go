@@ -1,73 +1,119 @@
 package meta
 
 import (
-	"fmt"
 	"otte_main_backend/src/config"
 	db "otte_main_backend/src/database"
+	"otte_main_backend/src/repository"
 	"otte_main_backend/src/vitec"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
 )
 
 type ApplicationContext struct {
 	// The application context is a struct that holds all the necessary resources for the application to run.
-	ColonyAssetDB    db.ColonyAssetDB
-	LanguageDB       db.LanguageDB
-	PlayerDB         db.PlayerDB
+	ColonyAssetDB db.ColonyAssetDB
+	LanguageDB    db.LanguageDB
+	PlayerDB      db.PlayerDB
+	// Typed access to the databases above, used by handlers instead of querying them directly
+	repository.Repositories
+	// Whether each of the databases above is currently reachable. Nil if not monitored, in which case all are considered healthy.
+	DBMonitor        *db.Monitor
 	VitecIntegration *vitec.VitecIntegration
-	DDH              string
-	AuthTokenName    string
+	// Validated at startup and on every reload, see config.Load.
+	// Config, DDH and the multiplayer addresses may be replaced by a config reload, see Publish.
+	// Outside of a request, read them through Current, handlers are given a Snapshot.
+	Config        *config.Config
+	DDH           string
+	AuthTokenName string
 	// Address to use when accessed by main backend
 	InternalMultiplayerServerAddress string
 	// Address to use when accessed by anyone else
 	ExternalMultiplayerServerAddress string
+	// Latest values published by a config reload, nil until the first one
+	reloaded *atomic.Pointer[Reloadable]
 }
 
-// Returns: internal, external, error
-func getMPAddresses() (string, string, error) {
-	internalMPAddrHost, err := config.LoudGet("MULTIPLAYER_BACKEND_HOST_INTERNAL")
-	if err != nil {
-		return "", "", err
-	}
-	externalMPAddrHost, err := config.LoudGet("MULTIPLAYER_BACKEND_HOST_EXTERNAL")
-	if err != nil {
-		return "", "", err
-	}
-	internalMPAddrPort, err := config.GetInt("MULTIPLAYER_BACKEND_PORT_INTERNAL")
-	if err != nil {
-		return "", "", err
-	}
-	externalMPAddrPort, err := config.GetInt("MULTIPLAYER_BACKEND_PORT_EXTERNAL")
-	if err != nil {
-		return "", "", err
-	}
-	internalMPAddr := fmt.Sprintf("http://%s:%d", internalMPAddrHost, internalMPAddrPort)
-	externalMPAddr := fmt.Sprintf("http://%s:%d", externalMPAddrHost, externalMPAddrPort)
-	return internalMPAddr, externalMPAddr, nil
+// The values of the application context a config reload may replace, see ApplicationContext.Publish
+type Reloadable struct {
+	Config                           *config.Config
+	DDH                              string
+	InternalMultiplayerServerAddress string
+	ExternalMultiplayerServerAddress string
 }
 
-func CreateApplicationContext(colonyAssetDB db.ColonyAssetDB, languageDB db.LanguageDB, playerDB db.PlayerDB, vitecIntegration *vitec.VitecIntegration) (*ApplicationContext, error) {
-	internalMPAddr, externalMPAddr, err := getMPAddresses()
-	if err != nil {
-		return nil, err
-	}
-
-	authTokenName, err := config.LoudGet("AUTH_TOKEN_NAME")
-	if err != nil {
-		return nil, err
-	}
-
-	ddh, err := config.LoudGet("DEFAULT_DEBUG_HEADER")
-	if err != nil {
-		return nil, err
+func NewReloadable(cfg *config.Config) Reloadable {
+	return Reloadable{
+		Config:                           cfg,
+		DDH:                              cfg.DebugHeader,
+		InternalMultiplayerServerAddress: cfg.Multiplayer.InternalAddress(),
+		ExternalMultiplayerServerAddress: cfg.Multiplayer.ExternalAddress(),
 	}
+}
 
+func CreateApplicationContext(colonyAssetDB db.ColonyAssetDB, languageDB db.LanguageDB, playerDB db.PlayerDB, dbMonitor *db.Monitor, vitecIntegration *vitec.VitecIntegration, cfg *config.Config) (*ApplicationContext, error) {
+	reloadable := NewReloadable(cfg)
 	return &ApplicationContext{
 		ColonyAssetDB:                    colonyAssetDB,
 		LanguageDB:                       languageDB,
 		PlayerDB:                         playerDB,
+		Repositories:                     repository.NewPostgresRepositories(colonyAssetDB, languageDB, playerDB),
+		DBMonitor:                        dbMonitor,
 		VitecIntegration:                 vitecIntegration,
-		DDH:                              ddh,
-		AuthTokenName:                    authTokenName,
-		InternalMultiplayerServerAddress: internalMPAddr,
-		ExternalMultiplayerServerAddress: externalMPAddr,
+		Config:                           reloadable.Config,
+		DDH:                              reloadable.DDH,
+		AuthTokenName:                    cfg.AuthTokenName,
+		InternalMultiplayerServerAddress: reloadable.InternalMultiplayerServerAddress,
+		ExternalMultiplayerServerAddress: reloadable.ExternalMultiplayerServerAddress,
+		reloaded:                         &atomic.Pointer[Reloadable]{},
 	}, nil
 }
+
+// Replaces the reloadable values in one step. Requests already being handled keep the values of their Snapshot,
+// so a reload never waits for them, nor do new requests wait for the reload.
+func (appContext *ApplicationContext) Publish(next Reloadable) {
+	appContext.reloaded.Store(&next)
+}
+
+// The latest published reloadable values, or the ones the application context was created with
+func (appContext *ApplicationContext) Current() Reloadable {
+	if appContext.reloaded != nil {
+		if reloaded := appContext.reloaded.Load(); reloaded != nil {
+			return *reloaded
+		}
+	}
+	return Reloadable{
+		Config:                           appContext.Config,
+		DDH:                              appContext.DDH,
+		InternalMultiplayerServerAddress: appContext.InternalMultiplayerServerAddress,
+		ExternalMultiplayerServerAddress: appContext.ExternalMultiplayerServerAddress,
+	}
+}
+
+// Copy of the application context holding the latest reloadable values, which don't change while it is used
+func (appContext *ApplicationContext) Snapshot() *ApplicationContext {
+	current := appContext.Current()
+	snapshot := *appContext
+	snapshot.Config = current.Config
+	snapshot.DDH = current.DDH
+	snapshot.InternalMultiplayerServerAddress = current.InternalMultiplayerServerAddress
+	snapshot.ExternalMultiplayerServerAddress = current.ExternalMultiplayerServerAddress
+	return &snapshot
+}
+
+const snapshotLocal = "applicationContext"
+
+// Binds the snapshot to the request, so everything handling it reads the same values, see ForRequest
+func BindToRequest(c *fiber.Ctx, snapshot *ApplicationContext) {
+	c.Locals(snapshotLocal, snapshot)
+}
+
+// The snapshot bound to the request, taking one if none is bound yet
+func ForRequest(c *fiber.Ctx, appContext *ApplicationContext) *ApplicationContext {
+	if snapshot, ok := c.Locals(snapshotLocal).(*ApplicationContext); ok && snapshot != nil {
+		return snapshot
+	}
+	snapshot := appContext.Snapshot()
+	BindToRequest(c, snapshot)
+	return snapshot
+}
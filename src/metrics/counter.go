@@ -0,0 +1,44 @@
+package metrics
+
+import "sync"
+
+// Counts occurrences by label, e.g. requests timed out by route. Safe for concurrent use.
+type Counter struct {
+	lock   sync.Mutex
+	counts map[string]int64
+}
+
+type CounterSnapshot struct {
+	Total   int64            `json:"total"`
+	ByLabel map[string]int64 `json:"byLabel"`
+}
+
+// Requests answered with 504 as their deadline was exceeded, by route. See REQUEST_TIMEOUT_MS.
+var RequestTimeouts = &Counter{}
+
+func (c *Counter) Inc(label string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.counts == nil {
+		c.counts = map[string]int64{}
+	}
+	c.counts[label]++
+}
+
+// The count of a single label
+func (c *Counter) Get(label string) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts[label]
+}
+
+func (c *Counter) Snapshot() CounterSnapshot {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	snapshot := CounterSnapshot{ByLabel: make(map[string]int64, len(c.counts))}
+	for label, count := range c.counts {
+		snapshot.ByLabel[label] = count
+		snapshot.Total += count
+	}
+	return snapshot
+}
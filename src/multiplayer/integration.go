@@ -1,6 +1,7 @@
 package multiplayer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -38,19 +39,19 @@ type LobbyStateResponseDTO struct {
 	Clients  []ClientResponseDTO `json:"clients"`
 }
 
-// Returns lobbyID, error
-func CreateLobby(ownerID uint32, colonyID uint32, appContext *meta.ApplicationContext) (uint32, error) {
+// Returns lobbyID, error. Every call is abandoned once ctx is done, in addition to the client timeout.
+func CreateLobby(ctx context.Context, ownerID uint32, colonyID uint32, appContext *meta.ApplicationContext) (uint32, error) {
 	url := fmt.Sprintf("%s/create-lobby?ownerID=%d&encoding=binary&colonyID=%d", appContext.InternalMultiplayerServerAddress, ownerID, colonyID)
-	body, err := makeEmptyPostRequest(url)
+	body, err := makeEmptyPostRequest(ctx, url)
 	if err != nil {
 		return 0, fmt.Errorf("error creating lobby: %v", err)
 	}
 	return body.ID, nil
 }
 
-func CheckConnection(appContext *meta.ApplicationContext) *HealthCheckResponseDTO {
+func CheckConnection(ctx context.Context, appContext *meta.ApplicationContext) *HealthCheckResponseDTO {
 	url := fmt.Sprintf("%s/health", appContext.InternalMultiplayerServerAddress)
-	resp, err := makeGetRequest[HealthCheckResponseDTO](url)
+	resp, err := makeGetRequest[HealthCheckResponseDTO](ctx, url)
 
 	if err != nil {
 		return &HealthCheckResponseDTO{
@@ -62,17 +63,17 @@ func CheckConnection(appContext *meta.ApplicationContext) *HealthCheckResponseDT
 	return resp
 }
 
-func GetLobbyState(lobbyID uint32, appContext *meta.ApplicationContext) (*LobbyStateResponseDTO, error) {
+func GetLobbyState(ctx context.Context, lobbyID uint32, appContext *meta.ApplicationContext) (*LobbyStateResponseDTO, error) {
 	url := fmt.Sprintf("%s/lobby/%d", appContext.InternalMultiplayerServerAddress, lobbyID)
-	resp, err := makeGetRequest[LobbyStateResponseDTO](url)
+	resp, err := makeGetRequest[LobbyStateResponseDTO](ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("error getting lobby state: %v", err)
 	}
 	return resp, nil
 }
 
-func makeGetRequest[T any](url string) (*T, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func makeGetRequest[T any](ctx context.Context, url string) (*T, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error performing multiplayer backend healthcheck: %v", err)
 	}
@@ -95,9 +96,9 @@ func makeGetRequest[T any](url string) (*T, error) {
 	return &dest, nil
 }
 
-func makeEmptyPostRequest(url string) (*CreateLobbyResponseDTO, error) {
+func makeEmptyPostRequest(ctx context.Context, url string) (*CreateLobbyResponseDTO, error) {
 	// Create a new POST request with no body
-	req, err := http.NewRequest("POST", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %v", err)
 	}
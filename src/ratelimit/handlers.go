@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"math"
+	"otte_main_backend/src/api/local"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/meta"
+	"otte_main_backend/src/middleware"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Derives the bucket key from the request
+type KeyFunc func(c *fiber.Ctx) string
+
+func ByIP(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+// Falls back to the IP if no session has been placed in the locals (yet)
+func BySession(c *fiber.Ctx) string {
+	if session, ok := c.Locals(local.Session).(*auth.Session); ok && session != nil {
+		return "player:" + strconv.FormatUint(uint64(session.Player), 10)
+	}
+	return ByIP(c)
+}
+
+// All requests to the route share a single bucket
+func ByRoute(c *fiber.Ctx) string {
+	return "route:" + c.Route().Path
+}
+
+var ErrorTooManyRequests *fiber.Error = fiber.NewError(fiber.StatusTooManyRequests, "Too many requests")
+
+// Sets the Retry-After header (whole seconds, rounded up) and returns a 429
+func RejectWithRetryAfter(c *fiber.Ctx, appContext *meta.ApplicationContext, retryAfter time.Duration, reason string) *fiber.Error {
+	c.Response().Header.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.Response().Header.Set(appContext.DDH, reason)
+	return ErrorTooManyRequests
+}
+
+// Route middleware, for limiting before the request is authenticated:
+//
+//	app.Post(path, limiter.Middleware(appContext, ratelimit.ByIP), handler)
+func (l *Limiter) Middleware(appContext *meta.ApplicationContext, key KeyFunc) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if l == nil {
+			return c.Next()
+		}
+		if allowed, retryAfter := l.Allow(key(c), time.Now()); !allowed {
+			err := RejectWithRetryAfter(c, meta.ForRequest(c, appContext), retryAfter, "Rate limit "+l.Name+" exceeded")
+			c.Status(err.Code)
+			middleware.LogRequests(c)
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// Wraps a handler, for limiting after the request is authenticated, e.g. per session:
+//
+//	auth.PrefixOn(appContext, limiter.Wrap(ratelimit.BySession, handler))
+func (l *Limiter) Wrap(key KeyFunc, existingHandler func(c *fiber.Ctx, appContext *meta.ApplicationContext) error) func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+	return func(c *fiber.Ctx, appContext *meta.ApplicationContext) error {
+		if l == nil {
+			return existingHandler(c, appContext)
+		}
+		if allowed, retryAfter := l.Allow(key(c), time.Now()); !allowed {
+			return RejectWithRetryAfter(c, appContext, retryAfter, "Rate limit "+l.Name+" exceeded")
+		}
+		return existingHandler(c, appContext)
+	}
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"otte_main_backend/src/config"
+	"sync"
+	"time"
+)
+
+// How often buckets that have refilled completely are dropped, to keep memory bounded
+const SWEEP_INTERVAL = time.Minute
+
+// Capacity tokens are available at once (burst), and the bucket refills at Capacity per Period
+type LimitSpec struct {
+	Capacity float64
+	Period   time.Duration
+}
+
+func (spec LimitSpec) String() string {
+	return fmt.Sprintf("%d/%ds", int(spec.Capacity), int(spec.Period.Seconds()))
+}
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// In-memory token buckets, one per key.
+// A nil Limiter allows everything when used through Middleware or Wrap.
+type Limiter struct {
+	Name      string
+	spec      LimitSpec
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(name string, spec LimitSpec) *Limiter {
+	return &Limiter{
+		Name:      name,
+		spec:      spec,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Returns nil (no limiting) if rate limiting is disabled, see config.RateLimitConfig
+func NewLimiterFromConfig(name string, cfg config.RateLimitConfig, limit config.RateLimit) *Limiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	spec := LimitSpec{Capacity: float64(limit.Requests), Period: limit.Period}
+	log.Printf("[ratelimit] Limit %s set to %s\n", name, spec)
+	return NewLimiter(name, spec)
+}
+
+// Takes a token for the key if available.
+// If not, returns false and how long until a token is available.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.lastSweep) > SWEEP_INTERVAL {
+		l.sweepLocked(now)
+	}
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.spec.Capacity, lastRefill: now}
+		l.buckets[key] = b
+	}
+	refillPerSecond := l.spec.Capacity / l.spec.Period.Seconds()
+	b.tokens = math.Min(l.spec.Capacity, b.tokens+now.Sub(b.lastRefill).Seconds()*refillPerSecond)
+	b.lastRefill = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	secondsUntilToken := (1 - b.tokens) / refillPerSecond
+	return false, time.Duration(secondsUntilToken * float64(time.Second))
+}
+
+func (l *Limiter) sweepLocked(now time.Time) {
+	refillPerSecond := l.spec.Capacity / l.spec.Period.Seconds()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.lastRefill).Seconds()*refillPerSecond >= l.spec.Capacity {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
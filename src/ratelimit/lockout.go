@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"log"
+	"otte_main_backend/src/config"
+	"sync"
+	"time"
+)
+
+type LockoutSpec struct {
+	//Failures allowed before locking out
+	Threshold int
+	//Duration of the first lockout. Doubles with each subsequent lockout.
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	//Failures older than this are forgotten, and so is the escalation
+	Window time.Duration
+}
+
+type lockoutState struct {
+	failures    int
+	lockouts    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Tracks failed attempts per key and locks the key out for escalating durations.
+// A nil Lockout never locks anything out.
+type Lockout struct {
+	Name   string
+	spec   LockoutSpec
+	lock   sync.Mutex
+	states map[string]*lockoutState
+}
+
+func NewLockout(name string, spec LockoutSpec) *Lockout {
+	return &Lockout{Name: name, spec: spec, states: map[string]*lockoutState{}}
+}
+
+// Returns nil (no lockouts) if rate limiting is disabled, see config.RateLimitConfig
+func NewLockoutFromConfig(name string, cfg config.RateLimitConfig, lockout config.LockoutConfig) *Lockout {
+	if !cfg.Enabled {
+		return nil
+	}
+	spec := LockoutSpec{
+		Threshold:    lockout.Threshold,
+		BaseDuration: lockout.BaseDuration,
+		MaxDuration:  lockout.MaxDuration,
+		Window:       lockout.Window,
+	}
+	log.Printf("[ratelimit] Lockout %s after %d failures, for %s up to %s\n", name, spec.Threshold, spec.BaseDuration, spec.MaxDuration)
+	return NewLockout(name, spec)
+}
+
+// Returns whether the key is locked out, and if so for how long still
+func (l *Lockout) IsLocked(key string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return false, 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	state, exists := l.states[key]
+	if !exists || !now.Before(state.lockedUntil) {
+		return false, 0
+	}
+	return true, state.lockedUntil.Sub(now)
+}
+
+// Registers a failed attempt. Returns the lockout duration if this failure caused a lockout.
+func (l *Lockout) RegisterFailure(key string, now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.sweepLocked(now)
+
+	state, exists := l.states[key]
+	if !exists {
+		state = &lockoutState{}
+		l.states[key] = state
+	}
+	state.failures++
+	state.lastFailure = now
+	if state.failures < l.spec.Threshold {
+		return 0
+	}
+
+	duration := l.spec.BaseDuration << state.lockouts
+	if duration > l.spec.MaxDuration || duration <= 0 {
+		duration = l.spec.MaxDuration
+	}
+	state.lockouts++
+	state.failures = 0
+	state.lockedUntil = now.Add(duration)
+	log.Printf("[ratelimit] %s locked out %s for %s\n", l.Name, key, duration)
+	return duration
+}
+
+// Forgets failures and escalation for the key
+func (l *Lockout) RegisterSuccess(key string) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.states, key)
+}
+
+func (l *Lockout) sweepLocked(now time.Time) {
+	for key, state := range l.states {
+		if now.After(state.lockedUntil) && now.Sub(state.lastFailure) > l.spec.Window {
+			delete(l.states, key)
+		}
+	}
+}
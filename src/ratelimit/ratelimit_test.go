@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	limiter := NewLimiter("test", LimitSpec{Capacity: 2, Period: 2 * time.Second})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("a", now); !allowed {
+			t.Fatalf("request %d within burst was not allowed", i)
+		}
+	}
+	allowed, retryAfter := limiter.Allow("a", now)
+	if allowed {
+		t.Fatal("request exceeding burst was allowed")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("unexpected retry after: %s", retryAfter)
+	}
+	if allowed, _ := limiter.Allow("b", now); !allowed {
+		t.Error("keys are expected to have separate buckets")
+	}
+	if allowed, _ := limiter.Allow("a", now.Add(time.Second)); !allowed {
+		t.Error("request after refill was not allowed")
+	}
+}
+
+func TestLockoutEscalates(t *testing.T) {
+	lockout := NewLockout("test", LockoutSpec{Threshold: 2, BaseDuration: time.Second, MaxDuration: 3 * time.Second, Window: time.Minute})
+	now := time.Now()
+
+	if lockedFor := lockout.RegisterFailure("a", now); lockedFor != 0 {
+		t.Fatal("locked out before reaching threshold")
+	}
+	if lockedFor := lockout.RegisterFailure("a", now); lockedFor != time.Second {
+		t.Fatalf("unexpected first lockout: %s", lockedFor)
+	}
+	if locked, _ := lockout.IsLocked("a", now); !locked {
+		t.Fatal("expected key to be locked")
+	}
+
+	now = now.Add(2 * time.Second)
+	if locked, _ := lockout.IsLocked("a", now); locked {
+		t.Fatal("expected lockout to have ended")
+	}
+	lockout.RegisterFailure("a", now)
+	if lockedFor := lockout.RegisterFailure("a", now); lockedFor != 2*time.Second {
+		t.Fatalf("expected second lockout to double, got: %s", lockedFor)
+	}
+
+	now = now.Add(3 * time.Second)
+	lockout.RegisterFailure("a", now)
+	if lockedFor := lockout.RegisterFailure("a", now); lockedFor != 3*time.Second {
+		t.Fatalf("expected lockout to be capped, got: %s", lockedFor)
+	}
+
+	lockout.RegisterSuccess("a")
+	if locked, _ := lockout.IsLocked("a", now); locked {
+		t.Error("expected success to clear the lockout")
+	}
+}
@@ -0,0 +1,108 @@
+package reload
+
+import (
+	"fmt"
+	"log"
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/database"
+	"otte_main_backend/src/meta"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type ReloadResultDTO struct {
+	// Changed keys that were applied
+	Applied []string `json:"applied"`
+	// Changed keys that require a restart. If any, nothing is applied.
+	Rejected []string `json:"rejected"`
+	// Why nothing was applied, if so
+	Error string `json:"error,omitempty"`
+}
+
+// Applies config changes to the running application, for keys marked reloadable, see config.IsReloadable
+type ConfigReloader struct {
+	appContext  *meta.ApplicationContext
+	authService *auth.AuthService
+	//One reload at a time
+	lock sync.Mutex
+}
+
+func NewConfigReloader(appContext *meta.ApplicationContext, authService *auth.AuthService) *ConfigReloader {
+	return &ConfigReloader{appContext: appContext, authService: authService}
+}
+
+// Re-reads every config source and applies the changes, see Apply
+func (r *ConfigReloader) Reload() (*ReloadResultDTO, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	next, err := config.ReloadFromSources()
+	if err != nil {
+		return nil, err
+	}
+	return r.apply(next)
+}
+
+// Applies every changed key of the given config, or none of them if any changed key is not reloadable
+func (r *ConfigReloader) Apply(next *config.Config) (*ReloadResultDTO, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.apply(next)
+}
+
+// Everything derived from the config is built before anything is published, so a failing reload changes nothing.
+// Requests already being handled keep the values they arrived with, see meta.ApplicationContext.Snapshot
+func (r *ConfigReloader) apply(next *config.Config) (*ReloadResultDTO, error) {
+	current := r.appContext.Current().Config
+	result := &ReloadResultDTO{Applied: []string{}, Rejected: []string{}}
+	for _, key := range current.Diff(next) {
+		if config.IsReloadable(key) {
+			result.Applied = append(result.Applied, key)
+		} else {
+			result.Rejected = append(result.Rejected, key)
+		}
+	}
+	if len(result.Rejected) > 0 {
+		applied := result.Applied
+		result.Applied = []string{}
+		return result, fmt.Errorf("changes to %s require a restart, not applying %s either",
+			strings.Join(result.Rejected, ", "), strings.Join(applied, ", "))
+	}
+	if len(result.Applied) == 0 {
+		log.Println("[config] Reload found no changes")
+		return result, nil
+	}
+
+	//Built first, as it is the only step that can fail
+	var nextAuthMode *auth.AuthMode
+	if next.Auth.Level != current.Auth.Level ||
+		next.Auth.SignedTokenKeys != current.Auth.SignedTokenKeys ||
+		next.Auth.SignedTokenValidDuration != current.Auth.SignedTokenValidDuration {
+		mode, err := auth.NewAuthMode(r.authService, next.Auth)
+		if err != nil {
+			result.Rejected, result.Applied = result.Applied, []string{}
+			return result, err
+		}
+		nextAuthMode = mode
+	}
+
+	r.appContext.Publish(meta.NewReloadable(next))
+	if nextAuthMode != nil {
+		r.authService.UseMode(nextAuthMode)
+	}
+	setLoggingLoudness("Player DB", r.appContext.PlayerDB, next.PlayerDB.LoggingLevel)
+	setLoggingLoudness("Colony Asset DB", r.appContext.ColonyAssetDB, next.ColonyAssetDB.LoggingLevel)
+	setLoggingLoudness("Language DB", r.appContext.LanguageDB, next.LanguageDB.LoggingLevel)
+
+	log.Println("[config] Reloaded: " + strings.Join(result.Applied, ", "))
+	return result, nil
+}
+
+func setLoggingLoudness(name string, db *gorm.DB, level string) {
+	if err := database.SetLoggingLoudness(db, database.DBLoggingLoudness(level)); err != nil {
+		log.Printf("[config] Unable to set logging level of %s: %s\n", name, err.Error())
+	}
+}
@@ -0,0 +1,119 @@
+package reload
+
+import (
+	"otte_main_backend/src/auth"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/meta"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setEnv(t *testing.T) {
+	env := map[string]string{
+		"SERVICE_PORT":                      "8080",
+		"AUTH_TOKEN_NAME":                   "URSA-Token",
+		"DEFAULT_DEBUG_HEADER":              "URSA-DDH",
+		"SESSION_TOKEN_HASH_KEY":            "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=",
+		"INTERNAL_AUTH_LEVEL":               "strict",
+		"MULTIPLAYER_BACKEND_HOST_INTERNAL": "localhost",
+		"MULTIPLAYER_BACKEND_PORT_INTERNAL": "5000",
+		"MULTIPLAYER_BACKEND_HOST_EXTERNAL": "localhost",
+		"MULTIPLAYER_BACKEND_PORT_EXTERNAL": "5001",
+		"VITEC_CROSS_VERIFICATION":          "never",
+		"ENABLE_TLS":                        "false",
+	}
+	for _, prefix := range []string{"PLAYER_DB", "COLONY_ASSET_DB", "LANGUAGE_DB"} {
+		env[prefix+"_HOST"] = "localhost"
+		env[prefix+"_PORT"] = "5432"
+		env[prefix+"_NAME"] = strings.ToLower(prefix)
+		env[prefix+"_USERNAME"] = "otte"
+		env[prefix+"_PASSWORD"] = "hunter2"
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func mockDB(t *testing.T) *gorm.DB {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gormDB
+}
+
+func newReloader(t *testing.T) (*ConfigReloader, *meta.ApplicationContext, *auth.AuthService) {
+	setEnv(t)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	appContext, _ := meta.CreateApplicationContext(mockDB(t), mockDB(t), mockDB(t), nil, nil, cfg)
+	authService := &auth.AuthService{}
+	if err := auth.ApplyAuthConfig(authService, cfg.Auth); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	return NewConfigReloader(appContext, authService), appContext, authService
+}
+
+func TestApplyReloadableKeys(t *testing.T) {
+	reloader, appContext, authService := newReloader(t)
+	t.Setenv("INTERNAL_AUTH_LEVEL", "naive")
+	t.Setenv("DEFAULT_DEBUG_HEADER", "OTTE-DDH")
+	t.Setenv("MULTIPLAYER_BACKEND_PORT_EXTERNAL", "6001")
+	next, err := config.Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	inFlight := appContext.Snapshot()
+	result, err := reloader.Apply(next)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(result.Applied) != 3 || len(result.Rejected) != 0 {
+		t.Errorf("expected 3 applied keys, got: %+v", result)
+	}
+	published := appContext.Current()
+	if authService.Mode().Level != auth.AuthLevelNaive || published.DDH != "OTTE-DDH" || published.ExternalMultiplayerServerAddress != "http://localhost:6001" {
+		t.Errorf("expected changes to be applied, got level %s, DDH %s, address %s", authService.Mode().Level, published.DDH, published.ExternalMultiplayerServerAddress)
+	}
+	if published.Config != next || appContext.Snapshot().DDH != "OTTE-DDH" {
+		t.Error("expected the new config to replace the current one")
+	}
+	//Requests already being handled keep the values they arrived with
+	if inFlight.DDH == "OTTE-DDH" || inFlight.Config == next {
+		t.Error("expected the reload to leave snapshots taken before it alone")
+	}
+}
+
+func TestRejectNonReloadableKeys(t *testing.T) {
+	reloader, appContext, authService := newReloader(t)
+	current := appContext.Config
+	t.Setenv("INTERNAL_AUTH_LEVEL", "naive")
+	t.Setenv("SERVICE_PORT", "9090")
+	next, err := config.Load()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	result, err := reloader.Apply(next)
+	if err == nil {
+		t.Fatal("expected reload changing SERVICE_PORT to be rejected")
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0] != "SERVICE_PORT" || len(result.Applied) != 0 {
+		t.Errorf("expected only SERVICE_PORT to be rejected and nothing applied, got: %+v", result)
+	}
+	if authService.Mode().Level != auth.AuthLevelStrict || appContext.Current().Config != current {
+		t.Error("expected nothing to be applied")
+	}
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type Asset struct {
+	ID      uint32 `json:"id"`
+	UseCase string `json:"useCase" gorm:"column:useCase"`
+	Type    string `json:"type"`
+	Width   uint32 `json:"width"`
+	Height  uint32 `json:"height"`
+	Alias   string `json:"alias"`
+	// Without blobs
+	LODs []LODDetails `json:"LODs" gorm:"foreignKey:GraphicalAsset;references:ID"`
+}
+
+func (a *Asset) TableName() string {
+	return "GraphicalAsset"
+}
+
+type LODDetails struct {
+	ID             uint32 `json:"id"`
+	DetailLevel    uint32 `json:"detailLevel" gorm:"column:detailLevel"`
+	GraphicalAsset uint32 `json:"graphicalAsset" gorm:"column:graphicalAsset"`
+}
+
+func (l LODDetails) TableName() string {
+	return "LOD"
+}
+
+type LOD struct {
+	ID             uint32 `json:"id"`
+	DetailLevel    uint32 `json:"detailLevel" gorm:"column:detailLevel"`
+	GraphicalAsset uint32 `json:"graphicalAsset" gorm:"column:graphicalAsset"`
+	Blob           []byte `json:"blob" gorm:"column:blob"`
+	ETag           string `json:"etag" gorm:"column:etag"`
+	MIMEType       string `json:"type" gorm:"column:type"`
+}
+
+func (lod *LOD) TableName() string {
+	//Gorm would otherwise overwrite the "LOD" to "lo_d" or smth like that
+	return "LOD"
+}
+
+// One LOD of one entry of an asset collection, flattened. Entries of assets without LODs have LODID 0.
+type CollectionEntryRow struct {
+	AssetCollectionID uint32  `gorm:"column:assetCollectionId"`
+	CollectionName    string  `gorm:"column:collectionName"`
+	CollectionEntryID uint32  `gorm:"column:collectionEntryId"`
+	GraphicalAssetID  uint32  `gorm:"column:graphicalAssetId"`
+	Width             int     `gorm:"column:width"`
+	Height            int     `gorm:"column:height"`
+	Alias             string  `gorm:"column:alias"`
+	Type              string  `gorm:"column:type"`
+	XOffset           float32 `gorm:"column:xOffset"`
+	YOffset           float32 `gorm:"column:yOffset"`
+	ZIndex            uint32  `gorm:"column:zIndex"`
+	XScale            float32 `gorm:"column:xScale"`
+	YScale            float32 `gorm:"column:yScale"`
+	LODID             uint32  `gorm:"column:lodId"`
+	DetailLevel       int     `gorm:"column:detailLevel"`
+}
+
+type AssetRepository interface {
+	FindByID(ctx context.Context, id uint32) (*Asset, error)
+	// Assets not found are left out
+	FindByIDs(ctx context.Context, ids []uint32) ([]Asset, error)
+	FindLOD(ctx context.Context, id uint32) (*LOD, error)
+	FindLODByDetailLevel(ctx context.Context, assetID uint32, detailLevel uint32) (*LOD, error)
+	// Empty if the collection doesn't exist or has no entries
+	FindCollectionEntries(ctx context.Context, collectionID uint32) ([]CollectionEntryRow, error)
+	CollectionExists(ctx context.Context, collectionID uint32) (bool, error)
+}
+
+type postgresAssetRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresAssetRepository(colonyAssetDB *gorm.DB) AssetRepository {
+	return &postgresAssetRepository{db: colonyAssetDB}
+}
+
+func (r *postgresAssetRepository) FindByID(ctx context.Context, id uint32) (*Asset, error) {
+	var asset Asset
+	if err := r.db.WithContext(ctx).
+		Preload("LODs"). // Preload the LODs field using the foreign key
+		Where(`"GraphicalAsset".id = ?`, id).
+		First(&asset).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &asset, nil
+}
+
+func (r *postgresAssetRepository) FindByIDs(ctx context.Context, ids []uint32) ([]Asset, error) {
+	var assets []Asset
+	if err := r.db.WithContext(ctx).
+		Preload("LODs").
+		Where(`"GraphicalAsset".id IN ?`, ids).
+		Find(&assets).Error; err != nil {
+		return nil, translate(err)
+	}
+	return assets, nil
+}
+
+func (r *postgresAssetRepository) FindLOD(ctx context.Context, id uint32) (*LOD, error) {
+	var lod LOD
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&lod).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &lod, nil
+}
+
+func (r *postgresAssetRepository) FindLODByDetailLevel(ctx context.Context, assetID uint32, detailLevel uint32) (*LOD, error) {
+	var lod LOD
+	if err := r.db.WithContext(ctx).
+		Where(`"LOD"."graphicalAsset" = ? AND "LOD"."detailLevel" = ?`, assetID, detailLevel).
+		First(&lod).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &lod, nil
+}
+
+const collectionQuery = `
+SELECT
+	ac.id AS "assetCollectionId",
+	ac.name AS "collectionName",
+	ce.id AS "collectionEntryId",
+	ga.id AS "graphicalAssetId",
+	ga.width AS "width",
+	ga.height AS "height",
+	ga.alias AS "alias",
+	ga.type AS "type",
+	t."xOffset" AS "xOffset",
+	t."yOffset" AS "yOffset",
+	t."zIndex" AS "zIndex",
+	t."xScale" AS "xScale",
+	t."yScale" AS "yScale",
+	lod.id AS "lodId",
+	lod."detailLevel" AS "detailLevel"
+FROM
+	"AssetCollection" ac
+JOIN
+	"CollectionEntry" ce ON ce."assetCollection" = ac.id
+JOIN
+	"Transform" t ON t.id = ce.transform
+JOIN
+	"GraphicalAsset" ga ON ga.id = ce."graphicalAsset"
+LEFT JOIN
+	"LOD" lod ON lod."graphicalAsset" = ga.id
+WHERE
+	ac.id = ?`
+
+func (r *postgresAssetRepository) FindCollectionEntries(ctx context.Context, collectionID uint32) ([]CollectionEntryRow, error) {
+	var rows []CollectionEntryRow
+	if err := r.db.WithContext(ctx).Raw(collectionQuery, collectionID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *postgresAssetRepository) CollectionExists(ctx context.Context, collectionID uint32) (bool, error) {
+	var exists bool
+	if err := r.db.WithContext(ctx).
+		Raw(`SELECT EXISTS (SELECT 1 FROM "AssetCollection" WHERE id = ?)`, collectionID).
+		Scan(&exists).Error; err != nil {
+		return false, err
+	}
+	return exists, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type AvailableLanguage struct {
+	ID         uint32  `json:"id" gorm:"column:id;primaryKey"`
+	Coverage   float32 `json:"coverage" gorm:"column:coverage"`
+	CommonName string  `json:"commonName" gorm:"column:commonName"`
+	Code       string  `json:"code" gorm:"column:code"`
+	Icon       uint32  `json:"icon" gorm:"column:icon"`
+}
+
+func (a *AvailableLanguage) TableName() string {
+	return "AvailableLanguages"
+}
+
+type CatalogueEntry struct {
+	Key   string
+	Value string
+}
+
+type CatalogueRepository interface {
+	FindLanguages(ctx context.Context) ([]AvailableLanguage, error)
+	// The entries of the language, limited to the given keys if any. Keys not in the catalogue are left out.
+	FindEntries(ctx context.Context, language string, keys []string) ([]CatalogueEntry, error)
+}
+
+type postgresCatalogueRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresCatalogueRepository(languageDB *gorm.DB) CatalogueRepository {
+	return &postgresCatalogueRepository{db: languageDB}
+}
+
+func (r *postgresCatalogueRepository) FindLanguages(ctx context.Context) ([]AvailableLanguage, error) {
+	var languages []AvailableLanguage
+	if err := r.db.WithContext(ctx).Find(&languages).Error; err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+func (r *postgresCatalogueRepository) FindEntries(ctx context.Context, language string, keys []string) ([]CatalogueEntry, error) {
+	//Each language is a column, so the language is quoted as an identifier as it can't be a query parameter.
+	//The alias is needed as GORM matches on the struct field name, which is dynamic in this case.
+	query := r.db.WithContext(ctx).
+		Table("Catalogue").
+		Select(`key, "` + strings.ReplaceAll(language, `"`, `""`) + `" AS value`)
+	if len(keys) > 0 {
+		query = query.Where("key IN ?", keys)
+	}
+	var entries []CatalogueEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, translate(err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,435 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"otte_main_backend/src/repository/colony"
+	"otte_main_backend/src/util"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Returned by ColonyRepository.AttachCode when the value of the code is already in use
+var ErrDuplicateCode = errors.New("duplicate colony code")
+
+type Colony struct {
+	ID          uint32 `gorm:"primaryKey"`
+	Name        string
+	AccLevel    uint32 `gorm:"column:accLevel"`
+	LatestVisit string `gorm:"column:latestVisit"`
+	ColonyCode  uint32 `gorm:"foreignKey:ColonyCode;references:ID;column:colonyCode"`
+	Owner       uint32
+	// IDs of ColonyAssets
+	Assets util.PGIntArray
+	// IDs of ColonyLocations
+	Locations util.PGIntArray
+}
+
+func (c *Colony) TableName() string {
+	return "Colony"
+}
+
+// A join code for a colony opened for multiplayer. A colony has at most one active code, see Colony.ColonyCode.
+type ColonyCode struct {
+	ID              uint32    `gorm:"column:id;primaryKey"`
+	LobbyID         uint32    `gorm:"column:lobbyId"`
+	ServerAddress   string    `gorm:"column:serverAddress"`
+	ColonyID        uint32    `gorm:"column:colony"`
+	Value           string    `gorm:"column:value"`
+	OwnerID         uint32    `gorm:"column:owner"`
+	CreatedAt       time.Time `gorm:"column:createdAt"`
+	ValidDurationMS uint32    `gorm:"column:validDurationMS"`
+}
+
+func (ColonyCode) TableName() string {
+	return "ColonyCode"
+}
+
+func (code *ColonyCode) ExpiredAt(now time.Time) bool {
+	return code.CreatedAt.Add(time.Duration(code.ValidDurationMS) * time.Millisecond).Before(now)
+}
+
+type ColonyLocation struct {
+	ID        uint32 `gorm:"primaryKey"`
+	Level     uint32
+	Colony    uint32 `gorm:"foreignKey:Colony;references:ID"`
+	Transform uint32 `gorm:"foreignKey:Transform;references:ID"`
+	Location  uint32 `gorm:"foreignKey:Location;references:ID"`
+}
+
+func (a *ColonyLocation) TableName() string {
+	return "ColonyLocation"
+}
+
+type ColonyAsset struct {
+	ID              uint32 `gorm:"primaryKey"`
+	AssetCollection uint32 `gorm:"column:assetCollection"`
+	Transform       uint32 `gorm:"column:transform"`
+	Colony          uint32 `gorm:"column:colony"`
+}
+
+func (a *ColonyAsset) TableName() string {
+	return "ColonyAsset"
+}
+
+type Transform struct {
+	ID      uint32  `gorm:"column:id;primaryKey"`
+	XScale  float32 `gorm:"column:xScale"`
+	YScale  float32 `gorm:"column:yScale"`
+	XOffset float32 `gorm:"column:xOffset"`
+	YOffset float32 `gorm:"column:yOffset"`
+	ZIndex  uint32  `gorm:"column:zIndex"`
+}
+
+func (t *Transform) TableName() string {
+	return "Transform"
+}
+
+// An edge of the path graph of a colony, between two ColonyLocations
+type ColonyPath struct {
+	From uint32 `json:"from" gorm:"column:locationA"` //Id of ColonyLocation
+	To   uint32 `json:"to" gorm:"column:locationB"`   //Id of ColonyLocation
+}
+
+func (p *ColonyPath) TableName() string {
+	return "ColonyLocationPath"
+}
+
+// A location of the world, of which every colony has its own ColonyLocation
+type Location struct {
+	ID          uint32               `gorm:"column:id;primaryKey"`
+	Name        string               `gorm:"column:name"`
+	Description string               `gorm:"column:description"`
+	MinigameID  uint32               `gorm:"column:minigame"`
+	Minigame    Minigame             `gorm:"foreignKey:MinigameID"`
+	Appearances []LocationAppearance `gorm:"foreignKey:LocationID"`
+}
+
+func (l *Location) TableName() string {
+	return "Location"
+}
+
+// How a location looks at some level
+type LocationAppearance struct {
+	ID                uint32          `gorm:"column:id;primaryKey"`
+	Level             int             `gorm:"column:level"`
+	LocationID        uint32          `gorm:"column:location"`
+	SplashArt         uint32          `gorm:"column:splashArt"`
+	AssetCollectionID uint32          `gorm:"column:assetCollection"`
+	AssetCollection   AssetCollection `gorm:"foreignKey:AssetCollectionID"`
+}
+
+func (l *LocationAppearance) TableName() string {
+	return "LocationAppearance"
+}
+
+type AssetCollection struct {
+	ID                uint32            `gorm:"column:id;primaryKey"`
+	Name              string            `gorm:"column:name"`
+	UseCase           string            `gorm:"column:useCase"`
+	CollectionEntries []CollectionEntry `gorm:"foreignKey:AssetCollectionID"`
+}
+
+func (a *AssetCollection) TableName() string {
+	return "AssetCollection"
+}
+
+type CollectionEntry struct {
+	ID                uint32          `gorm:"column:id;primaryKey"`
+	GraphicalAssetID  uint32          `gorm:"column:graphicalAsset"`
+	GraphicalAsset    CollectionAsset `gorm:"foreignKey:GraphicalAssetID"`
+	TransformID       uint32          `gorm:"column:transform"`
+	Transform         Transform       `gorm:"foreignKey:TransformID"`
+	AssetCollectionID uint32          `gorm:"column:assetCollection"`
+}
+
+func (c *CollectionEntry) TableName() string {
+	return "CollectionEntry"
+}
+
+// A GraphicalAsset as part of a collection, without blobs
+type CollectionAsset struct {
+	ID      uint32       `gorm:"column:id;primaryKey"`
+	Alias   string       `gorm:"column:alias"`
+	Type    string       `gorm:"column:type"`
+	UseCase string       `gorm:"column:useCase"`
+	Width   int          `gorm:"column:width"`
+	Height  int          `gorm:"column:height"`
+	LODs    []LODDetails `gorm:"foreignKey:GraphicalAsset"`
+}
+
+func (c *CollectionAsset) TableName() string {
+	return "GraphicalAsset"
+}
+
+type ColonyRepository interface {
+	FindByID(ctx context.Context, id uint32) (*Colony, error)
+	FindByOwner(ctx context.Context, owner uint32) ([]Colony, error)
+	// Creates the colony along with its locations, paths and assets, see the colony package.
+	// Sets the ID, Locations and Assets of the colony.
+	Create(ctx context.Context, colony *Colony) error
+	// Deletes the colonies owned by the player along with everything created with them:
+	// their codes, paths, locations, assets and the transforms of the latter two. Returns the amount of colonies deleted.
+	DeleteByOwner(ctx context.Context, owner uint32) (int, error)
+	FindLocation(ctx context.Context, id uint32) (*ColonyLocation, error)
+	FindAsset(ctx context.Context, id uint32) (*ColonyAsset, error)
+	FindTransform(ctx context.Context, id uint32) (*Transform, error)
+	// Increments the level of the location, if it belongs to the colony. Returns the upgraded location.
+	UpgradeLocation(ctx context.Context, colonyID uint32, colonyLocationID uint32) (*ColonyLocation, error)
+	FindPaths(ctx context.Context, colonyID uint32) ([]ColonyPath, error)
+	UpdateLatestVisit(ctx context.Context, id uint32, latestVisit string) error
+	FindCode(ctx context.Context, id uint32) (*ColonyCode, error)
+	// Any code with the value, expired or not
+	FindCodeByValue(ctx context.Context, value string) (*ColonyCode, error)
+	DeleteCode(ctx context.Context, id uint32) error
+	// Creates the code, sets its ID and makes it the code of its colony. Returns ErrDuplicateCode if the value is in use.
+	AttachCode(ctx context.Context, code *ColonyCode) error
+	// Removes the code of the colony owned by the player along with every code for the colony.
+	// Returns ErrNotFound if the player doesn't own the colony.
+	DetachCodes(ctx context.Context, colonyID uint32, owner uint32) error
+	// Including its appearances, but not their asset collections, nor the minigame
+	FindWorldLocation(ctx context.Context, id uint32) (*Location, error)
+	// Including the minigame with its difficulties, and the appearances with their asset collections
+	FindWorldLocationFull(ctx context.Context, id uint32) (*Location, error)
+}
+
+type postgresColonyRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresColonyRepository(colonyAssetDB *gorm.DB) ColonyRepository {
+	return &postgresColonyRepository{db: colonyAssetDB}
+}
+
+func (r *postgresColonyRepository) FindByID(ctx context.Context, id uint32) (*Colony, error) {
+	var colony Colony
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&colony).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &colony, nil
+}
+
+func (r *postgresColonyRepository) FindByOwner(ctx context.Context, owner uint32) ([]Colony, error) {
+	var colonies []Colony
+	if err := r.db.WithContext(ctx).Where("owner = ?", owner).Find(&colonies).Error; err != nil {
+		return nil, err
+	}
+	return colonies, nil
+}
+
+func (r *postgresColonyRepository) Create(ctx context.Context, newColony *Colony) error {
+	newColony.Assets = util.PGIntArray{}
+	newColony.Locations = util.PGIntArray{}
+	// Committed on its own, so the rows generated next can refer to it
+	if err := r.db.WithContext(ctx).Create(newColony).Error; err != nil {
+		return err
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transformIDs, err, boundingBox := colony.InsertLocationTransforms(tx)
+		if err != nil {
+			return fmt.Errorf("error inserting transforms: %w", err)
+		}
+		locationIDMap, err := colony.InsertColonyLocations(tx, uint(newColony.ID), transformIDs)
+		if err != nil {
+			return fmt.Errorf("error inserting colony locations: %w", err)
+		}
+		locations := util.PGIntArray{}
+		for _, locationID := range locationIDMap {
+			locations = append(locations, int(locationID))
+		}
+		if err := colony.InitializeColonyPaths(tx, newColony.ID, locationIDMap); err != nil {
+			return fmt.Errorf("error initializing colony paths: %w", err)
+		}
+		assetIDs, err := colony.InsertColonyAssets(tx, newColony.ID, boundingBox)
+		if err != nil {
+			return fmt.Errorf("error inserting colony assets: %w", err)
+		}
+		if err := tx.Model(newColony).Updates(map[string]interface{}{"Locations": locations, "Assets": util.PGIntArray(assetIDs)}).Error; err != nil {
+			return fmt.Errorf("error updating colony: %w", err)
+		}
+		newColony.Locations = locations
+		newColony.Assets = assetIDs
+		return nil
+	})
+	if err != nil {
+		// Everything generated was rolled back, only the colony itself is left.
+		// Not bound to the request, so the cleanup still happens if the request timed out.
+		r.db.Delete(&Colony{}, newColony.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *postgresColonyRepository) DeleteByOwner(ctx context.Context, owner uint32) (int, error) {
+	var colonyIDs []uint32
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("Colony").Where("owner = ?", owner).Pluck("id", &colonyIDs).Error; err != nil {
+			return err
+		}
+		if len(colonyIDs) == 0 {
+			return nil
+		}
+
+		var transformIDs []uint
+		var assetTransformIDs []uint
+		if err := tx.Table("ColonyLocation").Where("colony IN ?", colonyIDs).Pluck("transform", &transformIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("ColonyAsset").Where("colony IN ?", colonyIDs).Pluck("transform", &assetTransformIDs).Error; err != nil {
+			return err
+		}
+		transformIDs = append(transformIDs, assetTransformIDs...)
+
+		//Same order as when closing a colony: the reference to the code is removed before the code
+		if err := tx.Table("Colony").Where("id IN ?", colonyIDs).Update("colonyCode", nil).Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"ColonyCode", "ColonyLocationPath", "ColonyAsset", "ColonyLocation"} {
+			if err := tx.Table(table).Where("colony IN ?", colonyIDs).Delete(nil).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Table("Colony").Where("id IN ?", colonyIDs).Delete(nil).Error; err != nil {
+			return err
+		}
+		if len(transformIDs) > 0 {
+			return tx.Table("Transform").Where("id IN ?", transformIDs).Delete(nil).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(colonyIDs), nil
+}
+
+func (r *postgresColonyRepository) FindLocation(ctx context.Context, id uint32) (*ColonyLocation, error) {
+	var location ColonyLocation
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&location).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &location, nil
+}
+
+func (r *postgresColonyRepository) FindAsset(ctx context.Context, id uint32) (*ColonyAsset, error) {
+	var asset ColonyAsset
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&asset).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &asset, nil
+}
+
+func (r *postgresColonyRepository) FindTransform(ctx context.Context, id uint32) (*Transform, error) {
+	var transform Transform
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&transform).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &transform, nil
+}
+
+func (r *postgresColonyRepository) UpgradeLocation(ctx context.Context, colonyID uint32, colonyLocationID uint32) (*ColonyLocation, error) {
+	var location ColonyLocation
+	result := r.db.WithContext(ctx).Raw(`UPDATE "ColonyLocation" SET level = level + 1 WHERE id = ? AND colony = ? RETURNING *`, colonyLocationID, colonyID).
+		Scan(&location)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+	return &location, nil
+}
+
+func (r *postgresColonyRepository) FindPaths(ctx context.Context, colonyID uint32) ([]ColonyPath, error) {
+	var paths []ColonyPath
+	if err := r.db.WithContext(ctx).Where("colony = ?", colonyID).Find(&paths).Error; err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func (r *postgresColonyRepository) UpdateLatestVisit(ctx context.Context, id uint32, latestVisit string) error {
+	result := r.db.WithContext(ctx).Model(&Colony{}).Where("id = ?", id).Update("latestVisit", latestVisit)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresColonyRepository) FindCode(ctx context.Context, id uint32) (*ColonyCode, error) {
+	var code ColonyCode
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&code).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &code, nil
+}
+
+func (r *postgresColonyRepository) FindCodeByValue(ctx context.Context, value string) (*ColonyCode, error) {
+	var codes []ColonyCode
+	if err := r.db.WithContext(ctx).Where("value = ?", value).Find(&codes).Error; err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, ErrNotFound
+	}
+	return &codes[0], nil
+}
+
+func (r *postgresColonyRepository) DeleteCode(ctx context.Context, id uint32) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&ColonyCode{}).Error
+}
+
+func (r *postgresColonyRepository) AttachCode(ctx context.Context, code *ColonyCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(code).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrDuplicateCode
+			}
+			return err
+		}
+		return tx.Model(&Colony{}).Where("id = ?", code.ColonyID).Update("colonyCode", code.ID).Error
+	})
+}
+
+func (r *postgresColonyRepository) DetachCodes(ctx context.Context, colonyID uint32, owner uint32) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var colony Colony
+		if err := tx.Where("id = ? AND owner = ?", colonyID, owner).First(&colony).Error; err != nil {
+			return translate(err)
+		}
+		if err := tx.Model(&colony).Update("colonyCode", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("colony = ?", colonyID).Delete(&ColonyCode{}).Error
+	})
+}
+
+func (r *postgresColonyRepository) FindWorldLocation(ctx context.Context, id uint32) (*Location, error) {
+	var location Location
+	if err := r.db.WithContext(ctx).
+		Preload("Appearances").
+		Where("id = ?", id).
+		First(&location).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &location, nil
+}
+
+func (r *postgresColonyRepository) FindWorldLocationFull(ctx context.Context, id uint32) (*Location, error) {
+	var location Location
+	if err := r.db.WithContext(ctx).
+		Preload("Minigame.Difficulties").
+		Preload("Appearances.AssetCollection.CollectionEntries.GraphicalAsset.LODs").
+		Preload("Appearances.AssetCollection.CollectionEntries.Transform").
+		Where("id = ?", id).
+		First(&location).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &location, nil
+}
@@ -1,8 +1,6 @@
 package colony
 
 import (
-	"otte_main_backend/src/meta"
-
 	"gorm.io/gorm"
 )
 
@@ -19,7 +17,7 @@ func (ColonyLocation) TableName() string {
 	return "ColonyLocation"
 }
 
-func InsertColonyLocations(appContext *meta.ApplicationContext, tx *gorm.DB, colonyID uint, transformIDs map[string]uint) (map[uint]uint, error) {
+func InsertColonyLocations(tx *gorm.DB, colonyID uint, transformIDs map[string]uint) (map[uint]uint, error) {
 	locations := []struct {
 		Name     string
 		Location uint
@@ -1,8 +1,6 @@
 package colony
 
 import (
-	"otte_main_backend/src/meta"
-
 	"gorm.io/gorm"
 )
 
@@ -38,7 +36,7 @@ type BoundingBox struct {
 	MaxY float64
 }
 
-func InsertLocationTransforms(appContext *meta.ApplicationContext, tx *gorm.DB) (map[string]uint, error, *BoundingBox) {
+func InsertLocationTransforms(tx *gorm.DB) (map[string]uint, error, *BoundingBox) {
 	// Positions are scaled to act as if 2048 x 1080
 	transforms := []Transform{
 		createTransform(1, 1, 650, 400, 1),  // Town Hall
@@ -0,0 +1,948 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"otte_main_backend/src/util"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// In-memory implementations of every repository, for testing handlers without a database.
+// Records are copied in and out, so callers never share memory with the repository.
+// The Put methods add records directly, for setting up a test.
+
+type MemoryPlayerRepository struct {
+	lock                 sync.RWMutex
+	nextID               uint32
+	players              map[uint32]Player
+	achievements         map[uint32]Achievement
+	availablePreferences map[string]AvailablePreference
+	preferences          []PlayerPreference
+	profileChanges       []ProfileChange
+	progressEvents       []ProgressEvent
+}
+
+func NewMemoryPlayerRepository() *MemoryPlayerRepository {
+	return &MemoryPlayerRepository{
+		players:              map[uint32]Player{},
+		achievements:         map[uint32]Achievement{},
+		availablePreferences: map[string]AvailablePreference{},
+	}
+}
+
+// Replaces any player with the same ID
+func (r *MemoryPlayerRepository) PutPlayer(player Player) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player.Achievements = append(util.PGIntArray{}, player.Achievements...)
+	r.players[player.ID] = player
+	r.nextID = max(r.nextID, player.ID)
+}
+
+func (r *MemoryPlayerRepository) PutAchievement(achievement Achievement) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.achievements[achievement.ID] = achievement
+}
+
+func (r *MemoryPlayerRepository) PutAvailablePreference(preference AvailablePreference) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	preference.AvailableValues = append(util.PGStringArray{}, preference.AvailableValues...)
+	r.availablePreferences[preference.PreferenceKey] = preference
+}
+
+func (r *MemoryPlayerRepository) PutProgressEvent(event ProgressEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.progressEvents = append(r.progressEvents, event)
+}
+
+// Every profile change recorded for the player, oldest first
+func (r *MemoryPlayerRepository) ProfileChanges(playerID uint32) []ProfileChange {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	changes := []ProfileChange{}
+	for _, change := range r.profileChanges {
+		if change.Player == playerID {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// The Player fields SyncProfile can set, by column
+func profileColumn(player *Player, column string) (*string, error) {
+	switch column {
+	case "firstName":
+		return &player.FirstName, nil
+	case "lastName":
+		return &player.LastName, nil
+	}
+	return nil, fmt.Errorf("unknown profile column: %s", column)
+}
+
+func (r *MemoryPlayerRepository) find(id uint32) (*Player, error) {
+	player, exists := r.players[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	player.Achievements = append(util.PGIntArray{}, player.Achievements...)
+	return &player, nil
+}
+
+func (r *MemoryPlayerRepository) FindByID(ctx context.Context, id uint32) (*Player, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.find(id)
+}
+
+func (r *MemoryPlayerRepository) FindByReferenceID(ctx context.Context, referenceID string) (*Player, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for id, player := range r.players {
+		if player.ReferenceID == referenceID {
+			return r.find(id)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryPlayerRepository) Create(ctx context.Context, player *Player) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nextID++
+	player.ID = r.nextID
+	stored := *player
+	stored.Achievements = append(util.PGIntArray{}, player.Achievements...)
+	r.players[player.ID] = stored
+	return nil
+}
+
+func (r *MemoryPlayerRepository) RecordVerification(ctx context.Context, id uint32, attempt VerificationAttempt) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player, exists := r.players[id]
+	if !exists {
+		//Same as updating no rows
+		return nil
+	}
+	attemptedAt := attempt.AttemptedAt
+	player.LastVerificationAttemptAt = &attemptedAt
+	player.LastVerificationOutcome = attempt.Outcome
+	if attempt.Verified {
+		player.LastVerifiedAt = &attemptedAt
+	}
+	r.players[id] = player
+	return nil
+}
+
+func (r *MemoryPlayerRepository) SetRole(ctx context.Context, id uint32, role Role) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player, exists := r.players[id]
+	if !exists {
+		return ErrNotFound
+	}
+	player.Role = role
+	r.players[id] = player
+	return nil
+}
+
+func (r *MemoryPlayerRepository) SyncProfile(ctx context.Context, id uint32, values map[string]string, now time.Time) ([]ProfileChange, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player, exists := r.players[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	var changes []ProfileChange
+	for column, newValue := range values {
+		field, err := profileColumn(&player, column)
+		if err != nil {
+			return nil, err
+		}
+		if *field == newValue {
+			continue
+		}
+		changes = append(changes, ProfileChange{Player: id, Field: column, OldValue: *field, NewValue: newValue, ChangedAt: now})
+		*field = newValue
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	for i := range changes {
+		changes[i].ID = uint32(len(r.profileChanges) + 1)
+		r.profileChanges = append(r.profileChanges, changes[i])
+	}
+	r.players[id] = player
+	return changes, nil
+}
+
+func (r *MemoryPlayerRepository) SetDisabled(ctx context.Context, id uint32, disabled bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player, exists := r.players[id]
+	if !exists {
+		return ErrNotFound
+	}
+	player.Disabled = disabled
+	r.players[id] = player
+	return nil
+}
+
+// Sessions are kept by the MemorySessionRepository, so they are left as is
+func (r *MemoryPlayerRepository) Delete(ctx context.Context, id uint32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.players, id)
+	r.preferences = slices.DeleteFunc(r.preferences, func(preference PlayerPreference) bool { return preference.Player == id })
+	r.profileChanges = slices.DeleteFunc(r.profileChanges, func(change ProfileChange) bool { return change.Player == id })
+	r.progressEvents = slices.DeleteFunc(r.progressEvents, func(event ProgressEvent) bool { return event.Player == id })
+	return nil
+}
+
+func (r *MemoryPlayerRepository) FindProgressSummary(ctx context.Context, playerID uint32, recentLimit int) (*ProgressSummary, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	summary := &ProgressSummary{Recent: []ProgressEvent{}}
+	for _, event := range r.progressEvents {
+		if event.Player == playerID {
+			summary.count(event.Status, 1)
+			summary.Recent = append(summary.Recent, event)
+		}
+	}
+	sort.Slice(summary.Recent, func(i, j int) bool { return summary.Recent[i].ID > summary.Recent[j].ID })
+	summary.Recent = summary.Recent[:min(recentLimit, len(summary.Recent))]
+	return summary, nil
+}
+
+func (r *MemoryPlayerRepository) FindAchievement(ctx context.Context, id uint32) (*Achievement, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	achievement, exists := r.achievements[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &achievement, nil
+}
+
+func (r *MemoryPlayerRepository) GrantAchievement(ctx context.Context, playerID uint32, achievementID uint32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	player, exists := r.players[playerID]
+	if !exists {
+		return ErrNotFound
+	}
+	if !util.ArrayContains(player.Achievements, int(achievementID)) {
+		player.Achievements = append(append(util.PGIntArray{}, player.Achievements...), int(achievementID))
+	}
+	r.players[playerID] = player
+	return nil
+}
+
+func (r *MemoryPlayerRepository) FindPreferences(ctx context.Context, playerID uint32) ([]PlayerPreference, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	preferences := []PlayerPreference{}
+	for _, preference := range r.preferences {
+		if _, available := r.availablePreferences[preference.PreferenceKey]; available && preference.Player == playerID {
+			preferences = append(preferences, preference)
+		}
+	}
+	return preferences, nil
+}
+
+func (r *MemoryPlayerRepository) FindAvailablePreference(ctx context.Context, key string) (*AvailablePreference, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	preference, exists := r.availablePreferences[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	preference.AvailableValues = append(util.PGStringArray{}, preference.AvailableValues...)
+	return &preference, nil
+}
+
+func (r *MemoryPlayerRepository) SetPreference(ctx context.Context, playerID uint32, key string, value string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, preference := range r.preferences {
+		if preference.Player == playerID && preference.PreferenceKey == key {
+			r.preferences[i].ChosenValue = value
+			return nil
+		}
+	}
+	r.preferences = append(r.preferences, PlayerPreference{
+		ID:            uint32(len(r.preferences) + 1),
+		Player:        playerID,
+		PreferenceKey: key,
+		ChosenValue:   value,
+	})
+	return nil
+}
+
+type MemorySessionRepository struct {
+	lock     sync.RWMutex
+	nextID   uint32
+	sessions map[uint32]Session
+	denied   map[SessionToken]time.Time
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{sessions: map[uint32]Session{}, denied: map[SessionToken]time.Time{}}
+}
+
+func (r *MemorySessionRepository) Create(ctx context.Context, session *Session) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nextID++
+	session.ID = r.nextID
+	stored := *session
+	stored.RawToken = ""
+	stored.Role = ""
+	r.sessions[session.ID] = stored
+	return nil
+}
+
+func (r *MemorySessionRepository) FindByToken(ctx context.Context, token SessionToken) (*Session, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, session := range r.sessions {
+		if session.Token == token {
+			return &session, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemorySessionRepository) FindByPlayer(ctx context.Context, playerID uint32) ([]Session, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	sessions := []Session{}
+	for _, session := range r.sessions {
+		if session.Player == playerID {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) UpdateLastCheckIn(ctx context.Context, id uint32, at time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if session, exists := r.sessions[id]; exists {
+		session.LastCheckIn = at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) BulkCheckIn(ctx context.Context, checkIns map[uint32]time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for id, at := range checkIns {
+		if session, exists := r.sessions[id]; exists && session.LastCheckIn.Before(at) {
+			session.LastCheckIn = at
+			r.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByToken(ctx context.Context, token SessionToken) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for id, session := range r.sessions {
+		if session.Token == token {
+			delete(r.sessions, id)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (r *MemorySessionRepository) DeleteByIDs(ctx context.Context, ids []uint32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, id := range ids {
+		delete(r.sessions, id)
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByPlayer(ctx context.Context, playerID uint32) (int64, error) {
+	return r.deleteWhere(func(session Session) bool { return session.Player == playerID }), nil
+}
+
+func (r *MemorySessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return r.deleteWhere(func(session Session) bool { return session.expiredAt(now) }), nil
+}
+
+func (r *MemorySessionRepository) deleteWhere(matches func(Session) bool) int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var deleted int64 = 0
+	for id, session := range r.sessions {
+		if matches(session) {
+			delete(r.sessions, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func (r *MemorySessionRepository) FindUnhashed(ctx context.Context, hashedTokenLength int) ([]Session, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	sessions := []Session{}
+	for _, session := range r.sessions {
+		if len(session.Token) != hashedTokenLength {
+			sessions = append(sessions, Session{ID: session.ID, Token: session.Token})
+		}
+	}
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) ReplaceToken(ctx context.Context, id uint32, oldToken SessionToken, newToken SessionToken) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if session, exists := r.sessions[id]; exists && session.Token == oldToken {
+		session.Token = newToken
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) Deny(ctx context.Context, token SessionToken, until time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.denied[token] = until
+	return nil
+}
+
+func (r *MemorySessionRepository) FindDenied(ctx context.Context, now time.Time) ([]DeniedSessionToken, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	denied := []DeniedSessionToken{}
+	for token, until := range r.denied {
+		if until.After(now) {
+			denied = append(denied, DeniedSessionToken{Token: token, DeniedUntil: until})
+		}
+	}
+	return denied, nil
+}
+
+func (r *MemorySessionRepository) DeleteExpiredDenials(ctx context.Context, now time.Time) (int64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var deleted int64 = 0
+	for token, until := range r.denied {
+		if !until.After(now) {
+			delete(r.denied, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+type MemoryColonyRepository struct {
+	lock           sync.RWMutex
+	nextID         uint32
+	nextCodeID     uint32
+	colonies       map[uint32]Colony
+	locations      map[uint32]ColonyLocation
+	assets         map[uint32]ColonyAsset
+	transforms     map[uint32]Transform
+	paths          map[uint32][]ColonyPath
+	codes          map[uint32]ColonyCode
+	worldLocations map[uint32]Location
+}
+
+func NewMemoryColonyRepository() *MemoryColonyRepository {
+	return &MemoryColonyRepository{
+		colonies:       map[uint32]Colony{},
+		locations:      map[uint32]ColonyLocation{},
+		assets:         map[uint32]ColonyAsset{},
+		transforms:     map[uint32]Transform{},
+		paths:          map[uint32][]ColonyPath{},
+		codes:          map[uint32]ColonyCode{},
+		worldLocations: map[uint32]Location{},
+	}
+}
+
+func (r *MemoryColonyRepository) PutColony(colony Colony) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.colonies[colony.ID] = copyColony(colony)
+	r.nextID = max(r.nextID, colony.ID)
+}
+
+func (r *MemoryColonyRepository) PutLocation(location ColonyLocation) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.locations[location.ID] = location
+}
+
+func (r *MemoryColonyRepository) PutAsset(asset ColonyAsset) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.assets[asset.ID] = asset
+}
+
+func (r *MemoryColonyRepository) PutTransform(transform Transform) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.transforms[transform.ID] = transform
+}
+
+func (r *MemoryColonyRepository) PutPath(colonyID uint32, path ColonyPath) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.paths[colonyID] = append(r.paths[colonyID], path)
+}
+
+// Doesn't attach the code to its colony, set Colony.ColonyCode for that
+func (r *MemoryColonyRepository) PutCode(code ColonyCode) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.codes[code.ID] = code
+	r.nextCodeID = max(r.nextCodeID, code.ID)
+}
+
+// Returned as is by both FindWorldLocation and FindWorldLocationFull. Unlike other records, nested records
+// (appearances, minigame) are shared, so they must not be modified once put.
+func (r *MemoryColonyRepository) PutWorldLocation(location Location) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.worldLocations[location.ID] = location
+}
+
+func copyColony(colony Colony) Colony {
+	colony.Assets = append(util.PGIntArray{}, colony.Assets...)
+	colony.Locations = append(util.PGIntArray{}, colony.Locations...)
+	return colony
+}
+
+func (r *MemoryColonyRepository) FindByID(ctx context.Context, id uint32) (*Colony, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	colony, exists := r.colonies[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	colony = copyColony(colony)
+	return &colony, nil
+}
+
+func (r *MemoryColonyRepository) FindByOwner(ctx context.Context, owner uint32) ([]Colony, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	colonies := []Colony{}
+	for _, colony := range r.colonies {
+		if colony.Owner == owner {
+			colonies = append(colonies, copyColony(colony))
+		}
+	}
+	sort.Slice(colonies, func(i, j int) bool { return colonies[i].ID < colonies[j].ID })
+	return colonies, nil
+}
+
+// Nothing is generated for the colony, its Locations and Assets are left empty
+func (r *MemoryColonyRepository) Create(ctx context.Context, colony *Colony) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nextID++
+	colony.ID = r.nextID
+	colony.Assets = util.PGIntArray{}
+	colony.Locations = util.PGIntArray{}
+	r.colonies[colony.ID] = copyColony(*colony)
+	return nil
+}
+
+func (r *MemoryColonyRepository) DeleteByOwner(ctx context.Context, owner uint32) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	deleted := 0
+	for colonyID, colony := range r.colonies {
+		if colony.Owner != owner {
+			continue
+		}
+		for id, location := range r.locations {
+			if location.Colony == colonyID {
+				delete(r.transforms, location.Transform)
+				delete(r.locations, id)
+			}
+		}
+		for id, asset := range r.assets {
+			if asset.Colony == colonyID {
+				delete(r.transforms, asset.Transform)
+				delete(r.assets, id)
+			}
+		}
+		for id, code := range r.codes {
+			if code.ColonyID == colonyID {
+				delete(r.codes, id)
+			}
+		}
+		delete(r.paths, colonyID)
+		delete(r.colonies, colonyID)
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (r *MemoryColonyRepository) FindLocation(ctx context.Context, id uint32) (*ColonyLocation, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	location, exists := r.locations[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &location, nil
+}
+
+func (r *MemoryColonyRepository) FindAsset(ctx context.Context, id uint32) (*ColonyAsset, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	asset, exists := r.assets[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &asset, nil
+}
+
+func (r *MemoryColonyRepository) FindTransform(ctx context.Context, id uint32) (*Transform, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	transform, exists := r.transforms[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &transform, nil
+}
+
+func (r *MemoryColonyRepository) UpgradeLocation(ctx context.Context, colonyID uint32, colonyLocationID uint32) (*ColonyLocation, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	location, exists := r.locations[colonyLocationID]
+	if !exists || location.Colony != colonyID {
+		return nil, ErrNotFound
+	}
+	location.Level++
+	r.locations[colonyLocationID] = location
+	return &location, nil
+}
+
+func (r *MemoryColonyRepository) FindPaths(ctx context.Context, colonyID uint32) ([]ColonyPath, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]ColonyPath{}, r.paths[colonyID]...), nil
+}
+
+func (r *MemoryColonyRepository) UpdateLatestVisit(ctx context.Context, id uint32, latestVisit string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	colony, exists := r.colonies[id]
+	if !exists {
+		return ErrNotFound
+	}
+	colony.LatestVisit = latestVisit
+	r.colonies[id] = colony
+	return nil
+}
+
+func (r *MemoryColonyRepository) FindCode(ctx context.Context, id uint32) (*ColonyCode, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	code, exists := r.codes[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &code, nil
+}
+
+func (r *MemoryColonyRepository) FindCodeByValue(ctx context.Context, value string) (*ColonyCode, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, code := range r.codes {
+		if code.Value == value {
+			return &code, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryColonyRepository) DeleteCode(ctx context.Context, id uint32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.codes, id)
+	return nil
+}
+
+func (r *MemoryColonyRepository) AttachCode(ctx context.Context, code *ColonyCode) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, existing := range r.codes {
+		if existing.Value == code.Value {
+			return ErrDuplicateCode
+		}
+	}
+	r.nextCodeID++
+	code.ID = r.nextCodeID
+	r.codes[code.ID] = *code
+	if colony, exists := r.colonies[code.ColonyID]; exists {
+		colony.ColonyCode = code.ID
+		r.colonies[code.ColonyID] = colony
+	}
+	return nil
+}
+
+func (r *MemoryColonyRepository) DetachCodes(ctx context.Context, colonyID uint32, owner uint32) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	colony, exists := r.colonies[colonyID]
+	if !exists || colony.Owner != owner {
+		return ErrNotFound
+	}
+	colony.ColonyCode = 0
+	r.colonies[colonyID] = colony
+	for id, code := range r.codes {
+		if code.ColonyID == colonyID {
+			delete(r.codes, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryColonyRepository) FindWorldLocation(ctx context.Context, id uint32) (*Location, error) {
+	return r.FindWorldLocationFull(ctx, id)
+}
+
+func (r *MemoryColonyRepository) FindWorldLocationFull(ctx context.Context, id uint32) (*Location, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	location, exists := r.worldLocations[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &location, nil
+}
+
+type MemoryAssetRepository struct {
+	lock        sync.RWMutex
+	assets      map[uint32]Asset
+	lods        map[uint32]LOD
+	collections map[uint32][]CollectionEntryRow
+}
+
+func NewMemoryAssetRepository() *MemoryAssetRepository {
+	return &MemoryAssetRepository{
+		assets:      map[uint32]Asset{},
+		lods:        map[uint32]LOD{},
+		collections: map[uint32][]CollectionEntryRow{},
+	}
+}
+
+// The LODs of the asset are taken from the LODs put, so asset.LODs is ignored
+func (r *MemoryAssetRepository) PutAsset(asset Asset) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	asset.LODs = nil
+	r.assets[asset.ID] = asset
+}
+
+func (r *MemoryAssetRepository) PutLOD(lod LOD) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	lod.Blob = append([]byte{}, lod.Blob...)
+	r.lods[lod.ID] = lod
+}
+
+// Creates the collection if it doesn't exist. Pass no rows to create an empty collection.
+func (r *MemoryAssetRepository) PutCollectionEntries(collectionID uint32, rows ...CollectionEntryRow) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collections[collectionID] = append(r.collections[collectionID], rows...)
+}
+
+func (r *MemoryAssetRepository) withLODs(asset Asset) Asset {
+	asset.LODs = []LODDetails{}
+	for _, lod := range r.lods {
+		if lod.GraphicalAsset == asset.ID {
+			asset.LODs = append(asset.LODs, LODDetails{ID: lod.ID, DetailLevel: lod.DetailLevel, GraphicalAsset: lod.GraphicalAsset})
+		}
+	}
+	sort.Slice(asset.LODs, func(i, j int) bool { return asset.LODs[i].ID < asset.LODs[j].ID })
+	return asset
+}
+
+func (r *MemoryAssetRepository) FindByID(ctx context.Context, id uint32) (*Asset, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	asset, exists := r.assets[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	asset = r.withLODs(asset)
+	return &asset, nil
+}
+
+func (r *MemoryAssetRepository) FindByIDs(ctx context.Context, ids []uint32) ([]Asset, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	assets := []Asset{}
+	for _, id := range ids {
+		if asset, exists := r.assets[id]; exists {
+			assets = append(assets, r.withLODs(asset))
+		}
+	}
+	return assets, nil
+}
+
+func (r *MemoryAssetRepository) FindLOD(ctx context.Context, id uint32) (*LOD, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	lod, exists := r.lods[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	lod.Blob = append([]byte{}, lod.Blob...)
+	return &lod, nil
+}
+
+func (r *MemoryAssetRepository) FindLODByDetailLevel(ctx context.Context, assetID uint32, detailLevel uint32) (*LOD, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, lod := range r.lods {
+		if lod.GraphicalAsset == assetID && lod.DetailLevel == detailLevel {
+			lod.Blob = append([]byte{}, lod.Blob...)
+			return &lod, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryAssetRepository) FindCollectionEntries(ctx context.Context, collectionID uint32) ([]CollectionEntryRow, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]CollectionEntryRow{}, r.collections[collectionID]...), nil
+}
+
+func (r *MemoryAssetRepository) CollectionExists(ctx context.Context, collectionID uint32) (bool, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	_, exists := r.collections[collectionID]
+	return exists, nil
+}
+
+type MemoryCatalogueRepository struct {
+	lock      sync.RWMutex
+	languages []AvailableLanguage
+	// Language code to key to value
+	catalogues map[string]map[string]string
+}
+
+func NewMemoryCatalogueRepository() *MemoryCatalogueRepository {
+	return &MemoryCatalogueRepository{catalogues: map[string]map[string]string{}}
+}
+
+// Adds the language along with its catalogue
+func (r *MemoryCatalogueRepository) PutLanguage(language AvailableLanguage, catalogue map[string]string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.languages = append(r.languages, language)
+	r.catalogues[language.Code] = map[string]string{}
+	for key, value := range catalogue {
+		r.catalogues[language.Code][key] = value
+	}
+}
+
+func (r *MemoryCatalogueRepository) FindLanguages(ctx context.Context) ([]AvailableLanguage, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]AvailableLanguage{}, r.languages...), nil
+}
+
+func (r *MemoryCatalogueRepository) FindEntries(ctx context.Context, language string, keys []string) ([]CatalogueEntry, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	catalogue, exists := r.catalogues[language]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	entries := []CatalogueEntry{}
+	for key, value := range catalogue {
+		if len(keys) == 0 || util.ArrayContains(keys, key) {
+			entries = append(entries, CatalogueEntry{Key: key, Value: value})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+type MemoryMinigameRepository struct {
+	lock      sync.RWMutex
+	minigames map[uint32]Minigame
+}
+
+func NewMemoryMinigameRepository() *MemoryMinigameRepository {
+	return &MemoryMinigameRepository{minigames: map[uint32]Minigame{}}
+}
+
+// Including its difficulties, whose MinigameID is set to that of the minigame
+func (r *MemoryMinigameRepository) PutMinigame(minigame Minigame) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	minigame.Difficulties = append([]MinigameDifficulty{}, minigame.Difficulties...)
+	for i := range minigame.Difficulties {
+		minigame.Difficulties[i].MinigameID = minigame.ID
+	}
+	r.minigames[minigame.ID] = minigame
+}
+
+func (r *MemoryMinigameRepository) FindByID(ctx context.Context, id uint32) (*Minigame, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	minigame, exists := r.minigames[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	minigame.Difficulties = append([]MinigameDifficulty{}, minigame.Difficulties...)
+	return &minigame, nil
+}
+
+func (r *MemoryMinigameRepository) FindDifficulty(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameDifficulty, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, difficulty := range r.minigames[minigameID].Difficulties {
+		if difficulty.ID == difficultyID {
+			return &difficulty, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryMinigameRepository) FindSettings(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameSettings, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	minigame, exists := r.minigames[minigameID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	for _, other := range r.minigames {
+		for _, difficulty := range other.Difficulties {
+			if difficulty.ID == difficultyID {
+				return &MinigameSettings{
+					Settings:            datatypes.JSON(minigame.Settings),
+					OverwritingSettings: datatypes.JSON(difficulty.OverwritingSettings),
+				}, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
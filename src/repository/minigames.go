@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type MinigameDifficulty struct {
+	ID                  uint32 `json:"id"`
+	Name                string `json:"name"`
+	MinigameID          uint32 `json:"-" gorm:"column:minigame"`
+	Icon                uint32 `json:"icon"`
+	Description         string `json:"description"`
+	RequiredLevel       uint32 `json:"requiredLevel" gorm:"column:requiredLevel"`
+	OverwritingSettings string `json:"overwritingSettings" gorm:"column:overwritingSettings"`
+}
+
+func (md *MinigameDifficulty) TableName() string {
+	return "MiniGameDifficulty"
+}
+
+type Minigame struct {
+	ID           uint32               `json:"id"`
+	Name         string               `json:"name"`
+	Icon         uint32               `json:"icon"`
+	Description  string               `json:"description"`
+	Settings     string               `json:"settings"`
+	Difficulties []MinigameDifficulty `json:"difficulties" gorm:"foreignKey:MinigameID;references:ID"`
+}
+
+func (m *Minigame) TableName() string {
+	return "MiniGame"
+}
+
+// The settings of a minigame along with those a difficulty overwrites
+type MinigameSettings struct {
+	Settings            datatypes.JSON `json:"settings"`
+	OverwritingSettings datatypes.JSON `json:"overwritingSettings" gorm:"column:overwritingSettings"`
+}
+
+type MinigameRepository interface {
+	// Including its difficulties
+	FindByID(ctx context.Context, id uint32) (*Minigame, error)
+	// Only if the difficulty belongs to the minigame
+	FindDifficulty(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameDifficulty, error)
+	// Unlike FindDifficulty, the difficulty isn't required to belong to the minigame
+	FindSettings(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameSettings, error)
+}
+
+type postgresMinigameRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMinigameRepository(colonyAssetDB *gorm.DB) MinigameRepository {
+	return &postgresMinigameRepository{db: colonyAssetDB}
+}
+
+func (r *postgresMinigameRepository) FindByID(ctx context.Context, id uint32) (*Minigame, error) {
+	var minigame Minigame
+	if err := r.db.WithContext(ctx).
+		Preload("Difficulties").
+		Where(`"MiniGame".id = ?`, id).
+		First(&minigame).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &minigame, nil
+}
+
+func (r *postgresMinigameRepository) FindDifficulty(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameDifficulty, error) {
+	var difficulty MinigameDifficulty
+	if err := r.db.WithContext(ctx).Where("id = ? AND minigame = ?", difficultyID, minigameID).First(&difficulty).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &difficulty, nil
+}
+
+func (r *postgresMinigameRepository) FindSettings(ctx context.Context, minigameID uint32, difficultyID uint32) (*MinigameSettings, error) {
+	var settings MinigameSettings
+	if err := r.db.WithContext(ctx).
+		Table("MiniGame").
+		Select(`"MiniGame".settings, "MiniGameDifficulty"."overwritingSettings"`).
+		Joins(`JOIN "MiniGameDifficulty" ON "MiniGame".id = ?`, minigameID).
+		Where(`"MiniGameDifficulty".id = ?`, difficultyID).
+		Scan(&settings).Error; err != nil {
+		return nil, translate(err)
+	}
+	//Scan leaves the settings nil instead of returning gorm.ErrRecordNotFound
+	if settings.Settings == nil || settings.OverwritingSettings == nil {
+		return nil, ErrNotFound
+	}
+	return &settings, nil
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"otte_main_backend/src/util"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Stored in the "role" column of the Player table
+type Role string
+
+const (
+	RolePlayer  Role = "player"
+	RoleTeacher Role = "teacher"
+	RoleAdmin   Role = "admin"
+)
+
+// Higher rank includes the permissions of all lower ranks
+var roleRanks = map[Role]int{
+	RolePlayer:  0,
+	RoleTeacher: 1,
+	RoleAdmin:   2,
+}
+
+func (r Role) IsValid() bool {
+	_, exists := roleRanks[r]
+	return exists
+}
+
+// Whether the role is at least as privileged as the other role. Unknown roles are never.
+func (r Role) IsAtLeast(other Role) bool {
+	rank, exists := roleRanks[r]
+	if !exists {
+		return false
+	}
+	return rank >= roleRanks[other]
+}
+
+type Player struct {
+	ID           uint32          `json:"id"`
+	ReferenceID  string          `json:"referenceID" gorm:"column:referenceID"`
+	FirstName    string          `json:"firstName" gorm:"column:firstName"`
+	LastName     string          `json:"lastName" gorm:"column:lastName"`
+	Sprite       uint32          `json:"sprite"`
+	Achievements util.PGIntArray `json:"achievements"`
+	Role         Role            `json:"role" gorm:"column:role"`
+	//Set through the Vitec deprovisioning webhook, disabled players are refused new sessions
+	Disabled bool `json:"disabled" gorm:"column:disabled"`
+	//Last successful Vitec cross verification, nil if never verified
+	LastVerifiedAt *time.Time `json:"lastVerifiedAt" gorm:"column:lastVerifiedAt"`
+	//Time and outcome of the last Vitec cross verification attempt, see vitec.VerificationOutcome
+	LastVerificationAttemptAt *time.Time `json:"lastVerificationAttemptAt" gorm:"column:lastVerificationAttemptAt"`
+	LastVerificationOutcome   string     `json:"lastVerificationOutcome" gorm:"column:lastVerificationOutcome"`
+}
+
+func (p Player) TableName() string {
+	return "Player"
+}
+
+type Achievement struct {
+	ID          uint32 `json:"id" gorm:"column:id;primaryKey"`
+	Description string `json:"description" gorm:"column:description"`
+	Icon        uint32 `json:"icon" gorm:"column:icon"`
+	Title       string `json:"title" gorm:"column:title"`
+}
+
+func (a *Achievement) TableName() string {
+	return "Achievement"
+}
+
+// A value chosen by a player for one of the AvailablePreferences
+type PlayerPreference struct {
+	ID            uint32 `json:"id"`
+	Player        uint32 `json:"player" gorm:"column:player;foreignKey:player;references:ID"`
+	PreferenceKey string `json:"key" gorm:"column:preferenceKey;foreignKey:preferenceKey;references:preferenceKey"`
+	ChosenValue   string `json:"chosenValue" gorm:"column:chosenValue"`
+}
+
+func (p *PlayerPreference) TableName() string {
+	return "PlayerPreference"
+}
+
+type AvailablePreference struct {
+	ID              uint32             `gorm:"primaryKey"`
+	PreferenceKey   string             `gorm:"column:preferenceKey"`
+	AvailableValues util.PGStringArray `gorm:"column:availableValues"`
+}
+
+func (a *AvailablePreference) TableName() string {
+	return "AvailablePreference"
+}
+
+// A change to a player's profile made by PlayerRepository.SyncProfile
+type ProfileChange struct {
+	ID        uint32    `json:"id" gorm:"primaryKey"`
+	Player    uint32    `json:"player" gorm:"column:player"`
+	Field     string    `json:"field" gorm:"column:field"`
+	OldValue  string    `json:"oldValue" gorm:"column:oldValue"`
+	NewValue  string    `json:"newValue" gorm:"column:newValue"`
+	ChangedAt time.Time `json:"changedAt" gorm:"column:changedAt"`
+}
+
+func (c *ProfileChange) TableName() string {
+	return "PlayerProfileChange"
+}
+
+// The outcome of a Vitec cross verification attempt. LastVerifiedAt is only moved if Verified.
+type VerificationAttempt struct {
+	AttemptedAt time.Time
+	Outcome     string
+	Verified    bool
+}
+
+type PlayerRepository interface {
+	FindByID(ctx context.Context, id uint32) (*Player, error)
+	FindByReferenceID(ctx context.Context, referenceID string) (*Player, error)
+	// Sets the ID of the player
+	Create(ctx context.Context, player *Player) error
+	RecordVerification(ctx context.Context, id uint32, attempt VerificationAttempt) error
+	SetRole(ctx context.Context, id uint32, role Role) error
+	// Sets each Player column to its value, and records a ProfileChange for each that differs from the current value.
+	// Returns the changes made, if any.
+	SyncProfile(ctx context.Context, id uint32, values map[string]string, now time.Time) ([]ProfileChange, error)
+	// Disabled players are refused new sessions
+	SetDisabled(ctx context.Context, id uint32, disabled bool) error
+	// Deletes the player along with its sessions, preferences, profile changes and progress events
+	Delete(ctx context.Context, id uint32) error
+	// Counts the progress events of the player per delivery status, and includes up to recentLimit of the latest
+	FindProgressSummary(ctx context.Context, playerID uint32, recentLimit int) (*ProgressSummary, error)
+	FindAchievement(ctx context.Context, id uint32) (*Achievement, error)
+	// Idempotent, granting an achievement the player already has does nothing
+	GrantAchievement(ctx context.Context, playerID uint32, achievementID uint32) error
+	// Only preferences still available are included
+	FindPreferences(ctx context.Context, playerID uint32) ([]PlayerPreference, error)
+	FindAvailablePreference(ctx context.Context, key string) (*AvailablePreference, error)
+	// Creates or replaces the chosen value
+	SetPreference(ctx context.Context, playerID uint32, key string, value string) error
+}
+
+type postgresPlayerRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresPlayerRepository(playerDB *gorm.DB) PlayerRepository {
+	return &postgresPlayerRepository{db: playerDB}
+}
+
+func (r *postgresPlayerRepository) FindByID(ctx context.Context, id uint32) (*Player, error) {
+	var player Player
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&player).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &player, nil
+}
+
+func (r *postgresPlayerRepository) FindByReferenceID(ctx context.Context, referenceID string) (*Player, error) {
+	var player Player
+	if err := r.db.WithContext(ctx).Where(`"referenceID" = ?`, referenceID).First(&player).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &player, nil
+}
+
+func (r *postgresPlayerRepository) Create(ctx context.Context, player *Player) error {
+	return r.db.WithContext(ctx).Create(player).Error
+}
+
+func (r *postgresPlayerRepository) RecordVerification(ctx context.Context, id uint32, attempt VerificationAttempt) error {
+	updates := map[string]interface{}{
+		"lastVerificationAttemptAt": attempt.AttemptedAt,
+		"lastVerificationOutcome":   attempt.Outcome,
+	}
+	if attempt.Verified {
+		updates["lastVerifiedAt"] = attempt.AttemptedAt
+	}
+	return r.db.WithContext(ctx).Model(&Player{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *postgresPlayerRepository) SetRole(ctx context.Context, id uint32, role Role) error {
+	result := r.db.WithContext(ctx).Model(&Player{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresPlayerRepository) SyncProfile(ctx context.Context, id uint32, values map[string]string, now time.Time) ([]ProfileChange, error) {
+	var columns = make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, fmt.Sprintf(`"%s"`, column))
+	}
+	sort.Strings(columns)
+	var current = map[string]interface{}{}
+	if err := r.db.WithContext(ctx).Table("Player").Select(columns).Where("id = ?", id).Take(&current).Error; err != nil {
+		return nil, translate(err)
+	}
+
+	var updates = map[string]interface{}{}
+	var changes []ProfileChange
+	for column, newValue := range values {
+		var oldValue string
+		if current[column] != nil {
+			oldValue = fmt.Sprint(current[column])
+		}
+		if oldValue == newValue {
+			continue
+		}
+		updates[column] = newValue
+		changes = append(changes, ProfileChange{Player: id, Field: column, OldValue: oldValue, NewValue: newValue, ChangedAt: now})
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("Player").Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.Create(&changes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *postgresPlayerRepository) SetDisabled(ctx context.Context, id uint32, disabled bool) error {
+	result := r.db.WithContext(ctx).Model(&Player{}).Where("id = ?", id).Update("disabled", disabled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresPlayerRepository) Delete(ctx context.Context, id uint32) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"Session", "PlayerPreference", "PlayerProfileChange", "VitecProgressOutbox"} {
+			if err := tx.Table(table).Where("player = ?", id).Delete(nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Table("Player").Where("id = ?", id).Delete(nil).Error
+	})
+}
+
+func (r *postgresPlayerRepository) FindAchievement(ctx context.Context, id uint32) (*Achievement, error) {
+	var achievement Achievement
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&achievement).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &achievement, nil
+}
+
+func (r *postgresPlayerRepository) GrantAchievement(ctx context.Context, playerID uint32, achievementID uint32) error {
+	result := r.db.WithContext(ctx).Exec(`
+        UPDATE "Player"
+        SET achievements = ARRAY(SELECT DISTINCT UNNEST(array_append(achievements, ?)))
+        WHERE "id" = ?
+    `, achievementID, playerID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresPlayerRepository) FindPreferences(ctx context.Context, playerID uint32) ([]PlayerPreference, error) {
+	var preferences []PlayerPreference
+	if err := r.db.WithContext(ctx).
+		Table(`PlayerPreference`).
+		Where(`"PlayerPreference".player = ?`, playerID).
+		Select(`"PlayerPreference".id,
+				"PlayerPreference".player,
+				"PlayerPreference"."preferenceKey",
+				"PlayerPreference"."chosenValue"`).
+		Joins(`JOIN "AvailablePreference" ON "PlayerPreference"."preferenceKey" = "AvailablePreference"."preferenceKey"`).
+		Find(&preferences).Error; err != nil {
+		return nil, translate(err)
+	}
+	return preferences, nil
+}
+
+func (r *postgresPlayerRepository) FindAvailablePreference(ctx context.Context, key string) (*AvailablePreference, error) {
+	var preference AvailablePreference
+	if err := r.db.WithContext(ctx).Where(`"preferenceKey" = ?`, key).First(&preference).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &preference, nil
+}
+
+func (r *postgresPlayerRepository) SetPreference(ctx context.Context, playerID uint32, key string, value string) error {
+	var existing PlayerPreference
+	if err := r.db.WithContext(ctx).Where(`"player" = ? AND "preferenceKey" = ?`, playerID, key).First(&existing).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	//Saved with the ID of the existing preference if any, which makes it an update
+	return r.db.WithContext(ctx).Save(&PlayerPreference{
+		ID:            existing.ID,
+		Player:        playerID,
+		PreferenceKey: key,
+		ChosenValue:   value,
+	}).Error
+}
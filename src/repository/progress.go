@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// Given up on, either rejected by Vitec or out of attempts
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// A progress event waiting to be, or having been, delivered to Vitec, see vitec.ProgressReporter.
+// Persisted in the outbox table so events survive restarts.
+type ProgressEvent struct {
+	ID            uint32         `json:"id" gorm:"primaryKey"`
+	Player        uint32         `json:"player" gorm:"column:player"`
+	ReferenceID   string         `json:"referenceID" gorm:"column:referenceID"`
+	Type          string         `json:"type" gorm:"column:type"`
+	Payload       datatypes.JSON `json:"payload" gorm:"column:payload"`
+	Status        DeliveryStatus `json:"status" gorm:"column:status"`
+	Attempts      int            `json:"attempts" gorm:"column:attempts"`
+	LastError     string         `json:"lastError" gorm:"column:lastError"`
+	CreatedAt     time.Time      `json:"createdAt" gorm:"column:createdAt"`
+	NextAttemptAt time.Time      `json:"nextAttemptAt" gorm:"column:nextAttemptAt"`
+	DeliveredAt   *time.Time     `json:"deliveredAt" gorm:"column:deliveredAt"`
+}
+
+func (e *ProgressEvent) TableName() string {
+	return "VitecProgressOutbox"
+}
+
+// Delivery status of the progress events of a player, see PlayerRepository.FindProgressSummary
+type ProgressSummary struct {
+	Pending   int64 `json:"pending"`
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+	// Newest first
+	Recent []ProgressEvent `json:"recent"`
+}
+
+func (s *ProgressSummary) count(status DeliveryStatus, count int64) {
+	switch status {
+	case DeliveryPending:
+		s.Pending += count
+	case DeliveryDelivered:
+		s.Delivered += count
+	case DeliveryFailed:
+		s.Failed += count
+	}
+}
+
+func (r *postgresPlayerRepository) FindProgressSummary(ctx context.Context, playerID uint32, recentLimit int) (*ProgressSummary, error) {
+	var counts []struct {
+		Status DeliveryStatus
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).Model(&ProgressEvent{}).
+		Select("status, COUNT(*) AS count").
+		Where("player = ?", playerID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	summary := &ProgressSummary{Recent: []ProgressEvent{}}
+	for _, count := range counts {
+		summary.count(count.Status, count.Count)
+	}
+	if err := r.db.WithContext(ctx).Where("player = ?", playerID).Order("id DESC").Limit(recentLimit).Find(&summary.Recent).Error; err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Returned by every repository when the requested record doesn't exist
+var ErrNotFound = errors.New("record not found")
+
+// The repositories handlers use instead of querying the databases directly, see meta.ApplicationContext.
+// Each has a Postgres implementation, and an in-memory implementation for tests.
+// Every method takes the context of the request, so queries are cancelled along with it.
+type Repositories struct {
+	Players   PlayerRepository
+	Sessions  SessionRepository
+	Colonies  ColonyRepository
+	Assets    AssetRepository
+	Catalogue CatalogueRepository
+	Minigames MinigameRepository
+}
+
+func NewPostgresRepositories(colonyAssetDB *gorm.DB, languageDB *gorm.DB, playerDB *gorm.DB) Repositories {
+	return Repositories{
+		Players:   NewPostgresPlayerRepository(playerDB),
+		Sessions:  NewPostgresSessionRepository(playerDB),
+		Colonies:  NewPostgresColonyRepository(colonyAssetDB),
+		Assets:    NewPostgresAssetRepository(colonyAssetDB),
+		Catalogue: NewPostgresCatalogueRepository(languageDB),
+		Minigames: NewPostgresMinigameRepository(colonyAssetDB),
+	}
+}
+
+// Empty in-memory repositories. Use the concrete types (e.g. MemoryAssetRepository) to add records.
+func NewMemoryRepositories() Repositories {
+	return Repositories{
+		Players:   NewMemoryPlayerRepository(),
+		Sessions:  NewMemorySessionRepository(),
+		Colonies:  NewMemoryColonyRepository(),
+		Assets:    NewMemoryAssetRepository(),
+		Catalogue: NewMemoryCatalogueRepository(),
+		Minigames: NewMemoryMinigameRepository(),
+	}
+}
+
+// Translates gorm.ErrRecordNotFound to ErrNotFound, so callers don't depend on gorm
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
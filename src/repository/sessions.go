@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionToken string
+type Session struct {
+	ID     uint32       `json:"id" gorm:"primaryKey"` // ID
+	Player uint32       `json:"player" gorm:"foreignKey:player;references:ID"`
+	Token  SessionToken `json:"token"` //Hashed, see auth.AuthService.HashToken
+	//The token as handed to the client. Only known right after creation, never stored.
+	RawToken SessionToken `json:"-" gorm:"-"`
+	//MS
+	ValidDuration uint32    `json:"validDuration" gorm:"column:validDuration"` //Column defaults to 1h, or 3600000ms
+	CreatedAt     time.Time `json:"createdAt" gorm:"column:createdAt"`         //Column defaults to NOW()
+	LastCheckIn   time.Time `json:"lastCheckIn" gorm:"column:lastCheckIn"`     //Column defaults to NOW()
+	DeviceLabel   string    `json:"deviceLabel" gorm:"column:deviceLabel"`     //Optional, set by the client
+	UserAgent     string    `json:"userAgent" gorm:"column:userAgent"`
+	//Role of the player, not part of the Session table
+	Role Role `json:"role" gorm:"-"`
+}
+
+func (s *Session) TableName() string {
+	return "Session"
+}
+
+// Whether the session has gone without a check-in for longer than its valid duration, same as DeleteExpired
+func (s *Session) expiredAt(now time.Time) bool {
+	return s.LastCheckIn.Add(time.Duration(s.ValidDuration) * time.Millisecond).Before(now)
+}
+
+// A revoked session token, see SessionRepository.Deny
+type DeniedSessionToken struct {
+	Token       SessionToken `gorm:"column:token;primaryKey"`
+	DeniedUntil time.Time    `gorm:"column:deniedUntil"`
+}
+
+func (d *DeniedSessionToken) TableName() string {
+	return "SessionDenylist"
+}
+
+// Sessions are looked up by their hashed token, the raw token is never stored
+type SessionRepository interface {
+	// Sets the ID of the session
+	Create(ctx context.Context, session *Session) error
+	FindByToken(ctx context.Context, token SessionToken) (*Session, error)
+	// Every session of the player, expired or not, newest first
+	FindByPlayer(ctx context.Context, playerID uint32) ([]Session, error)
+	UpdateLastCheckIn(ctx context.Context, id uint32, at time.Time) error
+	// Moves the last check-in of each session to the given time, in one statement. Never moves a check-in backwards,
+	// and sessions that don't exist (anymore) are skipped.
+	BulkCheckIn(ctx context.Context, checkIns map[uint32]time.Time) error
+	// Returns ErrNotFound if no session has the token
+	DeleteByToken(ctx context.Context, token SessionToken) error
+	DeleteByIDs(ctx context.Context, ids []uint32) error
+	// Returns the amount of sessions deleted
+	DeleteByPlayer(ctx context.Context, playerID uint32) (int64, error)
+	// Deletes every session expired at the given time, returns the amount deleted
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+	// Sessions whose token isn't of the given length, i.e. stored before tokens were hashed. Only ID and Token are set.
+	FindUnhashed(ctx context.Context, hashedTokenLength int) ([]Session, error)
+	// Only replaced if the session still has the old token
+	ReplaceToken(ctx context.Context, id uint32, oldToken SessionToken, newToken SessionToken) error
+	// Records the token as revoked until the given time. Denying it again moves the time.
+	Deny(ctx context.Context, token SessionToken, until time.Time) error
+	// Every token still denied at the given time
+	FindDenied(ctx context.Context, now time.Time) ([]DeniedSessionToken, error)
+	// Deletes every denial expired at the given time, returns the amount deleted
+	DeleteExpiredDenials(ctx context.Context, now time.Time) (int64, error)
+}
+
+type postgresSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresSessionRepository(playerDB *gorm.DB) SessionRepository {
+	return &postgresSessionRepository{db: playerDB}
+}
+
+func (r *postgresSessionRepository) Create(ctx context.Context, session *Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *postgresSessionRepository) FindByToken(ctx context.Context, token SessionToken) (*Session, error) {
+	var session Session
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&session).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *postgresSessionRepository) FindByPlayer(ctx context.Context, playerID uint32) ([]Session, error) {
+	var sessions []Session
+	if err := r.db.WithContext(ctx).
+		Where("player = ?", playerID).
+		Order(`"createdAt" DESC`).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *postgresSessionRepository) UpdateLastCheckIn(ctx context.Context, id uint32, at time.Time) error {
+	//Update rather than Save, as Save would re-insert a session revoked in the meantime
+	return r.db.WithContext(ctx).Model(&Session{}).Where("id = ?", id).Update("lastCheckIn", at).Error
+}
+
+func (r *postgresSessionRepository) BulkCheckIn(ctx context.Context, checkIns map[uint32]time.Time) error {
+	if len(checkIns) == 0 {
+		return nil
+	}
+	var rows = make([]string, 0, len(checkIns))
+	var args = make([]interface{}, 0, len(checkIns)*2)
+	for sessionID, at := range checkIns {
+		rows = append(rows, "(?::bigint, ?::timestamptz)")
+		args = append(args, sessionID, at)
+	}
+	query := fmt.Sprintf(`UPDATE "Session" AS s SET "lastCheckIn" = v.at FROM (VALUES %s) AS v(id, at) WHERE s.id = v.id AND s."lastCheckIn" < v.at`, strings.Join(rows, ", "))
+	return r.db.WithContext(ctx).Exec(query, args...).Error
+}
+
+func (r *postgresSessionRepository) DeleteByToken(ctx context.Context, token SessionToken) error {
+	result := r.db.WithContext(ctx).Where("token = ?", token).Delete(&Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresSessionRepository) DeleteByIDs(ctx context.Context, ids []uint32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&Session{}).Error
+}
+
+func (r *postgresSessionRepository) DeleteByPlayer(ctx context.Context, playerID uint32) (int64, error) {
+	result := r.db.WithContext(ctx).Where("player = ?", playerID).Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where(`"lastCheckIn" + ("validDuration" * INTERVAL '1 millisecond') < ?`, now).
+		Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresSessionRepository) FindUnhashed(ctx context.Context, hashedTokenLength int) ([]Session, error) {
+	var sessions []Session
+	if err := r.db.WithContext(ctx).
+		Select("id", "token").
+		Where("LENGTH(token) <> ?", hashedTokenLength).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *postgresSessionRepository) ReplaceToken(ctx context.Context, id uint32, oldToken SessionToken, newToken SessionToken) error {
+	return r.db.WithContext(ctx).
+		Model(&Session{}).
+		Where("id = ? AND token = ?", id, oldToken).
+		Update("token", newToken).Error
+}
+
+func (r *postgresSessionRepository) Deny(ctx context.Context, token SessionToken, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "token"}}, DoUpdates: clause.AssignmentColumns([]string{"deniedUntil"})}).
+		Create(&DeniedSessionToken{Token: token, DeniedUntil: until}).Error
+}
+
+func (r *postgresSessionRepository) FindDenied(ctx context.Context, now time.Time) ([]DeniedSessionToken, error) {
+	var denied []DeniedSessionToken
+	if err := r.db.WithContext(ctx).Where(`"deniedUntil" > ?`, now).Find(&denied).Error; err != nil {
+		return nil, err
+	}
+	return denied, nil
+}
+
+func (r *postgresSessionRepository) DeleteExpiredDenials(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where(`"deniedUntil" <= ?`, now).Delete(&DeniedSessionToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net"
+	"otte_main_backend/src/config"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Behind a reverse proxy, c.IP() would be the address of the proxy, so every client would share the rate limits by IP.
+// With TRUSTED_PROXIES set, c.IP() is the first valid IP of the PROXY_HEADER instead, but only for requests coming
+// from those proxies, so clients connecting directly can't choose their IP.
+// Clients can put anything in X-Forwarded-For before the proxies append to it, so that header is narrowed down
+// to the client IP first, see ApplyClientIPResolution.
+func NewFiberConfig(cfg config.ProxyConfig) fiber.Config {
+	if len(cfg.TrustedProxies) == 0 {
+		return fiber.Config{}
+	}
+	return fiber.Config{
+		ProxyHeader:             cfg.Header,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          cfg.TrustedProxies,
+		EnableIPValidation:      true,
+	}
+}
+
+// Replaces X-Forwarded-For by the client IP, the rightmost entry not added by one of the trusted proxies.
+// Entries left of it were sent by the client, and can't be trusted. Must be applied before anything reads c.IP().
+// Other proxy headers, like X-Real-IP, hold a single IP set by the proxy and are left as is.
+func ApplyClientIPResolution(app *fiber.App, cfg config.ProxyConfig) {
+	if len(cfg.TrustedProxies) == 0 || !strings.EqualFold(cfg.Header, fiber.HeaderXForwardedFor) {
+		return
+	}
+	trusted := parseTrustedProxies(cfg.TrustedProxies)
+	app.Use(func(c *fiber.Ctx) error {
+		//A header may be split over several lines, each proxy appending to the last
+		var entries []string
+		for _, value := range c.Request().Header.PeekAll(cfg.Header) {
+			entries = append(entries, strings.Split(string(value), ",")...)
+		}
+		if len(entries) == 0 {
+			return c.Next()
+		}
+		if clientIP := clientIPFromForwardedFor(entries, trusted); clientIP != "" {
+			c.Request().Header.Set(cfg.Header, clientIP)
+		} else {
+			//c.IP() falls back to the address of the proxy
+			c.Request().Header.Del(cfg.Header)
+		}
+		return c.Next()
+	})
+}
+
+// Walks the entries from the right, skipping those of trusted proxies. Empty if an entry isn't a valid IP.
+func clientIPFromForwardedFor(entries []string, trusted []*net.IPNet) string {
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(entries[i]))
+		if ip == nil {
+			return ""
+		}
+		if i == 0 || !isTrustedProxy(ip, trusted) {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+func isTrustedProxy(ip net.IP, trusted []*net.IPNet) bool {
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validated when the config is loaded, so invalid entries are skipped
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			networks = append(networks, network)
+			continue
+		}
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return networks
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"io"
+	"net/http/httptest"
+	"otte_main_backend/src/config"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func requestIP(t *testing.T, cfg config.ProxyConfig, header string, value string) string {
+	app := fiber.New(NewFiberConfig(cfg))
+	ApplyClientIPResolution(app, cfg)
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString(c.IP()) })
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(header, value)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal("request failed:", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestClientIPIsOnlyTakenFromTrustedProxies(t *testing.T) {
+	direct := requestIP(t, config.ProxyConfig{}, "X-Forwarded-For", "203.0.113.7")
+	if direct == "203.0.113.7" {
+		t.Error("expected the header to be ignored without trusted proxies")
+	}
+	//Requests made through app.Test come from 0.0.0.0
+	if ip := requestIP(t, config.ProxyConfig{TrustedProxies: []string{"0.0.0.0/32", "10.0.0.0/8"}, Header: "X-Forwarded-For"}, "X-Forwarded-For", "203.0.113.7, 10.0.0.1"); ip != "203.0.113.7" {
+		t.Errorf("expected the client IP from the header of a trusted proxy, got %s", ip)
+	}
+	if ip := requestIP(t, config.ProxyConfig{TrustedProxies: []string{"10.0.0.1"}, Header: "X-Forwarded-For"}, "X-Forwarded-For", "203.0.113.7"); ip != direct {
+		t.Errorf("expected the header of an untrusted proxy to be ignored, got %s", ip)
+	}
+	if ip := requestIP(t, config.ProxyConfig{TrustedProxies: []string{"0.0.0.0/32"}, Header: "X-Real-IP"}, "X-Real-IP", "203.0.113.7"); ip != "203.0.113.7" {
+		t.Errorf("expected the client IP from X-Real-IP, got %s", ip)
+	}
+}
+
+func TestSpoofedForwardedForEntriesAreIgnored(t *testing.T) {
+	cfg := config.ProxyConfig{TrustedProxies: []string{"0.0.0.0/32", "10.0.0.0/8"}, Header: "X-Forwarded-For"}
+	//The client sent "198.51.100.1", the proxies appended the client IP and their own
+	if ip := requestIP(t, cfg, "X-Forwarded-For", "198.51.100.1, 203.0.113.7, 10.0.0.2"); ip != "203.0.113.7" {
+		t.Errorf("expected the rightmost untrusted entry, got %s", ip)
+	}
+	//An invalid entry means the chain can't be followed, so the proxy itself is used
+	if ip := requestIP(t, cfg, "X-Forwarded-For", "203.0.113.7, garbage, 10.0.0.2"); ip != "0.0.0.0" {
+		t.Errorf("expected the address of the proxy, got %s", ip)
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Browsers refuse certificates valid for longer than 398 days
+const SELF_SIGNED_VALIDITY = 90 * 24 * time.Hour
+
+const SELF_SIGNED_CERT_FILE = "otte_dev_cert.crt"
+const SELF_SIGNED_KEY_FILE = "otte_dev_cert.key"
+
+// Generates a self-signed leaf certificate for development. Entries of sans that parse as IPs become IP SANs, the rest DNS SANs.
+// If sans is empty, localhost and every LAN IP of this machine are used.
+func GenerateSelfSignedCert(sans []string, now time.Time) (*tls.Certificate, error) {
+	if len(sans) == 0 {
+		sans = defaultSANs()
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: sans[0], Organization: []string{"OTTE Development"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(SELF_SIGNED_VALIDITY),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		// A leaf, so trusting it in the browser doesn't allow it to sign other certificates
+		IsCA: false,
+	}
+	for _, san := range sans {
+		if ip := net.ParseIP(san); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, san)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// localhost, the loopback addresses and every non-loopback unicast IP of this machine
+func defaultSANs() []string {
+	sans := []string{"localhost", "127.0.0.1", "::1"}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return sans
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.IsGlobalUnicast() {
+			sans = append(sans, ipNet.IP.String())
+		}
+	}
+	return sans
+}
+
+// SHA-256 of the certificate, colon separated as shown by browsers
+func Fingerprint(cert *tls.Certificate) string {
+	sum := sha256.Sum256(cert.Certificate[0])
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+	pairs := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		pairs = append(pairs, hexSum[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// Writes the certificate and key as PEM to SELF_SIGNED_CERT_FILE and SELF_SIGNED_KEY_FILE in dir. Returns the cert path.
+func WriteSelfSignedCert(cert *tls.Certificate, dir string) (string, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	certPath := filepath.Join(dir, SELF_SIGNED_CERT_FILE)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return "", fmt.Errorf("unable to write %s: %s", certPath, err.Error())
+	}
+	keyPath := filepath.Join(dir, SELF_SIGNED_KEY_FILE)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return "", fmt.Errorf("unable to write %s: %s", keyPath, err.Error())
+	}
+	return certPath, nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/x509"
+	"otte_main_backend/src/config"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	now := time.Now()
+	cert, err := GenerateSelfSignedCert([]string{"otte.local", "192.168.1.20"}, now)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := cert.Leaf.VerifyHostname("otte.local"); err != nil {
+		t.Error("expected DNS SAN to be included:", err)
+	}
+	if err := cert.Leaf.VerifyHostname("192.168.1.20"); err != nil {
+		t.Error("expected IP SAN to be included:", err)
+	}
+	if !cert.Leaf.NotAfter.Equal(now.Add(SELF_SIGNED_VALIDITY).Truncate(time.Second)) {
+		t.Errorf("expected certificate to be valid for %s, got until %s", SELF_SIGNED_VALIDITY, cert.Leaf.NotAfter)
+	}
+	if cert.Leaf.IsCA || cert.Leaf.KeyUsage&x509.KeyUsageCertSign != 0 {
+		t.Error("expected a leaf certificate, unable to sign other certificates")
+	}
+	if fingerprint := Fingerprint(cert); len(fingerprint) != 32*3-1 {
+		t.Errorf("expected a colon separated SHA-256 fingerprint, got %s", fingerprint)
+	}
+}
+
+func TestSelfSignedFallback(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.TLSConfig{
+		CertFile:           filepath.Join(dir, SELF_SIGNED_CERT_FILE),
+		KeyFile:            filepath.Join(dir, SELF_SIGNED_KEY_FILE),
+		SelfSigned:         true,
+		SelfSignedSANs:     []string{"localhost"},
+		SelfSignedWriteDir: dir,
+	}
+	reloader, err := newCertReloaderOrSelfSigned(cfg, time.Now())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	generated, _ := reloader.GetCertificate(nil)
+
+	//The written certificate is picked up as the configured one from then on
+	again, err := newCertReloaderOrSelfSigned(cfg, time.Now())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	loaded, _ := again.GetCertificate(nil)
+	if Fingerprint(loaded) != Fingerprint(generated) {
+		t.Error("expected the written certificate to be loaded")
+	}
+
+	expired, err := newCertReloaderOrSelfSigned(cfg, time.Now().Add(SELF_SIGNED_VALIDITY+time.Hour))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if replaced, _ := expired.GetCertificate(nil); Fingerprint(replaced) == Fingerprint(generated) {
+		t.Error("expected an expired certificate to be replaced")
+	}
+
+	cfg.SelfSigned = false
+	cfg.CertFile = filepath.Join(dir, "missing.crt")
+	if _, err := newCertReloaderOrSelfSigned(cfg, time.Now()); err == nil {
+		t.Error("expected a missing certificate to be an error when self-signing is disabled")
+	}
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"otte_main_backend/src/config"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Serves the certificate loaded from disk, until replaced through Reload.
+// Connections already established keep the certificate they were handshaked with.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// Loads the key pair once, failing if it can't be loaded
+func NewCertReloader(certFile string, keyFile string) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reads the key pair from disk again. On error, the previous certificate is kept.
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load TLS certificate %s: %s", r.certFile, err.Error())
+	}
+	r.cert.Store(&cert)
+	log.Println("[server] Loaded TLS certificate", r.certFile)
+	return nil
+}
+
+// Falls back to a generated self-signed certificate if allowed and the configured one is missing or expired.
+// The configured paths are kept, so a certificate put in place later is picked up on Reload.
+func newCertReloaderOrSelfSigned(cfg config.TLSConfig, now time.Time) (*CertReloader, error) {
+	reloader := &CertReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if !cfg.SelfSigned {
+		return reloader, reloader.Reload()
+	}
+	if fileExists(cfg.CertFile) && fileExists(cfg.KeyFile) {
+		if err := reloader.Reload(); err != nil {
+			return nil, err
+		}
+		if leaf := reloader.cert.Load().Leaf; leaf == nil || now.Before(leaf.NotAfter) {
+			return reloader, nil
+		}
+		log.Println("[server] TLS certificate", cfg.CertFile, "has expired, generating a self-signed certificate instead")
+	} else {
+		log.Println("[server] No TLS certificate found at", cfg.CertFile, "generating a self-signed certificate")
+	}
+
+	cert, err := GenerateSelfSignedCert(cfg.SelfSignedSANs, now)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate self-signed certificate: %s", err.Error())
+	}
+	reloader.cert.Store(cert)
+	log.Printf("[server] Self-signed certificate for %s, valid until %s\n", strings.Join(append(cert.Leaf.DNSNames, ipStrings(cert.Leaf.IPAddresses)...), ", "), cert.Leaf.NotAfter.Format(time.DateOnly))
+	log.Println("[server] SHA-256 fingerprint:", Fingerprint(cert))
+	if cfg.SelfSignedWriteDir != "" {
+		certPath, err := WriteSelfSignedCert(cert, cfg.SelfSignedWriteDir)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("[server] Self-signed certificate written to", certPath, "add it to your trust store to avoid browser warnings")
+	}
+	return reloader, nil
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func ipStrings(ips []net.IP) []string {
+	var strs = make([]string, 0, len(ips))
+	for _, ip := range ips {
+		strs = append(strs, ip.String())
+	}
+	return strs
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Builds the server TLS config from TLS_CERT_FILE, TLS_KEY_FILE, TLS_MIN_VERSION, TLS_CLIENT_AUTH and TLS_CLIENT_CA_FILE.
+// The returned reloader is used to swap the certificate at runtime.
+func NewTLSConfig(cfg config.TLSConfig) (*tls.Config, *CertReloader, error) {
+	reloader, err := newCertReloaderOrSelfSigned(cfg, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.MinVersion == "1.3" {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	switch cfg.ClientAuth {
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return tlsConfig, reloader, nil
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read TLS_CLIENT_CA_FILE: %s", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, nil, fmt.Errorf("TLS_CLIENT_CA_FILE contains no valid certificates")
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, reloader, nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"otte_main_backend/src/config"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeKeyPair(t *testing.T, dir string, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, reloader *CertReloader) string {
+	cert, _ := reloader.GetCertificate(nil)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first")
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	writeKeyPair(t, dir, "second")
+	if err := reloader.Reload(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if name := servedCommonName(t, reloader); name != "second" {
+		t.Errorf("expected renewed certificate to be served, got %s", name)
+	}
+
+	os.WriteFile(keyFile, []byte("garbage"), 0600)
+	if err := reloader.Reload(); err == nil {
+		t.Error("expected reload of an invalid key pair to fail")
+	}
+	if name := servedCommonName(t, reloader); name != "second" {
+		t.Errorf("expected previous certificate to be kept, got %s", name)
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	certFile, keyFile := writeKeyPair(t, t.TempDir(), "server")
+
+	tlsConfig, _, err := NewTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3", ClientAuth: "require", ClientCAFile: certFile})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS13 || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert || tlsConfig.ClientCAs == nil {
+		t.Errorf("expected TLS 1.3 with required client certs, got: %+v", tlsConfig)
+	}
+
+	if _, _, err := NewTLSConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "require", ClientCAFile: keyFile}); err == nil {
+		t.Error("expected a client CA file without certificates to be rejected")
+	}
+}
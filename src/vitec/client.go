@@ -0,0 +1,216 @@
+package vitec
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"otte_main_backend/src/config"
+	"time"
+)
+
+const CROSS_VERIFY_PATH = "/cross-verify"
+const HEALTH_PATH = "/health"
+
+// Why a cross verification did not succeed
+type FailureReason string
+
+const (
+	// Vitec does not know the user, or the session token does not belong to them
+	FailureRejected FailureReason = "rejected"
+	// Vitec could not be reached
+	FailureUnreachable FailureReason = "unreachable"
+	// Vitec did not respond in time
+	FailureTimeout FailureReason = "timeout"
+	// The TLS handshake failed, most likely a certificate problem
+	FailureTLS FailureReason = "tls"
+	// Vitec responded, but with an error or something unparsable
+	FailureBadResponse FailureReason = "bad-response"
+)
+
+type VerificationError struct {
+	Reason FailureReason
+	// HTTP status code of the response, 0 if no response was received
+	StatusCode int
+	Err        error
+}
+
+func (e *VerificationError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("user could not be verified (%s): %s", e.Reason, e.Err.Error())
+	}
+	return fmt.Sprintf("user could not be verified (%s)", e.Reason)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Any VerificationError is an UNVERIFIABLE_USER
+func (e *VerificationError) Is(target error) bool {
+	return target == UNVERIFIABLE_USER
+}
+
+type crossVerificationRequestDTO struct {
+	UserIdentifier      string `json:"userIdentifier"`
+	CurrentSessionToken string `json:"currentSessionToken"`
+}
+
+type crossVerificationResponseDTO struct {
+	Verified bool   `json:"verified"`
+	Message  string `json:"message"`
+}
+
+// HTTP client for the Vitec MV auth endpoint
+type VitecClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewVitecClient(baseURL string, timeout time.Duration, tlsConfig *tls.Config) *VitecClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &VitecClient{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// Client for the auth endpoint at VITEC_MV_AUTH_SCHEME://VITEC_MV_AUTH_IP:VITEC_MV_AUTH_PORT, see newVitecTLSConfigFromConfig
+func newVitecClientFromConfig(cfg config.VitecConfig) (*VitecClient, error) {
+	tlsConfig, err := newVitecTLSConfigFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := fmt.Sprintf("%s://%s", cfg.AuthScheme, net.JoinHostPort(cfg.AuthIP, fmt.Sprint(cfg.AuthPort)))
+	return NewVitecClient(baseURL, cfg.AuthTimeout, tlsConfig), nil
+}
+
+// VITEC_MV_AUTH_CA_CERT: PEM file of the CA to trust, instead of the system pool.
+// VITEC_MV_AUTH_CLIENT_CERT, VITEC_MV_AUTH_CLIENT_KEY: client certificate, if Vitec requires mTLS.
+// VITEC_MV_AUTH_INSECURE_SKIP_VERIFY: true to skip verification entirely, never use in production.
+func newVitecTLSConfigFromConfig(cfg config.VitecConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caPath := cfg.AuthCACert; caPath != "" {
+		pem, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read VITEC_MV_AUTH_CA_CERT: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("VITEC_MV_AUTH_CA_CERT contains no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	certPath, keyPath := cfg.AuthClientCert, cfg.AuthClientKey
+	if certPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load Vitec client certificate: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	tlsConfig.InsecureSkipVerify = cfg.AuthInsecureSkipVerify
+	return tlsConfig, nil
+}
+
+// Checks that Vitec is reachable and responding. Returns a VerificationError otherwise.
+func (client *VitecClient) Probe() error {
+	req, err := http.NewRequest(http.MethodGet, client.baseURL+HEALTH_PATH, nil)
+	if err != nil {
+		return &VerificationError{Reason: FailureUnreachable, Err: err}
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return &VerificationError{Reason: FailureBadResponse, StatusCode: resp.StatusCode, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+	return nil
+}
+
+// Asks Vitec whether the session token belongs to the user. Returns nil if so, a VerificationError otherwise.
+func (client *VitecClient) CrossVerify(initiationDTO *SessionInitiationDTO) error {
+	body, err := json.Marshal(crossVerificationRequestDTO{
+		UserIdentifier:      initiationDTO.UserIdentifier,
+		CurrentSessionToken: initiationDTO.CurrentSessionToken,
+	})
+	if err != nil {
+		return &VerificationError{Reason: FailureBadResponse, Err: err}
+	}
+	req, err := http.NewRequest(http.MethodPost, client.baseURL+CROSS_VERIFY_PATH, bytes.NewReader(body))
+	if err != nil {
+		return &VerificationError{Reason: FailureUnreachable, Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return classifyTransportError(err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		return &VerificationError{Reason: FailureRejected, StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusOK:
+		return &VerificationError{Reason: FailureBadResponse, StatusCode: resp.StatusCode, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+
+	var response crossVerificationResponseDTO
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return &VerificationError{Reason: FailureBadResponse, StatusCode: resp.StatusCode, Err: fmt.Errorf("error parsing response body: %v", err)}
+	}
+	if !response.Verified {
+		return &VerificationError{Reason: FailureRejected, StatusCode: resp.StatusCode, Err: errors.New(response.Message)}
+	}
+	return nil
+}
+
+// Posts the body as JSON to the path. Returns the status code of the response.
+func (client *VitecClient) postJSON(ctx context.Context, path string, body interface{}, headers map[string]string) (int, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.baseURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return 0, classifyTransportError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+func classifyTransportError(err error) *VerificationError {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &VerificationError{Reason: FailureTimeout, Err: err}
+	}
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordHeaderErr tls.RecordHeaderError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &recordHeaderErr) {
+		return &VerificationError{Reason: FailureTLS, Err: err}
+	}
+	return &VerificationError{Reason: FailureUnreachable, Err: err}
+}
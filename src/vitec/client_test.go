@@ -0,0 +1,98 @@
+package vitec_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/vitec"
+	"otte_main_backend/src/vitec/vitectest"
+	"testing"
+	"time"
+)
+
+func TestCrossVerify(t *testing.T) {
+	fake := vitectest.NewFakeVitecServer()
+	defer fake.Close()
+	fake.AddUser("user-1", "vitec-token")
+	client := vitec.NewVitecClient(fake.Server.URL, time.Second, nil)
+
+	if err := client.Probe(); err != nil {
+		t.Fatal("unexpected probe error:", err)
+	}
+	if err := client.CrossVerify(&vitec.SessionInitiationDTO{UserIdentifier: "user-1", CurrentSessionToken: "vitec-token"}); err != nil {
+		t.Error("expected known user to be verified, got:", err)
+	}
+
+	expectReason(t, client.CrossVerify(&vitec.SessionInitiationDTO{UserIdentifier: "user-1", CurrentSessionToken: "stale"}), vitec.FailureRejected)
+	expectReason(t, client.CrossVerify(&vitec.SessionInitiationDTO{UserIdentifier: "user-2", CurrentSessionToken: "vitec-token"}), vitec.FailureRejected)
+
+	fake.FailWith(http.StatusServiceUnavailable)
+	expectReason(t, client.Probe(), vitec.FailureBadResponse)
+	expectReason(t, client.CrossVerify(&vitec.SessionInitiationDTO{UserIdentifier: "user-1", CurrentSessionToken: "vitec-token"}), vitec.FailureBadResponse)
+	fake.FailWith(0)
+
+	fake.SetDelay(100 * time.Millisecond)
+	impatientClient := vitec.NewVitecClient(fake.Server.URL, 10*time.Millisecond, nil)
+	expectReason(t, impatientClient.CrossVerify(&vitec.SessionInitiationDTO{UserIdentifier: "user-1", CurrentSessionToken: "vitec-token"}), vitec.FailureTimeout)
+}
+
+func TestCrossVerifyTLS(t *testing.T) {
+	fake := vitectest.NewFakeVitecTLSServer()
+	defer fake.Close()
+
+	untrustingClient := vitec.NewVitecClient(fake.Server.URL, time.Second, &tls.Config{})
+	expectReason(t, untrustingClient.Probe(), vitec.FailureTLS)
+
+	trustingClient := vitec.NewVitecClient(fake.Server.URL, time.Second, fake.Server.Client().Transport.(*http.Transport).TLSClientConfig)
+	if err := trustingClient.Probe(); err != nil {
+		t.Error("unexpected probe error:", err)
+	}
+}
+
+func TestCrossVerifyUnreachable(t *testing.T) {
+	fake := vitectest.NewFakeVitecServer()
+	url := fake.Server.URL
+	fake.Close()
+
+	expectReason(t, vitec.NewVitecClient(url, time.Second, nil).Probe(), vitec.FailureUnreachable)
+}
+
+func expectReason(t *testing.T, err error, reason vitec.FailureReason) {
+	t.Helper()
+	var verificationErr *vitec.VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("expected a VerificationError, got: %v", err)
+	}
+	if verificationErr.Reason != reason {
+		t.Errorf("expected reason %s, got %s (%v)", reason, verificationErr.Reason, err)
+	}
+	if !errors.Is(err, vitec.UNVERIFIABLE_USER) {
+		t.Error("expected VerificationError to be an UNVERIFIABLE_USER")
+	}
+}
+
+func TestCreateNewVitecIntegrationAlways(t *testing.T) {
+	fake := vitectest.NewFakeVitecServer()
+	defer fake.Close()
+	fake.AddUser("user-1", "vitec-token")
+	ip, port := fake.Address()
+	cfg := config.VitecConfig{
+		CrossVerification: "always",
+		AuthIP:            ip,
+		AuthPort:          port,
+		AuthScheme:        "http",
+		AuthTimeout:       time.Second,
+	}
+
+	integration, err := vitec.CreateNewVitecIntegration(cfg)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := integration.VerifyUser(&vitec.SessionInitiationDTO{UserIdentifier: "user-1", CurrentSessionToken: "vitec-token"}); err != nil {
+		t.Error("expected known user to be verified, got:", err)
+	}
+	if fake.Requests() != 1 {
+		t.Errorf("expected 1 cross verification request, got %d", fake.Requests())
+	}
+}
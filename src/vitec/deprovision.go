@@ -0,0 +1,158 @@
+package vitec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/middleware"
+	"otte_main_backend/src/repository"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type DeprovisioningAction string
+
+const (
+	// The player is refused new sessions and all existing sessions are revoked
+	DeprovisionDisable DeprovisioningAction = "disable"
+	DeprovisionEnable  DeprovisioningAction = "enable"
+	// The player and everything they own is deleted
+	DeprovisionDelete DeprovisioningAction = "delete"
+)
+
+type DeprovisioningRequestDTO struct {
+	ReferenceID string               `json:"referenceID"`
+	Action      DeprovisioningAction `json:"action"`
+}
+
+type DeprovisioningResponseDTO struct {
+	ReferenceID     string               `json:"referenceID"`
+	Action          DeprovisioningAction `json:"action"`
+	PlayerID        uint32               `json:"playerId"`
+	DeletedColonies int                  `json:"deletedColonies"`
+}
+
+// Session handling lives in the auth package, which depends on this package, so it is passed in
+type DeprovisioningHooks struct {
+	// Revokes and evicts all sessions of the player
+	RevokeSessions func(ctx context.Context, playerID uint32) error
+	// Called once the player is deleted, to forget any cached state keyed by the referenceID
+	ForgetPlayer func(referenceID string)
+}
+
+type deprovisioningContext struct {
+	players  repository.PlayerRepository
+	colonies repository.ColonyRepository
+	//Read per request, as a config reload may change it
+	ddh      func() string
+	verifier *WebhookVerifier
+	hooks    DeprovisioningHooks
+}
+
+// Registers the webhook Vitec calls when a user leaves a school. Not registered if VITEC_WEBHOOK_SECRET is not set.
+func ApplyDeprovisioningWebhook(app *fiber.App, players repository.PlayerRepository, colonies repository.ColonyRepository, ddh func() string, cfg config.VitecConfig, hooks DeprovisioningHooks) error {
+	verifier := newWebhookVerifierFromConfig(cfg)
+	if verifier == nil {
+		log.Println("[MV INT] VITEC_WEBHOOK_SECRET not set, deprovisioning webhook disabled")
+		return nil
+	}
+	deprovisioning := &deprovisioningContext{
+		players:  players,
+		colonies: colonies,
+		ddh:      ddh,
+		verifier: verifier,
+		hooks:    hooks,
+	}
+	app.Post("/api/v1/vitec/deprovision", func(c *fiber.Ctx) error {
+		err := deprovisionHandler(c, deprovisioning)
+		middleware.LogRequests(c)
+		return err
+	})
+	log.Println("[MV INT] Deprovisioning webhook enabled")
+	return nil
+}
+
+func deprovisionHandler(c *fiber.Ctx, context *deprovisioningContext) error {
+	if err := context.verifier.Verify(c, time.Now()); err != nil {
+		return respondWithWebhookError(c, context.ddh(), err)
+	}
+	//The signature is only used up once the action succeeded, so Vitec can retry after an error
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			context.verifier.Forget(c)
+		}
+	}()
+
+	var request DeprovisioningRequestDTO
+	if err := c.BodyParser(&request); err != nil || request.ReferenceID == "" {
+		return respondWithWebhookError(c, context.ddh(), fiber.NewError(fiber.StatusBadRequest, "Invalid request body"))
+	}
+
+	ctx := c.UserContext()
+	player, err := context.players.FindByReferenceID(ctx, request.ReferenceID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return respondWithWebhookError(c, context.ddh(), fiber.NewError(fiber.StatusNotFound, "Unknown referenceID"))
+		}
+		log.Println("[MV INT] Unable to lookup player for deprovisioning: " + err.Error())
+		return respondWithWebhookError(c, context.ddh(), fiber.NewError(fiber.StatusInternalServerError, "Unable to lookup player"))
+	}
+	playerID := player.ID
+
+	response := DeprovisioningResponseDTO{ReferenceID: request.ReferenceID, Action: request.Action, PlayerID: playerID}
+	var actionErr error
+	switch request.Action {
+	case DeprovisionDisable:
+		actionErr = setPlayerDisabled(ctx, context, playerID, true)
+	case DeprovisionEnable:
+		actionErr = setPlayerDisabled(ctx, context, playerID, false)
+	case DeprovisionDelete:
+		response.DeletedColonies, actionErr = deletePlayer(ctx, context, playerID, request.ReferenceID)
+	default:
+		return respondWithWebhookError(c, context.ddh(), fiber.NewError(fiber.StatusBadRequest, "Unknown action: "+string(request.Action)))
+	}
+	if actionErr != nil {
+		log.Printf("[MV INT] Unable to %s player %d: %s\n", request.Action, playerID, actionErr.Error())
+		return respondWithWebhookError(c, context.ddh(), fiber.NewError(fiber.StatusInternalServerError, "Unable to "+string(request.Action)+" player"))
+	}
+
+	log.Printf("[MV INT] Player %d (%s): %s\n", playerID, request.ReferenceID, request.Action)
+	succeeded = true
+	c.Status(fiber.StatusOK)
+	return c.JSON(response)
+}
+
+func setPlayerDisabled(ctx context.Context, context *deprovisioningContext, playerID uint32, disabled bool) error {
+	if err := context.players.SetDisabled(ctx, playerID, disabled); err != nil {
+		return err
+	}
+	if disabled && context.hooks.RevokeSessions != nil {
+		return context.hooks.RevokeSessions(ctx, playerID)
+	}
+	return nil
+}
+
+// Colonies are deleted first, so if anything fails the player still exists and Vitec can retry the delete.
+// Returns the amount of colonies deleted.
+func deletePlayer(ctx context.Context, context *deprovisioningContext, playerID uint32, referenceID string) (int, error) {
+	if context.hooks.RevokeSessions != nil {
+		if err := context.hooks.RevokeSessions(ctx, playerID); err != nil {
+			return 0, err
+		}
+	}
+	deletedColonies, err := context.colonies.DeleteByOwner(ctx, playerID)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete colonies: %s", err.Error())
+	}
+	if err := context.players.Delete(ctx, playerID); err != nil {
+		return deletedColonies, fmt.Errorf("unable to delete player: %s", err.Error())
+	}
+	if context.hooks.ForgetPlayer != nil {
+		context.hooks.ForgetPlayer(referenceID)
+	}
+	return deletedColonies, nil
+}
@@ -0,0 +1,157 @@
+package vitec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"otte_main_backend/src/repository"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// A player repository whose lookups fail a given amount of times before succeeding, as when the database is briefly down
+type flakyPlayerRepository struct {
+	repository.PlayerRepository
+	failures int
+}
+
+func (r *flakyPlayerRepository) FindByReferenceID(ctx context.Context, referenceID string) (*repository.Player, error) {
+	if r.failures > 0 {
+		r.failures--
+		return nil, errors.New("connection refused")
+	}
+	return r.PlayerRepository.FindByReferenceID(ctx, referenceID)
+}
+
+func setupDeprovisioningTest(t *testing.T, lookupFailures int) (*fiber.App, *repository.MemoryPlayerRepository, *repository.MemoryColonyRepository, *WebhookVerifier, *[]uint32) {
+	players := repository.NewMemoryPlayerRepository()
+	players.PutPlayer(repository.Player{ID: 7, ReferenceID: "vitec-7"})
+	colonies := repository.NewMemoryColonyRepository()
+	var revoked []uint32
+	context := &deprovisioningContext{
+		players:  &flakyPlayerRepository{PlayerRepository: players, failures: lookupFailures},
+		colonies: colonies,
+		ddh:      func() string { return "DDH" },
+		verifier: NewWebhookVerifier([]byte("0123456789abcdef0123456789abcdef"), time.Minute),
+		hooks: DeprovisioningHooks{
+			RevokeSessions: func(ctx context.Context, playerID uint32) error {
+				revoked = append(revoked, playerID)
+				return nil
+			},
+		},
+	}
+	app := fiber.New()
+	app.Post("/deprovision", func(c *fiber.Ctx) error { return deprovisionHandler(c, context) })
+	return app, players, colonies, context.verifier, &revoked
+}
+
+func sendSigned(t *testing.T, app *fiber.App, verifier *WebhookVerifier, body string, at time.Time, tamper bool) int {
+	timestamp, signature := verifier.Sign([]byte(body), at)
+	if tamper {
+		body += " "
+	}
+	req := httptest.NewRequest("POST", "/deprovision", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WEBHOOK_TIMESTAMP_HEADER, timestamp)
+	req.Header.Set(WEBHOOK_SIGNATURE_HEADER, signature)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal("request failed:", err)
+	}
+	return resp.StatusCode
+}
+
+func TestDeprovisioningWebhookRejectsUnauthenticRequests(t *testing.T) {
+	app, _, _, verifier, _ := setupDeprovisioningTest(t, 0)
+	body := `{"referenceID":"vitec-7","action":"disable"}`
+
+	if status := sendSigned(t, app, verifier, body, time.Now(), true); status != fiber.StatusUnauthorized {
+		t.Errorf("expected tampered body to be rejected, got %d", status)
+	}
+	if status := sendSigned(t, app, verifier, body, time.Now().Add(-time.Hour), false); status != fiber.StatusUnauthorized {
+		t.Errorf("expected old timestamp to be rejected, got %d", status)
+	}
+}
+
+func TestDeprovisioningWebhookDisableAndReplay(t *testing.T) {
+	app, players, _, verifier, revoked := setupDeprovisioningTest(t, 0)
+	body := `{"referenceID":"vitec-7","action":"disable"}`
+	now := time.Now()
+
+	if status := sendSigned(t, app, verifier, body, now, false); status != fiber.StatusOK {
+		t.Fatalf("expected disable to succeed, got %d", status)
+	}
+	if player, _ := players.FindByID(context.Background(), 7); !player.Disabled {
+		t.Error("expected player 7 to be disabled")
+	}
+	if len(*revoked) != 1 || (*revoked)[0] != 7 {
+		t.Errorf("expected sessions of player 7 to be revoked, got %v", *revoked)
+	}
+	if status := sendSigned(t, app, verifier, body, now, false); status != fiber.StatusConflict {
+		t.Errorf("expected replay to be rejected, got %d", status)
+	}
+
+	if status := sendSigned(t, app, verifier, `{"referenceID":"vitec-7","action":"enable"}`, now, false); status != fiber.StatusOK {
+		t.Fatalf("expected enable to succeed, got %d", status)
+	}
+	if player, _ := players.FindByID(context.Background(), 7); player.Disabled {
+		t.Error("expected player 7 to be enabled again")
+	}
+}
+
+func TestDeprovisioningWebhookDeleteCascades(t *testing.T) {
+	app, players, colonies, verifier, _ := setupDeprovisioningTest(t, 0)
+	colonies.PutColony(repository.Colony{ID: 3, Owner: 7})
+	colonies.PutLocation(repository.ColonyLocation{ID: 30, Colony: 3, Transform: 10})
+	colonies.PutTransform(repository.Transform{ID: 10})
+	colonies.PutColony(repository.Colony{ID: 4, Owner: 8})
+	players.PutProgressEvent(repository.ProgressEvent{ID: 1, Player: 7, Status: repository.DeliveryPending})
+
+	if status := sendSigned(t, app, verifier, `{"referenceID":"vitec-7","action":"delete"}`, time.Now(), false); status != fiber.StatusOK {
+		t.Fatalf("expected delete to succeed, got %d", status)
+	}
+	ctx := context.Background()
+	if _, err := players.FindByID(ctx, 7); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected player 7 to be deleted, got %v", err)
+	}
+	if summary, _ := players.FindProgressSummary(ctx, 7, 10); summary.Pending != 0 {
+		t.Errorf("expected the progress events of player 7 to be deleted, got %+v", summary)
+	}
+	if _, err := colonies.FindByID(ctx, 3); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected colony 3 to be deleted, got %v", err)
+	}
+	if _, err := colonies.FindLocation(ctx, 30); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the locations of colony 3 to be deleted, got %v", err)
+	}
+	if _, err := colonies.FindTransform(ctx, 10); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected the transforms of colony 3 to be deleted, got %v", err)
+	}
+	if _, err := colonies.FindByID(ctx, 4); err != nil {
+		t.Errorf("expected colonies of other players to be kept, got %v", err)
+	}
+}
+
+func TestDeprovisioningWebhookCanBeRetriedAfterFailure(t *testing.T) {
+	app, players, _, verifier, revoked := setupDeprovisioningTest(t, 1)
+	body := `{"referenceID":"vitec-7","action":"disable"}`
+	now := time.Now()
+
+	if status := sendSigned(t, app, verifier, body, now, false); status != fiber.StatusInternalServerError {
+		t.Fatalf("expected the first attempt to fail, got %d", status)
+	}
+	if status := sendSigned(t, app, verifier, body, now, false); status != fiber.StatusOK {
+		t.Fatalf("expected the retry to succeed, got %d", status)
+	}
+	if player, _ := players.FindByID(context.Background(), 7); !player.Disabled {
+		t.Error("expected player 7 to be disabled")
+	}
+	if len(*revoked) != 1 {
+		t.Errorf("expected sessions to be revoked once, got %v", *revoked)
+	}
+	if status := sendSigned(t, app, verifier, body, now, false); status != fiber.StatusConflict {
+		t.Errorf("expected replay after success to be rejected, got %d", status)
+	}
+}
@@ -1,16 +1,21 @@
 package vitec
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"otte_main_backend/src/config"
+	"time"
 )
 
 type SessionInitiationDTO struct {
 	UserIdentifier      string `json:"userIdentifier"`
 	CurrentSessionToken string `json:"currentSessionToken"`
-	FirstName           string `json:"firstName"`
-	LastName            string `json:"lastName"`
+	// Synced to the Player on every login, see ProfileFields
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	// Optional, shown to the player when listing their active sessions
+	DeviceLabel string `json:"deviceLabel,omitempty"`
 }
 
 type CrossVerificationType string
@@ -18,8 +23,16 @@ type CrossVerificationType string
 const (
 	CrossVerificationNever  CrossVerificationType = "never"
 	CrossVerificationAlways CrossVerificationType = "always"
+	// Like always, but existing players are also re-verified when their last successful verification is older than the window
+	CrossVerificationPeriodic CrossVerificationType = "periodic"
 )
 
+// Stored as the outcome of a successful verification, failed verifications store the FailureReason
+const VerificationOutcomeVerified = "verified"
+
+// Stored for players created without asking Vitec, as cross verification is disabled (never)
+const VerificationOutcomeSkipped = "skipped"
+
 var UNVERIFIABLE_USER error = fmt.Errorf("user could not be verified")
 
 func neverVerifyUser(integration *VitecIntegration, initiationDTO *SessionInitiationDTO) error {
@@ -28,13 +41,17 @@ func neverVerifyUser(integration *VitecIntegration, initiationDTO *SessionInitia
 }
 
 func alwaysVerifyUser(integration *VitecIntegration, initiationDTO *SessionInitiationDTO) error {
-	return fmt.Errorf("user could not be verified: Integration Not Implemented")
+	if err := integration.client.CrossVerify(initiationDTO); err != nil {
+		log.Printf("[MV INT] Cross verification of user %s failed: %s\n", initiationDTO.UserIdentifier, err.Error())
+		return err
+	}
+	return nil
 }
 
-func CreateNewVitecIntegration() (*VitecIntegration, error) {
-	crossVerificationType := config.GetOr("VITEC_CROSS_VERIFICATION", "always")
+func CreateNewVitecIntegration(cfg config.VitecConfig) (*VitecIntegration, error) {
+	crossVerificationType := cfg.CrossVerification
 
-	var integration = &VitecIntegration{} //Struct stepwise initialized in this function
+	var integration = &VitecIntegration{Type: CrossVerificationType(crossVerificationType)} //Struct stepwise initialized in this function
 
 	switch CrossVerificationType(crossVerificationType) {
 	case CrossVerificationNever:
@@ -42,24 +59,26 @@ func CreateNewVitecIntegration() (*VitecIntegration, error) {
 			return neverVerifyUser(integration, initiationDTO)
 		}
 		log.Println("[MV INT] Establishing Vitec Cross Verification. Type: ", CrossVerificationNever)
-	case CrossVerificationAlways:
-		ip, ipErr := config.LoudGet("VITEC_MV_AUTH_IP")
-		if ipErr != nil {
-			return nil, ipErr
-		}
-		port, portErr := config.GetInt("VITEC_MV_AUTH_PORT")
-		if portErr != nil {
-			return nil, portErr
+	case CrossVerificationAlways, CrossVerificationPeriodic:
+		ip, port := cfg.AuthIP, cfg.AuthPort
+		client, clientErr := newVitecClientFromConfig(cfg)
+		if clientErr != nil {
+			return nil, clientErr
 		}
-		if err := authEndpointTest(ip, port); err != nil {
+		if err := authEndpointTest(client); err != nil {
 			return nil, err
 		}
 		integration.ip = ip
 		integration.port = port
+		integration.client = client
 		integration.VerifyUser = func(initiationDTO *SessionInitiationDTO) error {
 			return alwaysVerifyUser(integration, initiationDTO)
 		}
-		log.Println("[MV INT] Establishing Vitec Cross Verification. Type: ", CrossVerificationAlways)
+		if CrossVerificationType(crossVerificationType) == CrossVerificationPeriodic {
+			integration.ReverificationWindow = cfg.ReverificationWindow
+			log.Println("[MV INT] Re-verifying existing players every", integration.ReverificationWindow)
+		}
+		log.Println("[MV INT] Establishing Vitec Cross Verification. Type: ", crossVerificationType)
 	default:
 		return nil, fmt.Errorf("invalid cross verification type: %s", crossVerificationType)
 	}
@@ -67,13 +86,49 @@ func CreateNewVitecIntegration() (*VitecIntegration, error) {
 	return integration, nil
 }
 
-func authEndpointTest(ip string, port int) error {
-	//var fullurl = fmt.Sprintf("https://%s:%d/cross-verify", ip, port)
-	return fmt.Errorf("authEndpointTest not implemented")
+// Startup reachability probe, so a misconfigured integration fails at boot rather than on the first login
+func authEndpointTest(client *VitecClient) error {
+	if err := client.Probe(); err != nil {
+		return fmt.Errorf("vitec auth endpoint at %s not reachable: %s", client.baseURL, err.Error())
+	}
+	log.Println("[MV INT] Vitec auth endpoint reachable at", client.baseURL)
+	return nil
 }
 
 type VitecIntegration struct {
-	ip         string
-	port       int
+	ip     string
+	port   int
+	client *VitecClient
+	Type   CrossVerificationType
+	//Only set when periodic
+	ReverificationWindow time.Duration
+	//Set when the progress reporter is started, see ReportProgress
+	Progress   *ProgressReporter
 	VerifyUser func(initiationDTO *SessionInitiationDTO) error
 }
+
+// Whether VerifyUser asks Vitec, false when cross verification is disabled
+func (integration *VitecIntegration) ContactsVitec() bool {
+	return integration.Type != CrossVerificationNever
+}
+
+// Whether an existing player, last successfully verified at lastVerifiedAt (nil if never), has to be verified again.
+// Only ever true when periodic.
+func (integration *VitecIntegration) NeedsReverification(lastVerifiedAt *time.Time, now time.Time) bool {
+	if integration.Type != CrossVerificationPeriodic {
+		return false
+	}
+	return lastVerifiedAt == nil || now.Sub(*lastVerifiedAt) >= integration.ReverificationWindow
+}
+
+// The outcome to record for the result of VerifyUser
+func VerificationOutcome(verificationErr error) string {
+	if verificationErr == nil {
+		return VerificationOutcomeVerified
+	}
+	var typedErr *VerificationError
+	if errors.As(verificationErr, &typedErr) {
+		return string(typedErr.Reason)
+	}
+	return string(FailureRejected)
+}
@@ -0,0 +1,36 @@
+package vitec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNeedsReverification(t *testing.T) {
+	now := time.Now()
+	recently := now.Add(-time.Minute)
+	longAgo := now.Add(-2 * time.Hour)
+	periodic := &VitecIntegration{Type: CrossVerificationPeriodic, ReverificationWindow: time.Hour}
+	always := &VitecIntegration{Type: CrossVerificationAlways}
+
+	if !periodic.NeedsReverification(nil, now) {
+		t.Error("expected never verified player to need verification")
+	}
+	if periodic.NeedsReverification(&recently, now) {
+		t.Error("expected recently verified player not to need verification")
+	}
+	if !periodic.NeedsReverification(&longAgo, now) {
+		t.Error("expected player verified outside the window to need verification")
+	}
+	if always.NeedsReverification(&longAgo, now) {
+		t.Error("expected existing players never to be re-verified when not periodic")
+	}
+}
+
+func TestVerificationOutcome(t *testing.T) {
+	if outcome := VerificationOutcome(nil); outcome != VerificationOutcomeVerified {
+		t.Errorf("unexpected outcome for success: %s", outcome)
+	}
+	if outcome := VerificationOutcome(&VerificationError{Reason: FailureTimeout}); outcome != string(FailureTimeout) {
+		t.Errorf("unexpected outcome for timeout: %s", outcome)
+	}
+}
@@ -0,0 +1,36 @@
+package vitec
+
+import (
+	"context"
+	"otte_main_backend/src/repository"
+	"time"
+)
+
+// A Player column kept in sync with a field of the SessionInitiationDTO
+type ProfileField struct {
+	Column  string
+	FromDTO func(initiationDTO *SessionInitiationDTO) string
+}
+
+// Fields reconciled on every login. To sync another Vitec field (school, class, locale...),
+// add it to the SessionInitiationDTO, add a column to Player and to the memory repository, and add it here.
+var ProfileFields = []ProfileField{
+	{Column: "firstName", FromDTO: func(initiationDTO *SessionInitiationDTO) string { return initiationDTO.FirstName }},
+	{Column: "lastName", FromDTO: func(initiationDTO *SessionInitiationDTO) string { return initiationDTO.LastName }},
+}
+
+// Updates the player with the values Vitec currently has for the user, and records each change.
+// Fields left empty in the DTO are not synced, so Vitec not sending a field doesn't clear it.
+// Returns the changes made, if any.
+func SyncProfile(ctx context.Context, players repository.PlayerRepository, playerID uint32, initiationDTO *SessionInitiationDTO) ([]repository.ProfileChange, error) {
+	var values = map[string]string{}
+	for _, field := range ProfileFields {
+		if value := field.FromDTO(initiationDTO); value != "" {
+			values[field.Column] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return players.SyncProfile(ctx, playerID, values, time.Now())
+}
@@ -0,0 +1,33 @@
+package vitec
+
+import (
+	"context"
+	"otte_main_backend/src/repository"
+	"testing"
+)
+
+func TestSyncProfileRecordsChangedFields(t *testing.T) {
+	players := repository.NewMemoryPlayerRepository()
+	players.PutPlayer(repository.Player{ID: 7, FirstName: "Alice", LastName: "Smith"})
+
+	//First name unchanged, last name changed
+	changes, err := SyncProfile(context.Background(), players, 7, &SessionInitiationDTO{FirstName: "Alice", LastName: "Jones"})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(changes) != 1 || changes[0].Field != "lastName" || changes[0].OldValue != "Smith" || changes[0].NewValue != "Jones" {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+	player, _ := players.FindByID(context.Background(), 7)
+	if player.FirstName != "Alice" || player.LastName != "Jones" {
+		t.Errorf("expected the last name to be updated, got %+v", player)
+	}
+	if recorded := players.ProfileChanges(7); len(recorded) != 1 {
+		t.Errorf("expected the change to be recorded, got %+v", recorded)
+	}
+
+	//Fields Vitec doesn't send are left as is
+	if changes, err := SyncProfile(context.Background(), players, 7, &SessionInitiationDTO{FirstName: "Alice"}); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", changes, err)
+	}
+}
@@ -0,0 +1,234 @@
+package vitec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"otte_main_backend/src/config"
+	db "otte_main_backend/src/database"
+	"otte_main_backend/src/repository"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+type ProgressEventType string
+
+const (
+	ProgressAchievement        ProgressEventType = "achievement"
+	ProgressLocationUpgrade    ProgressEventType = "location-upgrade"
+	ProgressMinigameCompletion ProgressEventType = "minigame-completion"
+)
+
+const PROGRESS_BATCH_SIZE = 100
+
+// How long a claimed event is left alone by other instances before it is considered abandoned
+const PROGRESS_CLAIM_LEASE = 5 * time.Minute
+
+// How long delivering a batch may take. Shorter than the lease, so the outcomes are stored before the events can be claimed again.
+const PROGRESS_BATCH_TIMEOUT = 4 * time.Minute
+
+// What Vitec receives for each event. The event ID doubles as idempotency key, as an event may be delivered more than once.
+type progressReportDTO struct {
+	EventID     uint32            `json:"eventId"`
+	ReferenceID string            `json:"referenceID"`
+	Type        ProgressEventType `json:"type"`
+	OccurredAt  time.Time         `json:"occurredAt"`
+	Payload     datatypes.JSON    `json:"payload"`
+}
+
+// Delivers queued progress events to VITEC_PROGRESS_URL in the background, retrying with exponential backoff
+type ProgressReporter struct {
+	playerDB     db.PlayerDB
+	client       *VitecClient
+	interval     time.Duration
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	maxAttempts  int
+	stop         chan struct{}
+	done         chan struct{}
+	deliveryLock sync.Mutex
+}
+
+// Starts the reporter and attaches it to the integration. Does nothing if VITEC_PROGRESS_URL is not set,
+// in which case ReportProgress discards events.
+func StartProgressReporter(integration *VitecIntegration, playerDB db.PlayerDB, cfg config.VitecConfig) (*ProgressReporter, error) {
+	url := cfg.ProgressURL
+	if url == "" {
+		log.Println("[MV INT] VITEC_PROGRESS_URL not set, progress reporting disabled")
+		return nil, nil
+	}
+	tlsConfig, err := newVitecTLSConfigFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	reporter := NewProgressReporter(
+		playerDB,
+		NewVitecClient(url, cfg.AuthTimeout, tlsConfig),
+		cfg.ProgressBackoffBase,
+		cfg.ProgressBackoffMax,
+		cfg.ProgressMaxAttempts,
+	)
+	reporter.interval = cfg.ProgressInterval
+	integration.Progress = reporter
+
+	log.Printf("[MV INT] Progress reporter started, delivering to %s every %s\n", url, reporter.interval)
+	go reporter.loop()
+	return reporter, nil
+}
+
+// Creates a reporter without starting it, see StartProgressReporter
+func NewProgressReporter(playerDB db.PlayerDB, client *VitecClient, backoffBase time.Duration, backoffMax time.Duration, maxAttempts int) *ProgressReporter {
+	return &ProgressReporter{
+		playerDB:    playerDB,
+		client:      client,
+		backoffBase: backoffBase,
+		backoffMax:  backoffMax,
+		maxAttempts: maxAttempts,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Stops the reporter and waits for any ongoing delivery to finish. Undelivered events stay in the outbox.
+func (r *ProgressReporter) Stop() {
+	close(r.stop)
+	<-r.done
+	log.Println("[MV INT] Progress reporter stopped")
+}
+
+func (r *ProgressReporter) loop() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if _, err := r.DeliverDue(time.Now()); err != nil {
+				log.Println("[MV INT] INTERNAL ERROR: unable to deliver progress events: " + err.Error())
+			}
+		}
+	}
+}
+
+// Queues a progress event for the player, bound to the context of the request it stems from.
+// Nil-safe: discards the event if reporting is disabled.
+func (integration *VitecIntegration) ReportProgress(ctx context.Context, playerID uint32, eventType ProgressEventType, payload map[string]interface{}) {
+	if integration == nil || integration.Progress == nil {
+		return
+	}
+	if err := integration.Progress.Enqueue(ctx, playerID, eventType, payload, time.Now()); err != nil {
+		//Progress reporting must never fail the request it stems from
+		log.Printf("[MV INT] INTERNAL ERROR: unable to queue %s event for player %d: %s\n", eventType, playerID, err.Error())
+	}
+}
+
+func (r *ProgressReporter) Enqueue(ctx context.Context, playerID uint32, eventType ProgressEventType, payload map[string]interface{}, now time.Time) error {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	playerDB := r.playerDB.WithContext(ctx)
+	var referenceID string
+	if err := playerDB.Table("Player").Select(`"referenceID"`).Where("id = ?", playerID).Take(&referenceID).Error; err != nil {
+		return fmt.Errorf("unable to lookup referenceID: %s", err.Error())
+	}
+	return playerDB.Create(&repository.ProgressEvent{
+		Player:        playerID,
+		ReferenceID:   referenceID,
+		Type:          string(eventType),
+		Payload:       datatypes.JSON(encoded),
+		Status:        repository.DeliveryPending,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}).Error
+}
+
+// Attempts delivery of every pending event that is due, within PROGRESS_BATCH_TIMEOUT. Returns the amount delivered.
+// Events not attempted in time stay claimed until the lease expires.
+func (r *ProgressReporter) DeliverDue(now time.Time) (int, error) {
+	r.deliveryLock.Lock()
+	defer r.deliveryLock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), PROGRESS_BATCH_TIMEOUT)
+	defer cancel()
+	playerDB := r.playerDB.WithContext(ctx)
+
+	//Claimed by pushing nextAttemptAt ahead, so other instances skip them while they are being delivered
+	var events []repository.ProgressEvent
+	if err := playerDB.Raw(`
+        UPDATE "VitecProgressOutbox" SET "nextAttemptAt" = ?
+        WHERE id IN (
+            SELECT id FROM "VitecProgressOutbox"
+            WHERE status = ? AND "nextAttemptAt" <= ?
+            ORDER BY id LIMIT ?
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING *
+    `, now.Add(PROGRESS_CLAIM_LEASE), repository.DeliveryPending, now, PROGRESS_BATCH_SIZE).Scan(&events).Error; err != nil {
+		return 0, err
+	}
+
+	var delivered = 0
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+		updates := r.AttemptDelivery(ctx, &event, now)
+		if updates["status"] == repository.DeliveryDelivered {
+			delivered++
+		}
+		if err := playerDB.Model(&repository.ProgressEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// Posts the event to Vitec once. Returns the columns to update for the event after the attempt.
+func (r *ProgressReporter) AttemptDelivery(ctx context.Context, event *repository.ProgressEvent, now time.Time) map[string]interface{} {
+	attempts := event.Attempts + 1
+	statusCode, err := r.client.postJSON(ctx, "", progressReportDTO{
+		EventID:     event.ID,
+		ReferenceID: event.ReferenceID,
+		Type:        ProgressEventType(event.Type),
+		OccurredAt:  event.CreatedAt,
+		Payload:     event.Payload,
+	}, map[string]string{"Idempotency-Key": strconv.FormatUint(uint64(event.ID), 10)})
+
+	if err == nil && statusCode >= 200 && statusCode < 300 {
+		return map[string]interface{}{"status": repository.DeliveryDelivered, "attempts": attempts, "deliveredAt": now, "lastError": ""}
+	}
+	var lastError string
+	if err != nil {
+		lastError = err.Error()
+	} else {
+		lastError = fmt.Sprintf("unexpected status code: %d", statusCode)
+	}
+	//Client errors won't be any different next time, except for being rate limited
+	permanent := err == nil && statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests
+	if permanent || attempts >= r.maxAttempts {
+		return map[string]interface{}{"status": repository.DeliveryFailed, "attempts": attempts, "lastError": lastError}
+	}
+	return map[string]interface{}{
+		"attempts":      attempts,
+		"lastError":     lastError,
+		"nextAttemptAt": now.Add(r.backoff(attempts)),
+	}
+}
+
+// Exponential backoff with up to 20% jitter, so events failed together aren't retried together
+func (r *ProgressReporter) backoff(attempts int) time.Duration {
+	delay := r.backoffBase
+	for i := 1; i < attempts && delay < r.backoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.backoffMax)
+	return delay - time.Duration(rand.Int64N(int64(delay)/5+1))
+}
@@ -0,0 +1,57 @@
+package vitec_test
+
+import (
+	"context"
+	"net/http"
+	"otte_main_backend/src/repository"
+	"otte_main_backend/src/vitec"
+	"otte_main_backend/src/vitec/vitectest"
+	"testing"
+	"time"
+)
+
+func TestProgressDeliveryRetriesThenDelivers(t *testing.T) {
+	fake := vitectest.NewFakeVitecServer()
+	defer fake.Close()
+	reporter := vitec.NewProgressReporter(nil, vitec.NewVitecClient(fake.Server.URL+"/progress", time.Second, nil), time.Minute, time.Hour, 3)
+	event := &repository.ProgressEvent{ID: 1, ReferenceID: "vitec-7", Type: string(vitec.ProgressAchievement), Payload: []byte(`{"achievementId":1}`)}
+	now := time.Now()
+
+	fake.FailWith(http.StatusServiceUnavailable)
+	updates := reporter.AttemptDelivery(context.Background(), event, now)
+	if _, failed := updates["status"]; failed {
+		t.Fatalf("expected a temporary failure to be retried, got: %v", updates)
+	}
+	nextAttemptAt := updates["nextAttemptAt"].(time.Time)
+	if nextAttemptAt.Before(now.Add(48*time.Second)) || nextAttemptAt.After(now.Add(time.Minute)) {
+		t.Errorf("unexpected first backoff: %s", nextAttemptAt.Sub(now))
+	}
+
+	fake.FailWith(0)
+	event.Attempts = 1
+	updates = reporter.AttemptDelivery(context.Background(), event, now)
+	if updates["status"] != repository.DeliveryDelivered || updates["attempts"] != 2 {
+		t.Errorf("expected event to be delivered on the second attempt, got: %v", updates)
+	}
+	if reports := fake.ProgressReports(); len(reports) != 1 {
+		t.Errorf("expected 1 report to be received, got %d", len(reports))
+	}
+}
+
+func TestProgressDeliveryGivesUp(t *testing.T) {
+	fake := vitectest.NewFakeVitecServer()
+	defer fake.Close()
+	reporter := vitec.NewProgressReporter(nil, vitec.NewVitecClient(fake.Server.URL+"/progress", time.Second, nil), time.Minute, time.Hour, 3)
+	event := &repository.ProgressEvent{ID: 1, ReferenceID: "vitec-7", Type: string(vitec.ProgressAchievement), Payload: []byte(`{}`)}
+
+	fake.FailWith(http.StatusBadRequest)
+	if updates := reporter.AttemptDelivery(context.Background(), event, time.Now()); updates["status"] != repository.DeliveryFailed {
+		t.Errorf("expected a rejected event to fail permanently, got: %v", updates)
+	}
+
+	fake.FailWith(http.StatusServiceUnavailable)
+	event.Attempts = 2
+	if updates := reporter.AttemptDelivery(context.Background(), event, time.Now()); updates["status"] != repository.DeliveryFailed {
+		t.Errorf("expected event out of attempts to fail, got: %v", updates)
+	}
+}
@@ -0,0 +1,177 @@
+// In-process fake of the Vitec MV auth endpoint, for testing the integration offline.
+package vitectest
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type crossVerificationRequestDTO struct {
+	UserIdentifier      string `json:"userIdentifier"`
+	CurrentSessionToken string `json:"currentSessionToken"`
+}
+
+type crossVerificationResponseDTO struct {
+	Verified bool   `json:"verified"`
+	Message  string `json:"message"`
+}
+
+// Answers /health and /cross-verify like Vitec does. Users are registered with AddUser.
+type FakeVitecServer struct {
+	Server *httptest.Server
+
+	lock sync.Mutex
+	// userIdentifier to the session token Vitec currently has for them
+	users map[string]string
+	// Status code to answer every request with, 0 to answer normally
+	failWith int
+	delay    time.Duration
+	requests int
+	// Bodies received on /progress, in order
+	progressReports []json.RawMessage
+}
+
+func newFakeVitecServer() *FakeVitecServer {
+	fake := &FakeVitecServer{users: make(map[string]string)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", fake.handleHealth)
+	mux.HandleFunc("/cross-verify", fake.handleCrossVerify)
+	mux.HandleFunc("/progress", fake.handleProgress)
+	fake.Server = httptest.NewUnstartedServer(mux)
+	return fake
+}
+
+// Starts a plain HTTP fake. Close it when done.
+func NewFakeVitecServer() *FakeVitecServer {
+	fake := newFakeVitecServer()
+	fake.Server.Start()
+	return fake
+}
+
+// Starts an HTTPS fake with a self signed certificate, trusted by Server.Client(). Close it when done.
+func NewFakeVitecTLSServer() *FakeVitecServer {
+	fake := newFakeVitecServer()
+	fake.Server.StartTLS()
+	return fake
+}
+
+func (fake *FakeVitecServer) Close() {
+	fake.Server.Close()
+}
+
+// Host and port, as given by VITEC_MV_AUTH_IP and VITEC_MV_AUTH_PORT
+func (fake *FakeVitecServer) Address() (string, int) {
+	host, portStr, _ := net.SplitHostPort(fake.Server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+func (fake *FakeVitecServer) AddUser(userIdentifier string, sessionToken string) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.users[userIdentifier] = sessionToken
+}
+
+func (fake *FakeVitecServer) RemoveUser(userIdentifier string) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	delete(fake.users, userIdentifier)
+}
+
+// Makes every following request fail with the status code. 0 restores normal behaviour.
+func (fake *FakeVitecServer) FailWith(statusCode int) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.failWith = statusCode
+}
+
+// Delays every following response, for testing timeouts
+func (fake *FakeVitecServer) SetDelay(delay time.Duration) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	fake.delay = delay
+}
+
+// Amount of cross verification requests received
+func (fake *FakeVitecServer) Requests() int {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	return fake.requests
+}
+
+// Progress reports received, as VITEC_PROGRESS_URL pointed at Server.URL + "/progress"
+func (fake *FakeVitecServer) ProgressReports() []json.RawMessage {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	return append([]json.RawMessage{}, fake.progressReports...)
+}
+
+// Returns the status code to fail with, if any, after applying the delay
+func (fake *FakeVitecServer) before() int {
+	fake.lock.Lock()
+	delay, failWith := fake.delay, fake.failWith
+	fake.lock.Unlock()
+	time.Sleep(delay)
+	return failWith
+}
+
+func (fake *FakeVitecServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if failWith := fake.before(); failWith != 0 {
+		w.WriteHeader(failWith)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (fake *FakeVitecServer) handleCrossVerify(w http.ResponseWriter, r *http.Request) {
+	fake.lock.Lock()
+	fake.requests++
+	fake.lock.Unlock()
+	if failWith := fake.before(); failWith != 0 {
+		w.WriteHeader(failWith)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var request crossVerificationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fake.lock.Lock()
+	token, known := fake.users[request.UserIdentifier]
+	fake.lock.Unlock()
+
+	response := crossVerificationResponseDTO{Verified: true}
+	if !known {
+		response = crossVerificationResponseDTO{Verified: false, Message: "unknown user"}
+	} else if token != request.CurrentSessionToken {
+		response = crossVerificationResponseDTO{Verified: false, Message: "session token mismatch"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (fake *FakeVitecServer) handleProgress(w http.ResponseWriter, r *http.Request) {
+	if failWith := fake.before(); failWith != 0 {
+		w.WriteHeader(failWith)
+		return
+	}
+	var report json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fake.lock.Lock()
+	fake.progressReports = append(fake.progressReports, report)
+	fake.lock.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
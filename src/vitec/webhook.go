@@ -0,0 +1,108 @@
+package vitec
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"otte_main_backend/src/config"
+	"otte_main_backend/src/util"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const WEBHOOK_TIMESTAMP_HEADER = "X-Vitec-Timestamp"
+const WEBHOOK_SIGNATURE_HEADER = "X-Vitec-Signature"
+
+// Verifies signed webhook requests from Vitec.
+//
+// Vitec signs "<timestamp>.<body>" with HMAC-SHA256 using the shared secret, and sends the hex encoded signature
+// along with the unix timestamp (seconds). Requests outside the tolerance are rejected, and so are signatures seen before,
+// so a captured request can't be replayed. A request which then fails should be forgotten again, see Forget, so Vitec can retry it.
+type WebhookVerifier struct {
+	secret    []byte
+	tolerance time.Duration
+	//Signatures seen within the tolerance, and when they can be forgotten
+	seen util.ConcurrentTypedMap[string, time.Time]
+}
+
+func NewWebhookVerifier(secret []byte, tolerance time.Duration) *WebhookVerifier {
+	return &WebhookVerifier{
+		secret:    secret,
+		tolerance: tolerance,
+		seen:      util.ConcurrentTypedMap[string, time.Time]{},
+	}
+}
+
+// From VITEC_WEBHOOK_SECRET and VITEC_WEBHOOK_TOLERANCE_S. Returns nil if no secret is set.
+func newWebhookVerifierFromConfig(cfg config.VitecConfig) *WebhookVerifier {
+	if cfg.WebhookSecret == "" {
+		return nil
+	}
+	return NewWebhookVerifier([]byte(cfg.WebhookSecret.Reveal()), cfg.WebhookTolerance)
+}
+
+// Signs the body as Vitec would. Returns the timestamp and signature headers.
+func (v *WebhookVerifier) Sign(body []byte, at time.Time) (string, string) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return timestamp, v.signature(timestamp, body)
+}
+
+func (v *WebhookVerifier) signature(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns a fiber.Error describing why the request is refused, nil if it is authentic and not a replay
+func (v *WebhookVerifier) Verify(c *fiber.Ctx, now time.Time) *fiber.Error {
+	timestamp := string(c.Request().Header.Peek(WEBHOOK_TIMESTAMP_HEADER))
+	signature := string(c.Request().Header.Peek(WEBHOOK_SIGNATURE_HEADER))
+	if timestamp == "" || signature == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "Missing signature")
+	}
+	unixSeconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid timestamp")
+	}
+	signedAt := time.Unix(unixSeconds, 0)
+	if signedAt.Before(now.Add(-v.tolerance)) || signedAt.After(now.Add(v.tolerance)) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Timestamp outside tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(v.signature(timestamp, c.Body()))) {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid signature")
+	}
+
+	v.forgetExpired(now)
+	//Only remembered for as long as the timestamp would be accepted anyway
+	if _, replayed := v.seen.LoadOrStore(signature, signedAt.Add(v.tolerance)); replayed {
+		return fiber.NewError(fiber.StatusConflict, "Request already processed")
+	}
+	return nil
+}
+
+// Allows the signature of the request to be used again, for when processing it failed after Verify accepted it
+func (v *WebhookVerifier) Forget(c *fiber.Ctx) {
+	v.seen.Delete(string(c.Request().Header.Peek(WEBHOOK_SIGNATURE_HEADER)))
+}
+
+func (v *WebhookVerifier) forgetExpired(now time.Time) {
+	v.seen.Range(func(signature string, forgetAt time.Time) bool {
+		if now.After(forgetAt) {
+			v.seen.CompareAndDelete(signature, forgetAt)
+		}
+		return true
+	})
+}
+
+// Logs the request and sets the status like auth.PrefixOn does
+func respondWithWebhookError(c *fiber.Ctx, ddh string, err *fiber.Error) error {
+	c.Response().Header.Set(ddh, err.Message)
+	c.Status(err.Code)
+	log.Printf("[MV INT] Refused webhook request from %s: %s\n", c.IP(), err.Message)
+	return err
+}